@@ -0,0 +1,124 @@
+package account
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"life/audit"
+	"life/models"
+
+	"gorm.io/gorm"
+)
+
+// exportedIdentity inclui no arquivo o identificador do usuário no provedor,
+// omitido nas respostas da API
+type exportedIdentity struct {
+	models.Identity
+	Subject string `json:"subject"`
+}
+
+// exportedUser é o perfil sem o hash da senha e sem o segredo TOTP
+type exportedUser struct {
+	models.User
+	Password  string     `json:"password,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Export grava em w um arquivo zip com um JSON para cada tipo de dado
+// armazenado sobre o usuário: perfil, sessões, API keys e seu uso,
+// identidades externas, clientes OAuth2, consentimentos e eventos de auditoria.
+// Hashes de senhas, tokens e chaves não são incluídos, nem o autor, o IP, o
+// user agent e o ID da requisição de eventos executados por outras pessoas.
+func Export(ctx context.Context, db *gorm.DB, userID uint, w io.Writer) error {
+	db = db.WithContext(ctx)
+
+	var user models.User
+	if err := db.Unscoped().First(&user, userID).Error; err != nil {
+		return err
+	}
+	profile := exportedUser{User: user}
+	if user.DeletedAt.Valid {
+		profile.DeletedAt = &user.DeletedAt.Time
+	}
+
+	var sessions []models.RefreshToken
+	if err := db.Unscoped().Where("user_id = ?", userID).Order("id").Find(&sessions).Error; err != nil {
+		return err
+	}
+
+	var apiKeys []models.APIKey
+	if err := db.Unscoped().Where("user_id = ?", userID).Order("id").Find(&apiKeys).Error; err != nil {
+		return err
+	}
+
+	var usage []models.APIKeyUsage
+	if err := db.Where("api_key_id IN (?)", db.Unscoped().Model(&models.APIKey{}).Select("id").Where("user_id = ?", userID)).
+		Order("day, api_key_id, route, status").Find(&usage).Error; err != nil {
+		return err
+	}
+
+	var identities []models.Identity
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&identities).Error; err != nil {
+		return err
+	}
+	exportedIdentities := make([]exportedIdentity, len(identities))
+	for i, identity := range identities {
+		exportedIdentities[i] = exportedIdentity{Identity: identity, Subject: identity.Subject}
+	}
+
+	var clients []models.OAuthClient
+	if err := db.Where("owner_id = ?", userID).Order("id").Find(&clients).Error; err != nil {
+		return err
+	}
+
+	var consents []models.OAuthConsent
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&consents).Error; err != nil {
+		return err
+	}
+
+	var events []models.AuditEvent
+	if err := db.Where("actor_id = ? OR (target_type = ? AND target_id = ?)", userID, audit.TargetUser, strconv.FormatUint(uint64(userID), 10)).
+		Order("id").Find(&events).Error; err != nil {
+		return err
+	}
+	// Em eventos executados por outra pessoa (ex: moderação), os dados de quem
+	// executou a ação não pertencem ao usuário
+	for i := range events {
+		if events[i].ActorID == nil || *events[i].ActorID != userID {
+			events[i].ActorID = nil
+			events[i].IP = ""
+			events[i].UserAgent = ""
+			events[i].RequestID = ""
+		}
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", profile},
+		{"sessions.json", sessions},
+		{"api_keys.json", apiKeys},
+		{"api_key_usage.json", usage},
+		{"identities.json", exportedIdentities},
+		{"oauth_clients.json", clients},
+		{"oauth_consents.json", consents},
+		{"audit_events.json", events},
+	} {
+		entry, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
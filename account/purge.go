@@ -0,0 +1,186 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"life/audit"
+	"life/auth"
+	"life/models"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultDeletionGrace é o período em que a exclusão da conta pode ser cancelada
+	defaultDeletionGrace = 30 * 24 * time.Hour
+
+	// defaultPurgeInterval define de quanto em quanto tempo as contas vencidas são removidas
+	defaultPurgeInterval = time.Hour
+
+	// purgeBatchSize limita as contas removidas por consulta
+	purgeBatchSize = 100
+)
+
+// DeletionGraceFromEnv lê ACCOUNT_DELETION_GRACE (padrão: 720h, ou 30 dias)
+func DeletionGraceFromEnv() (time.Duration, error) {
+	value := os.Getenv("ACCOUNT_DELETION_GRACE")
+	if value == "" {
+		return defaultDeletionGrace, nil
+	}
+
+	grace, err := time.ParseDuration(value)
+	if err != nil || grace < 0 {
+		return 0, fmt.Errorf("ACCOUNT_DELETION_GRACE inválido: %q", value)
+	}
+	return grace, nil
+}
+
+// PurgeIntervalFromEnv lê ACCOUNT_PURGE_INTERVAL (padrão: 1h)
+func PurgeIntervalFromEnv() (time.Duration, error) {
+	value := os.Getenv("ACCOUNT_PURGE_INTERVAL")
+	if value == "" {
+		return defaultPurgeInterval, nil
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("ACCOUNT_PURGE_INTERVAL inválido: %q", value)
+	}
+	return interval, nil
+}
+
+// Purger remove definitivamente, em segundo plano, as contas excluídas há
+// mais tempo que o período de cancelamento
+type Purger struct {
+	db       *gorm.DB
+	grace    time.Duration
+	interval time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewPurger cria um Purger e inicia a remoção periódica em segundo plano
+func NewPurger(db *gorm.DB, grace, interval time.Duration) *Purger {
+	p := &Purger{
+		db:       db,
+		grace:    grace,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Grace retorna o período em que a exclusão pode ser cancelada
+func (p *Purger) Grace() time.Duration {
+	return p.grace
+}
+
+// PurgeExpired remove as contas cujo período de cancelamento terminou e
+// retorna quantas foram removidas
+func (p *Purger) PurgeExpired(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-p.grace)
+	purged := 0
+
+	for {
+		var users []models.User
+		if err := p.db.WithContext(ctx).Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at <= ?", cutoff).
+			Order("id").Limit(purgeBatchSize).
+			Find(&users).Error; err != nil {
+			return purged, err
+		}
+
+		for i := range users {
+			if err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				return PurgeUser(tx, &users[i])
+			}); err != nil {
+				return purged, err
+			}
+			audit.Record(p.db, nil, audit.Event{Action: audit.ActionAccountPurged, TargetType: audit.TargetUser, TargetID: users[i].ID, Success: true})
+			purged++
+		}
+
+		if len(users) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// Close interrompe a remoção periódica
+func (p *Purger) Close() {
+	p.once.Do(func() { close(p.stop) })
+	<-p.done
+}
+
+// run remove as contas vencidas a cada intervalo
+func (p *Purger) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		if purged, err := p.PurgeExpired(context.Background()); err != nil {
+			log.Warn().Err(err).Msg("Falha ao remover contas excluídas; nova tentativa no próximo intervalo")
+		} else if purged > 0 {
+			log.Info().Int("accounts", purged).Msg("Contas excluídas removidas definitivamente")
+		}
+	}
+}
+
+// PurgeUser remove definitivamente o usuário e tudo o que pertence a ele:
+// API keys e seu uso, sessões, identidades externas, credenciais de 2FA e de
+// recuperação, clientes OAuth2 registrados e consentimentos. Os eventos de
+// auditoria não podem ser removidos: são pseudonimizados por
+// audit.Pseudonymize, mantendo a ação, a data, o resultado e os IDs numéricos
+// do autor e do recurso, sem IP, user agent, nome de usuário ou email.
+// Deve rodar dentro de uma transação.
+func PurgeUser(tx *gorm.DB, user *models.User) error {
+	if err := audit.Pseudonymize(tx, user.ID, user.Username); err != nil {
+		return err
+	}
+
+	apiKeyIDs := tx.Unscoped().Model(&models.APIKey{}).Select("id").Where("user_id = ?", user.ID)
+	clientIDs := tx.Unscoped().Model(&models.OAuthClient{}).Select("client_id").Where("owner_id = ?", user.ID)
+
+	steps := []struct {
+		model interface{}
+		query string
+		args  []interface{}
+	}{
+		{&models.APIKeyUsage{}, "api_key_id IN (?)", []interface{}{apiKeyIDs}},
+		{&models.OAuthConsent{}, "user_id = ? OR client_id IN (?)", []interface{}{user.ID, clientIDs}},
+		{&models.OAuthAuthorizationCode{}, "user_id = ? OR client_id IN (?)", []interface{}{user.ID, clientIDs}},
+		{&models.OAuthClient{}, "owner_id = ?", []interface{}{user.ID}},
+		{&models.APIKey{}, "user_id = ?", []interface{}{user.ID}},
+		{&models.RefreshToken{}, "user_id = ?", []interface{}{user.ID}},
+		{&models.RecoveryCode{}, "user_id = ?", []interface{}{user.ID}},
+		{&models.PasswordResetToken{}, "user_id = ?", []interface{}{user.ID}},
+		{&models.Identity{}, "user_id = ?", []interface{}{user.ID}},
+		{&models.DeviceCode{}, "user_id = ?", []interface{}{user.ID}},
+		{&models.OIDCState{}, "user_id = ?", []interface{}{user.ID}},
+		{&models.LoginThrottle{}, "key = ?", []interface{}{auth.AccountThrottleKey(user.Username)}},
+	}
+	for _, step := range steps {
+		if err := tx.Unscoped().Where(step.query, step.args...).Delete(step.model).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Unscoped().Delete(user).Error
+}
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"strconv"
+
+	"life/logger"
+	"life/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Ações registradas no log de auditoria
+const (
+	ActionRegister         = "auth.register"
+	ActionLogin            = "auth.login"
+	ActionLoginMFA         = "auth.login_mfa"
+	ActionLogout           = "auth.logout"
+	ActionLogoutAll        = "auth.logout_all"
+	ActionRefreshReuse     = "auth.refresh_token_reused"
+	ActionSessionRevoked   = "auth.session_revoked"
+	ActionPasswordReset    = "auth.password_reset"
+	ActionPasswordChanged  = "auth.password_changed"
+	ActionEmailVerified    = "auth.email_verified"
+	ActionMFAEnabled       = "auth.mfa_enabled"
+	ActionMFADisabled      = "auth.mfa_disabled"
+	ActionIdentityLinked   = "auth.identity_linked"
+	ActionIdentityUnlinked = "auth.identity_unlinked"
+	ActionAPIKeyCreated    = "api_key.created"
+	ActionAPIKeyUpdated    = "api_key.updated"
+	ActionAPIKeyDeleted    = "api_key.deleted"
+	ActionAPIKeyRotated    = "api_key.rotated"
+	ActionProfileUpdated   = "user.profile_updated"
+	ActionUserUpdated      = "user.updated"
+	ActionUserRoleChanged  = "user.role_changed"
+	ActionUserSuspended    = "user.suspended"
+	ActionUserBanned       = "user.banned"
+	ActionUserReinstated   = "user.reinstated"
+	ActionAccountDeletion  = "account.deletion_requested"
+	ActionAccountRestored  = "account.restored"
+	ActionAccountPurged    = "account.purged"
+	ActionAccountExported  = "account.exported"
+)
+
+// Tipos de recurso afetados pelas ações
+const (
+	TargetUser     = "user"
+	TargetAPIKey   = "api_key"
+	TargetSession  = "session"
+	TargetIdentity = "identity"
+)
+
+// Event descreve uma ação a ser registrada; a origem da requisição (IP,
+// user agent e request ID) é preenchida a partir do contexto
+type Event struct {
+	// Usuário que executou a ação; zero usa o usuário autenticado, se houver
+	ActorID uint
+
+	Action     string
+	TargetType string
+	TargetID   uint
+	Success    bool
+	Metadata   map[string]interface{}
+}
+
+// Record grava o evento no log de auditoria. Falhas são registradas no log da
+// aplicação sem interromper a requisição, que já foi concluída. c é nil em
+// tarefas em segundo plano, que não têm requisição de origem.
+func Record(db *gorm.DB, c *gin.Context, event Event) {
+	actorID := event.ActorID
+	entry := models.AuditEvent{
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		Success:    event.Success,
+		Metadata:   event.Metadata,
+	}
+	if c != nil {
+		if actorID == 0 {
+			actorID = c.GetUint("user_id")
+		}
+		entry.IP = c.ClientIP()
+		entry.UserAgent = c.Request.UserAgent()
+		entry.RequestID = c.GetString(logger.RequestIDKey)
+	}
+	if actorID != 0 {
+		entry.ActorID = &actorID
+	}
+	if event.TargetID != 0 {
+		entry.TargetID = strconv.FormatUint(uint64(event.TargetID), 10)
+	}
+
+	if err := db.Create(&entry).Error; err != nil {
+		log.Error().Err(err).Str("action", event.Action).Str("request_id", entry.RequestID).Msg("Falha ao gravar evento de auditoria")
+	}
+}
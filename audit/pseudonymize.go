@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// PseudonymizeSetting é a configuração de transação que libera, no trigger de
+// audit_events, a pseudonimização feita por Pseudonymize
+const PseudonymizeSetting = "life.audit_pseudonymize"
+
+// personalMetadataKeys são as chaves dos metadados que guardam dados pessoais,
+// no formato de array do Postgres
+var personalMetadataKeys = "{" + strings.Join([]string{"username", "email", "previous_email", "display_name", "previous_display_name"}, ",") + "}"
+
+// Pseudonymize apaga os dados pessoais dos eventos ligados ao usuário: os
+// eventos executados por ele ou sobre ele e as tentativas de login com o seu
+// nome de usuário. O IP e o user agent são apagados e os dados pessoais são
+// removidos dos metadados; a ação, a data, o resultado e os IDs numéricos do
+// autor e do recurso são mantidos. Deve rodar dentro de uma transação.
+func Pseudonymize(tx *gorm.DB, userID uint, username string) error {
+	if err := tx.Exec(fmt.Sprintf("SET LOCAL %s = 'on'", PseudonymizeSetting)).Error; err != nil {
+		return err
+	}
+
+	return tx.Exec(`UPDATE audit_events SET
+			ip = '',
+			user_agent = '',
+			metadata = CASE WHEN jsonb_typeof(metadata::jsonb) = 'object' THEN metadata::jsonb - ?::text[] ELSE metadata::jsonb END
+		WHERE actor_id = ?
+			OR (target_type = ? AND target_id = ?)
+			OR (action = ? AND lower(metadata::jsonb ->> 'username') = lower(?))`,
+		personalMetadataKeys, userID, TargetUser, strconv.FormatUint(uint64(userID), 10), ActionLogin, username,
+	).Error
+}
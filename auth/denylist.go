@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"life/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// denylistSyncInterval define de quanto em quanto tempo o cache é
+// sincronizado com o banco; é o atraso máximo para que uma revogação feita
+// por outra instância passe a valer nesta
+const denylistSyncInterval = 15 * time.Second
+
+// Denylist mantém os jti de access tokens revogados antes da expiração.
+// As consultas usam um cache em memória sincronizado periodicamente com o
+// Postgres, evitando uma query por requisição autenticada.
+type Denylist struct {
+	db *gorm.DB
+
+	mu       sync.RWMutex
+	entries  map[string]time.Time
+	lastSync time.Time
+
+	syncMu sync.Mutex
+}
+
+// NewDenylist cria uma nova instância da Denylist
+func NewDenylist(db *gorm.DB) *Denylist {
+	return &Denylist{
+		db:      db,
+		entries: make(map[string]time.Time),
+	}
+}
+
+// Revoke adiciona um jti à denylist até a expiração do token
+func (d *Denylist) Revoke(jti string, expiresAt time.Time) error {
+	if !expiresAt.After(time.Now()) {
+		return nil
+	}
+
+	entry := models.RevokedToken{JTI: jti, ExpiresAt: expiresAt}
+	if err := d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry).Error; err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.entries[jti] = expiresAt
+	d.mu.Unlock()
+
+	return nil
+}
+
+// IsRevoked informa se o jti foi revogado
+func (d *Denylist) IsRevoked(jti string) (bool, error) {
+	if err := d.syncIfStale(); err != nil {
+		return false, err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	expiresAt, ok := d.entries[jti]
+	return ok && expiresAt.After(time.Now()), nil
+}
+
+// syncIfStale carrega do banco as revogações feitas desde a última sincronização
+func (d *Denylist) syncIfStale() error {
+	d.mu.RLock()
+	stale := time.Since(d.lastSync) >= denylistSyncInterval
+	d.mu.RUnlock()
+	if !stale {
+		return nil
+	}
+
+	d.syncMu.Lock()
+	defer d.syncMu.Unlock()
+
+	// Outra goroutine pode ter sincronizado enquanto esperávamos
+	d.mu.RLock()
+	stale = time.Since(d.lastSync) >= denylistSyncInterval
+	since := d.lastSync
+	d.mu.RUnlock()
+	if !stale {
+		return nil
+	}
+
+	now := time.Now()
+
+	// Sobreposição de uma janela para não perder entradas gravadas com atraso
+	var rows []models.RevokedToken
+	query := d.db.Select("jti", "expires_at").Where("expires_at > ?", now)
+	if !since.IsZero() {
+		query = query.Where("created_at >= ?", since.Add(-denylistSyncInterval))
+	}
+	if err := query.Find(&rows).Error; err != nil {
+		return err
+	}
+
+	// Entradas expiradas não precisam mais ser mantidas
+	if err := d.db.Where("expires_at <= ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for jti, expiresAt := range d.entries {
+		if !expiresAt.After(now) {
+			delete(d.entries, jti)
+		}
+	}
+	for _, row := range rows {
+		d.entries[row.JTI] = row.ExpiresAt
+	}
+	d.lastSync = now
+
+	return nil
+}
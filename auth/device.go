@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// userCodeAlphabet contém apenas consoantes, evitando palavras e caracteres
+// ambíguos ao digitar o código em outro dispositivo (RFC 8628, seção 6.1)
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+// userCodeLength é a quantidade de caracteres do código exibido no dispositivo
+const userCodeLength = 8
+
+// GenerateUserCode gera o código de verificação exibido no dispositivo, já normalizado
+func GenerateUserCode() (string, error) {
+	b := make([]byte, userCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	code := make([]byte, userCodeLength)
+	for i, v := range b {
+		code[i] = userCodeAlphabet[int(v)%len(userCodeAlphabet)]
+	}
+	return string(code), nil
+}
+
+// FormatUserCode formata o código para exibição (XXXX-XXXX)
+func FormatUserCode(code string) string {
+	if len(code) != userCodeLength {
+		return code
+	}
+	return code[:userCodeLength/2] + "-" + code[userCodeLength/2:]
+}
+
+// NormalizeUserCode descarta separadores e padroniza a caixa do código digitado pelo usuário
+func NormalizeUserCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		if r < 'A' || r > 'Z' {
+			return -1
+		}
+		return r
+	}, code)
+}
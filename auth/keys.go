@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// minRSAKeyBits é o tamanho mínimo aceito para chaves RSA
+const minRSAKeyBits = 2048
+
+var (
+	// ErrUnknownKey indica que o token foi assinado com um kid desconhecido
+	ErrUnknownKey = errors.New("chave de assinatura desconhecida")
+
+	// SupportedMethods lista os algoritmos aceitos na verificação de tokens
+	SupportedMethods = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+)
+
+// SigningKey representa uma chave usada para assinar ou verificar JWTs
+type SigningKey struct {
+	// ID publicado no header "kid" dos tokens e no JWKS
+	ID string
+
+	// Algoritmo de assinatura (RS256 ou EdDSA)
+	Method jwt.SigningMethod
+
+	// Chave privada; nula para chaves mantidas apenas para verificação
+	Private crypto.Signer
+
+	// Chave pública correspondente
+	Public crypto.PublicKey
+}
+
+// KeySet guarda a chave ativa de assinatura e todas as chaves aceitas na
+// verificação, permitindo rotacionar chaves sem invalidar tokens já emitidos
+type KeySet struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	order  []string
+}
+
+// JWK representa uma chave pública no formato JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS representa um conjunto de chaves públicas
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet carrega as chaves a partir das variáveis de ambiente.
+//
+// JWT_KEYS_DIR aponta para um diretório com arquivos <kid>.pem contendo chaves
+// privadas (RSA ou Ed25519) ou chaves públicas de chaves já aposentadas.
+// JWT_ACTIVE_KID escolhe a chave usada para assinar; é obrigatório quando há
+// mais de uma chave privada. Sem JWT_KEYS_DIR é gerada uma chave efêmera.
+func LoadKeySet() (*KeySet, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		log.Warn().Msg("JWT_KEYS_DIR não definido, usando chave Ed25519 efêmera: tokens não sobrevivem a reinícios")
+		return NewEphemeralKeySet()
+	}
+
+	return LoadKeySetFromDir(dir, os.Getenv("JWT_ACTIVE_KID"))
+}
+
+// LoadKeySetFromDir carrega todas as chaves .pem de um diretório
+func LoadKeySetFromDir(dir, activeKID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var keys []*SigningKey
+	for _, path := range paths {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao carregar chave %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+
+	if activeKID == "" {
+		var signers []*SigningKey
+		for _, key := range keys {
+			if key.Private != nil {
+				signers = append(signers, key)
+			}
+		}
+		if len(signers) != 1 {
+			return nil, fmt.Errorf("JWT_ACTIVE_KID é obrigatório quando %s não possui exatamente uma chave privada", dir)
+		}
+		activeKID = signers[0].ID
+	}
+
+	return NewKeySet(activeKID, keys...)
+}
+
+// NewKeySet cria um KeySet a partir de chaves já carregadas
+func NewKeySet(activeKID string, keys ...*SigningKey) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*SigningKey)}
+	for _, key := range keys {
+		if _, exists := ks.keys[key.ID]; exists {
+			return nil, fmt.Errorf("kid duplicado: %s", key.ID)
+		}
+		ks.keys[key.ID] = key
+		ks.order = append(ks.order, key.ID)
+	}
+
+	active, ok := ks.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("chave ativa %q não encontrada", activeKID)
+	}
+	if active.Private == nil {
+		return nil, fmt.Errorf("chave ativa %q não possui chave privada", activeKID)
+	}
+	ks.active = active
+
+	return ks, nil
+}
+
+// NewEphemeralKeySet gera um KeySet com uma única chave Ed25519 em memória
+func NewEphemeralKeySet() (*KeySet, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{
+		ID:      "ephemeral-" + hex.EncodeToString(id),
+		Method:  jwt.SigningMethodEdDSA,
+		Private: private,
+		Public:  public,
+	}
+
+	return NewKeySet(key.ID, key)
+}
+
+// Sign assina as claims com a chave ativa, incluindo o header "kid"
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	token.Header["kid"] = ks.active.ID
+	return token.SignedString(ks.active.Private)
+}
+
+// Keyfunc resolve a chave pública de verificação a partir do header "kid"
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	// Impede que um token declare um algoritmo diferente do da chave
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, jwt.ErrSignatureInvalid
+	}
+
+	return key.Public, nil
+}
+
+// JWKS retorna as chaves públicas de verificação no formato JWK Set
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(ks.order))}
+	for _, kid := range ks.order {
+		key := ks.keys[kid]
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+// loadKeyFile lê uma chave PEM; o kid é o nome do arquivo sem extensão
+func loadKeyFile(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("arquivo PEM inválido")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("tipo de bloco PEM não suportado: %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, errors.New("algoritmo de chave não suportado (use RSA ou Ed25519)")
+	}
+
+	if public, ok := key.Public.(*rsa.PublicKey); ok && public.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("chave RSA deve ter ao menos %d bits", minRSAKeyBits)
+	}
+
+	return key, nil
+}
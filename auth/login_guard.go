@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"life/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultMaxAccountFailures é quantas falhas seguidas bloqueiam uma conta
+	defaultMaxAccountFailures = 5
+
+	// defaultMaxIPFailures é quantas falhas seguidas bloqueiam um IP
+	defaultMaxIPFailures = 20
+
+	// defaultBaseLockout é a duração do primeiro bloqueio; cada falha seguinte a dobra
+	defaultBaseLockout = time.Minute
+
+	// defaultMaxLockout limita a duração de um bloqueio
+	defaultMaxLockout = time.Hour
+
+	// defaultFailureWindow é o tempo sem falhas após o qual a contagem
+	// recomeça, medido a partir da última falha ou do fim do último bloqueio
+	defaultFailureWindow = 15 * time.Minute
+
+	// loginThrottlePruneInterval define de quanto em quanto tempo os registros antigos são removidos
+	loginThrottlePruneInterval = 10 * time.Minute
+)
+
+// LoginGuardConfig define os limites de tentativas de login
+type LoginGuardConfig struct {
+	MaxAccountFailures int
+	MaxIPFailures      int
+	BaseLockout        time.Duration
+	MaxLockout         time.Duration
+	FailureWindow      time.Duration
+}
+
+// LoginGuard protege o login contra força bruta, contando as falhas por conta
+// e por IP e aplicando bloqueios com backoff exponencial. O estado fica no
+// Postgres, de modo que sobrevive a reinícios e vale para todas as instâncias.
+type LoginGuard struct {
+	db     *gorm.DB
+	config LoginGuardConfig
+
+	pruneMu   sync.Mutex
+	lastPrune time.Time
+}
+
+// NewLoginGuard cria uma nova instância do LoginGuard
+func NewLoginGuard(db *gorm.DB, config LoginGuardConfig) *LoginGuard {
+	return &LoginGuard{db: db, config: config}
+}
+
+// LoginGuardConfigFromEnv lê LOGIN_MAX_FAILURES, LOGIN_IP_MAX_FAILURES,
+// LOGIN_LOCKOUT_BASE, LOGIN_LOCKOUT_MAX e LOGIN_FAILURE_WINDOW
+func LoginGuardConfigFromEnv() (LoginGuardConfig, error) {
+	config := LoginGuardConfig{
+		MaxAccountFailures: defaultMaxAccountFailures,
+		MaxIPFailures:      defaultMaxIPFailures,
+		BaseLockout:        defaultBaseLockout,
+		MaxLockout:         defaultMaxLockout,
+		FailureWindow:      defaultFailureWindow,
+	}
+
+	for name, target := range map[string]*int{
+		"LOGIN_MAX_FAILURES":    &config.MaxAccountFailures,
+		"LOGIN_IP_MAX_FAILURES": &config.MaxIPFailures,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				return config, fmt.Errorf("%s inválido: %q", name, value)
+			}
+			*target = parsed
+		}
+	}
+
+	for name, target := range map[string]*time.Duration{
+		"LOGIN_LOCKOUT_BASE":   &config.BaseLockout,
+		"LOGIN_LOCKOUT_MAX":    &config.MaxLockout,
+		"LOGIN_FAILURE_WINDOW": &config.FailureWindow,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				return config, fmt.Errorf("%s inválido: %q", name, value)
+			}
+			*target = parsed
+		}
+	}
+
+	return config, nil
+}
+
+// Check retorna quanto tempo falta para a conta ou o IP serem desbloqueados;
+// zero quando o login é permitido
+func (g *LoginGuard) Check(username, ip string) (time.Duration, error) {
+	now := time.Now()
+
+	var rows []models.LoginThrottle
+	if err := g.db.Where("key IN ? AND locked_until > ?", []string{AccountThrottleKey(username), ipKey(ip)}, now).
+		Find(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	var retryAfter time.Duration
+	for _, row := range rows {
+		if remaining := row.LockedUntil.Sub(now); remaining > retryAfter {
+			retryAfter = remaining
+		}
+	}
+
+	return retryAfter, nil
+}
+
+// RecordFailure registra uma tentativa malsucedida para a conta e o IP,
+// bloqueando-os quando o limite de falhas é atingido
+func (g *LoginGuard) RecordFailure(username, ip string) error {
+	g.pruneIfStale()
+
+	if err := g.recordFailure(AccountThrottleKey(username), g.config.MaxAccountFailures); err != nil {
+		return err
+	}
+	return g.recordFailure(ipKey(ip), g.config.MaxIPFailures)
+}
+
+// RecordSuccess zera a contagem de falhas da conta. A contagem do IP é
+// mantida para que um login válido não libere novas tentativas contra outras contas.
+func (g *LoginGuard) RecordSuccess(username string) error {
+	return g.db.Where("key = ?", AccountThrottleKey(username)).Delete(&models.LoginThrottle{}).Error
+}
+
+// recordFailure incrementa atomicamente a contagem da chave e aplica o bloqueio
+func (g *LoginGuard) recordFailure(key string, maxFailures int) error {
+	now := time.Now()
+
+	// A contagem recomeça se a última falha e o fim do último bloqueio
+	// estiverem fora da janela. Medir só pela última falha zeraria o backoff
+	// logo após qualquer bloqueio mais longo que a janela.
+	var failures int
+	if err := g.db.Raw(`
+		INSERT INTO login_throttles ("key", failures, last_failure_at) VALUES (?, 1, ?)
+		ON CONFLICT ("key") DO UPDATE SET
+			failures = CASE WHEN GREATEST(login_throttles.last_failure_at, login_throttles.locked_until) < ? THEN 1 ELSE login_throttles.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures`, key, now, now.Add(-g.config.FailureWindow)).
+		Scan(&failures).Error; err != nil {
+		return err
+	}
+
+	if failures < maxFailures {
+		return nil
+	}
+
+	return g.db.Model(&models.LoginThrottle{}).
+		Where("key = ?", key).
+		Update("locked_until", now.Add(g.lockoutFor(failures-maxFailures))).Error
+}
+
+// lockoutFor calcula a duração do bloqueio dobrando-a a cada falha além do limite
+func (g *LoginGuard) lockoutFor(excess int) time.Duration {
+	lockout := g.config.BaseLockout
+	for i := 0; i < excess && lockout < g.config.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > g.config.MaxLockout {
+		lockout = g.config.MaxLockout
+	}
+	return lockout
+}
+
+// pruneIfStale remove periodicamente os registros sem falhas recentes nem bloqueio ativo
+func (g *LoginGuard) pruneIfStale() {
+	g.pruneMu.Lock()
+	if time.Since(g.lastPrune) < loginThrottlePruneInterval {
+		g.pruneMu.Unlock()
+		return
+	}
+	g.lastPrune = time.Now()
+	g.pruneMu.Unlock()
+
+	now := time.Now()
+	windowStart := now.Add(-g.config.FailureWindow)
+	g.db.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", windowStart, windowStart).
+		Delete(&models.LoginThrottle{})
+}
+
+// AccountThrottleKey monta a chave de controle de uma conta no bloqueio de login
+func AccountThrottleKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+// ipKey monta a chave de controle de um IP
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
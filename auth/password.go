@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algoritmos de hash de senha suportados
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+// BcryptMaxPasswordLength é o tamanho máximo, em bytes, de uma senha aceita pelo bcrypt
+const BcryptMaxPasswordLength = 72
+
+// Parâmetros padrão do argon2id, seguindo a recomendação mínima da OWASP
+// (19 MiB, 2 iterações, 1 thread)
+const (
+	defaultArgon2Memory      = 19 * 1024
+	defaultArgon2Iterations  = 2
+	defaultArgon2Parallelism = 1
+	argon2SaltLength         = 16
+	argon2KeyLength          = 32
+)
+
+// ErrUnknownPasswordHash indica um hash armazenado em formato não reconhecido
+var ErrUnknownPasswordHash = errors.New("formato de hash de senha desconhecido")
+
+// dummyHashes guarda, para cada configuração do hasher, o hash fixo usado por VerifyDummy
+var dummyHashes sync.Map
+
+// Argon2Params são os parâmetros de custo do argon2id
+type Argon2Params struct {
+	// Memória usada, em KiB
+	Memory uint32
+
+	// Quantidade de passagens sobre a memória
+	Iterations uint32
+
+	// Quantidade de threads
+	Parallelism uint8
+}
+
+// PasswordHasher gera e verifica hashes de senha. Novos hashes usam o
+// algoritmo configurado; hashes de outros algoritmos ou com parâmetros
+// diferentes continuam aceitos e são indicados para recálculo.
+type PasswordHasher struct {
+	// Algoritmo dos novos hashes (argon2id ou bcrypt)
+	Algorithm string
+
+	// Parâmetros dos hashes argon2id
+	Argon2 Argon2Params
+
+	// Custo dos hashes bcrypt
+	BcryptCost int
+}
+
+// DefaultPasswordHasher retorna o hasher padrão: argon2id com os parâmetros recomendados
+func DefaultPasswordHasher() *PasswordHasher {
+	return &PasswordHasher{
+		Algorithm: PasswordHashArgon2id,
+		Argon2: Argon2Params{
+			Memory:      defaultArgon2Memory,
+			Iterations:  defaultArgon2Iterations,
+			Parallelism: defaultArgon2Parallelism,
+		},
+		BcryptCost: bcrypt.DefaultCost,
+	}
+}
+
+// PasswordHasherFromEnv lê PASSWORD_HASH_ALGORITHM (argon2id ou bcrypt),
+// PASSWORD_ARGON2_MEMORY (KiB), PASSWORD_ARGON2_ITERATIONS,
+// PASSWORD_ARGON2_PARALLELISM e PASSWORD_BCRYPT_COST
+func PasswordHasherFromEnv() (*PasswordHasher, error) {
+	hasher := DefaultPasswordHasher()
+
+	if value := os.Getenv("PASSWORD_HASH_ALGORITHM"); value != "" {
+		if value != PasswordHashArgon2id && value != PasswordHashBcrypt {
+			return nil, fmt.Errorf("PASSWORD_HASH_ALGORITHM inválido: %q", value)
+		}
+		hasher.Algorithm = value
+	}
+
+	for name, target := range map[string]struct {
+		value *uint32
+		max   uint64
+	}{
+		"PASSWORD_ARGON2_MEMORY":     {&hasher.Argon2.Memory, 4 * 1024 * 1024},
+		"PASSWORD_ARGON2_ITERATIONS": {&hasher.Argon2.Iterations, 100},
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.ParseUint(value, 10, 32)
+			if err != nil || parsed == 0 || parsed > target.max {
+				return nil, fmt.Errorf("%s inválido: %q", name, value)
+			}
+			*target.value = uint32(parsed)
+		}
+	}
+
+	if value := os.Getenv("PASSWORD_ARGON2_PARALLELISM"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 8)
+		if err != nil || parsed == 0 {
+			return nil, fmt.Errorf("PASSWORD_ARGON2_PARALLELISM inválido: %q", value)
+		}
+		hasher.Argon2.Parallelism = uint8(parsed)
+	}
+	// O argon2 exige ao menos 8 KiB por thread
+	if hasher.Argon2.Memory < 8*uint32(hasher.Argon2.Parallelism) {
+		return nil, fmt.Errorf("PASSWORD_ARGON2_MEMORY deve ter ao menos 8 KiB por thread")
+	}
+
+	if value := os.Getenv("PASSWORD_BCRYPT_COST"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < bcrypt.MinCost || parsed > bcrypt.MaxCost {
+			return nil, fmt.Errorf("PASSWORD_BCRYPT_COST inválido: %q", value)
+		}
+		hasher.BcryptCost = parsed
+	}
+
+	return hasher, nil
+}
+
+// Hash gera o hash da senha com o algoritmo configurado. Hashes argon2id usam
+// o formato PHC ($argon2id$v=19$m=...,t=...,p=...$salt$hash) e hashes bcrypt
+// o formato modular ($2a$custo$...), ambos autodescritivos.
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.Algorithm == PasswordHashBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	params := h.Argon2
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify compara a senha com o hash armazenado. needsRehash indica que a
+// senha confere, mas o hash usa outro algoritmo ou parâmetros desatualizados
+// e deve ser substituído por Hash(password). Hashes vazios (contas sem senha,
+// como as criadas por login externo) nunca conferem.
+func (h *PasswordHasher) Verify(password, encoded string) (ok, needsRehash bool, err error) {
+	switch {
+	case encoded == "":
+		return false, false, nil
+
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		params, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, false, err
+		}
+		candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(candidate, key) != 1 {
+			return false, false, nil
+		}
+		outdated := h.Algorithm != PasswordHashArgon2id || params != h.Argon2 || len(key) != argon2KeyLength
+		return true, outdated, nil
+
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, false, nil
+			}
+			return false, false, err
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		if err != nil {
+			return false, false, err
+		}
+		return true, h.Algorithm != PasswordHashBcrypt || cost != h.BcryptCost, nil
+
+	default:
+		return false, false, ErrUnknownPasswordHash
+	}
+}
+
+// VerifyDummy compara a senha com um hash fixo gerado com o algoritmo e os
+// parâmetros configurados, sem resultado. Usado quando não há hash a verificar
+// (usuário inexistente ou sem senha), para que a resposta leve o mesmo tempo e
+// não revele quais usuários existem.
+func (h *PasswordHasher) VerifyDummy(password string) {
+	key := fmt.Sprintf("%s:%d:%d:%d:%d", h.Algorithm, h.Argon2.Memory, h.Argon2.Iterations, h.Argon2.Parallelism, h.BcryptCost)
+	encoded, ok := dummyHashes.Load(key)
+	if !ok {
+		hash, err := h.Hash("life-dummy-password")
+		if err != nil {
+			return
+		}
+		encoded, _ = dummyHashes.LoadOrStore(key, hash)
+	}
+	_, _, _ = h.Verify(password, encoded.(string))
+}
+
+// decodeArgon2id lê os parâmetros, o salt e a chave de um hash argon2id no formato PHC
+func decodeArgon2id(encoded string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("versão do argon2 não suportada: %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("parâmetros do argon2 inválidos: %q", parts[3])
+	}
+	if params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, fmt.Errorf("parâmetros do argon2 inválidos: %q", parts[3])
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	return params, salt, key, nil
+}
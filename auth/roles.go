@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// Papéis atribuídos aos usuários
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// RoleClient é o papel fixo dos tokens client_credentials, que não agem em nome
+// de nenhum usuário. Não pode ser atribuído a usuários e não concede permissões,
+// para que o cliente não herde os privilégios de quem o registrou.
+const RoleClient = "client"
+
+// Permission é uma ação que depende do papel do usuário, além de ser o dono do recurso
+type Permission string
+
+// Permissões verificadas pelas políticas de autorização
+const (
+	// Listar todos os usuários
+	PermissionUsersList Permission = "users.list"
+
+	// Consultar qualquer usuário
+	PermissionUsersRead Permission = "users.read"
+
+	// Alterar qualquer usuário
+	PermissionUsersWrite Permission = "users.write"
+
+	// Alterar o papel dos usuários
+	PermissionRolesManage Permission = "roles.manage"
+
+	// Suspender, banir e reabilitar usuários
+	PermissionUsersModerate Permission = "users.moderate"
+
+	// Consultar e exportar o log de auditoria
+	PermissionAuditRead Permission = "audit.read"
+)
+
+// rolePermissions define as permissões de cada papel; usuários comuns só
+// acessam os próprios recursos
+var rolePermissions = map[string][]Permission{
+	RoleUser:  {},
+	RoleAdmin: {PermissionUsersList, PermissionUsersRead, PermissionUsersWrite, PermissionRolesManage, PermissionUsersModerate, PermissionAuditRead},
+}
+
+// Roles retorna os papéis existentes em ordem alfabética
+func Roles() []string {
+	roles := make([]string, 0, len(rolePermissions))
+	for role := range rolePermissions {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// ValidRole informa se o papel existe
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission informa se o papel concede a permissão
+func RoleHasPermission(role string, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsConfiguredAdmin informa se o email está em ADMIN_USERS, a lista separada
+// por vírgulas de emails (ex: ana@empresa.com) ou domínios (ex: @empresa.com)
+// de administradores
+func IsConfiguredAdmin(email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return false
+	}
+
+	for _, admin := range strings.Split(os.Getenv("ADMIN_USERS"), ",") {
+		admin = strings.ToLower(strings.TrimSpace(admin))
+		if admin == "" {
+			continue
+		}
+		if admin == email || (strings.HasPrefix(admin, "@") && strings.HasSuffix(email, admin)) {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveRole retorna o papel usado na autorização: o papel do usuário ou
+// admin, se o email verificado estiver em ADMIN_USERS. Emails não verificados
+// nunca concedem o papel, pois qualquer um pode se registrar com eles.
+func EffectiveRole(role, email string, emailVerified bool) string {
+	if emailVerified && IsConfiguredAdmin(email) {
+		return RoleAdmin
+	}
+	return role
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Escopos que podem ser concedidos a clientes OAuth2
+const (
+	ScopeProfileRead  = "profile:read"
+	ScopeProfileWrite = "profile:write"
+	ScopeUsersRead    = "users:read"
+	ScopeScoresRead   = "scores:read"
+	ScopeScoresWrite  = "scores:write"
+)
+
+// Scopes descreve cada escopo disponível; as descrições são exibidas na tela de consentimento
+var Scopes = map[string]string{
+	ScopeProfileRead:  "Ler seu perfil (nome, nome de exibição e email)",
+	ScopeProfileWrite: "Alterar seu nome de exibição e email",
+	ScopeUsersRead:    "Consultar perfis de jogadores, conforme as permissões da sua conta",
+	ScopeScoresRead:   "Consultar pontuações",
+	ScopeScoresWrite:  "Registrar pontuações em seu nome",
+}
+
+// ParseScopes separa uma lista de escopos delimitada por espaços (RFC 6749, seção 3.3)
+func ParseScopes(scope string) []string {
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, s := range strings.Fields(scope) {
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// ValidateScopes verifica se todos os escopos existem no catálogo
+func ValidateScopes(scopes []string) error {
+	for _, s := range scopes {
+		if _, ok := Scopes[s]; !ok {
+			return fmt.Errorf("escopo desconhecido: %s", s)
+		}
+	}
+	return nil
+}
+
+// HasScope informa se o escopo está entre os concedidos
+func HasScope(granted []string, scope string) bool {
+	for _, s := range granted {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsScopes informa se todos os escopos pedidos estão entre os concedidos
+func ContainsScopes(granted, requested []string) bool {
+	for _, s := range requested {
+		if !HasScope(granted, s) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// APIKeyLookupLength é a quantidade de caracteres iniciais da API key
+	// armazenados em claro para localizar a chave e identificá-la ao usuário
+	APIKeyLookupLength = 12
+
+	// defaultAPIKeyRotationGrace é por quanto tempo a chave anterior continua
+	// válida após uma rotação
+	defaultAPIKeyRotationGrace = 24 * time.Hour
+)
+
+// HashSecret retorna o hash SHA-256 (hex) de um segredo aleatório de alta entropia.
+// Não deve ser usado para senhas escolhidas por usuários.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// SecretMatches compara em tempo constante um segredo com o hash armazenado
+func SecretMatches(secret, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashSecret(secret)), []byte(hash)) == 1
+}
+
+// APIKeyLookupPrefix retorna o prefixo usado para localizar uma API key no banco
+func APIKeyLookupPrefix(key string) string {
+	if len(key) < APIKeyLookupLength {
+		return key
+	}
+	return key[:APIKeyLookupLength]
+}
+
+// APIKeyRotationGraceFromEnv retorna por quanto tempo a chave anterior continua
+// aceita após uma rotação (API_KEY_ROTATION_GRACE, ex: "24h"; "0s" encerra na hora)
+func APIKeyRotationGraceFromEnv() (time.Duration, error) {
+	value := os.Getenv("API_KEY_ROTATION_GRACE")
+	if value == "" {
+		return defaultAPIKeyRotationGrace, nil
+	}
+
+	grace, err := time.ParseDuration(value)
+	if err != nil || grace < 0 {
+		return 0, fmt.Errorf("API_KEY_ROTATION_GRACE inválido: %q", value)
+	}
+	return grace, nil
+}
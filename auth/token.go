@@ -1,18 +1,358 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
-// GenerateToken gera um novo token JWT
-func GenerateToken(userID uint) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 1).Unix(),
-	})
+const (
+	// defaultAccessTTL é a validade padrão dos access tokens
+	defaultAccessTTL = time.Hour
 
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	// defaultIssuer é o emissor padrão (claim "iss")
+	defaultIssuer = "life-api"
+
+	// defaultAudience é o público padrão (claim "aud")
+	defaultAudience = "life-game"
+
+	// defaultLeeway é a tolerância padrão a diferenças de relógio
+	defaultLeeway = 30 * time.Second
+
+	// PurposeMFA identifica os tokens de desafio do login em duas etapas
+	PurposeMFA = "mfa"
+
+	// PurposeEmailVerification identifica os tokens de verificação de email
+	PurposeEmailVerification = "email_verification"
+
+	// PurposeAccountRestore identifica os tokens que cancelam a exclusão da conta
+	PurposeAccountRestore = "account_restore"
+
+	// emailVerificationTTL define a validade dos links de verificação de email
+	emailVerificationTTL = 24 * time.Hour
+)
+
+var (
+	// ErrInvalidClaims indica que o token não possui as claims obrigatórias
+	ErrInvalidClaims = errors.New("claims do token inválidas")
+
+	// ErrTokenRevoked indica que o token foi revogado antes de expirar
+	ErrTokenRevoked = errors.New("token revogado")
+)
+
+// TokenConfig define os parâmetros de emissão e validação dos access tokens
+type TokenConfig struct {
+	// Validade do access token
+	AccessTTL time.Duration
+
+	// Emissor (claim "iss")
+	Issuer string
+
+	// Público (claim "aud")
+	Audience string
+
+	// Tolerância a diferenças de relógio na validação de exp, nbf e iat
+	Leeway time.Duration
+}
+
+// Claims representa as claims dos access tokens
+type Claims struct {
+	UserID uint `json:"user_id"`
+
+	// Finalidade de tokens de uso restrito (ex: desafio de 2FA); vazio em access tokens
+	Purpose string `json:"purpose,omitempty"`
+
+	// Email ao qual o token está vinculado (tokens de verificação de email)
+	Email string `json:"email,omitempty"`
+
+	// Escopos concedidos, separados por espaço (RFC 9068); vazio em tokens
+	// primários, que dão acesso total à conta
+	Scope string `json:"scope,omitempty"`
+
+	// Cliente OAuth2 para o qual o token foi emitido
+	ClientID string `json:"client_id,omitempty"`
+
+	jwt.RegisteredClaims
+}
+
+// Delegated informa se o token foi emitido para um cliente OAuth2, e portanto
+// está restrito aos escopos concedidos
+func (c *Claims) Delegated() bool {
+	return c.ClientID != ""
+}
+
+// Scopes retorna os escopos concedidos
+func (c *Claims) Scopes() []string {
+	return ParseScopes(c.Scope)
+}
+
+// AccessToken representa um access token recém-emitido
+type AccessToken struct {
+	// Token JWT assinado
+	Token string
+
+	// Identificador único do token (claim "jti")
+	ID string
+
+	// Data de emissão
+	IssuedAt time.Time
+
+	// Data de expiração
+	ExpiresAt time.Time
+}
+
+// ExpiresIn retorna a validade do token em segundos
+func (t *AccessToken) ExpiresIn() int64 {
+	return int64(t.ExpiresAt.Sub(t.IssuedAt) / time.Second)
+}
+
+// TokenService centraliza a emissão e a validação dos access tokens
+type TokenService struct {
+	keys     *KeySet
+	config   TokenConfig
+	denylist *Denylist
+}
+
+// NewTokenService cria uma nova instância do TokenService. A denylist é
+// opcional; sem ela os tokens não podem ser revogados antes de expirar.
+func NewTokenService(keys *KeySet, config TokenConfig, denylist *Denylist) *TokenService {
+	return &TokenService{keys: keys, config: config, denylist: denylist}
+}
+
+// LoadTokenService cria o TokenService a partir das variáveis de ambiente
+func LoadTokenService(db *gorm.DB) (*TokenService, error) {
+	keys, err := LoadKeySet()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := TokenConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTokenService(keys, config, NewDenylist(db)), nil
+}
+
+// TokenConfigFromEnv lê JWT_ACCESS_TTL (ex: "15m"), JWT_ISSUER e JWT_AUDIENCE
+func TokenConfigFromEnv() (TokenConfig, error) {
+	config := TokenConfig{
+		AccessTTL: defaultAccessTTL,
+		Issuer:    defaultIssuer,
+		Audience:  defaultAudience,
+		Leeway:    defaultLeeway,
+	}
+
+	if ttl := os.Getenv("JWT_ACCESS_TTL"); ttl != "" {
+		parsed, err := time.ParseDuration(ttl)
+		if err != nil || parsed <= 0 {
+			return config, fmt.Errorf("JWT_ACCESS_TTL inválido: %q", ttl)
+		}
+		config.AccessTTL = parsed
+	}
+
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		config.Issuer = issuer
+	}
+
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		config.Audience = audience
+	}
+
+	return config, nil
+}
+
+// Keys retorna o KeySet usado pelo serviço
+func (s *TokenService) Keys() *KeySet {
+	return s.keys
+}
+
+// Issue emite um novo access token para o usuário
+func (s *TokenService) Issue(userID uint) (*AccessToken, error) {
+	claims, err := s.newClaims(userID, "", s.config.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.signAccessToken(claims)
+}
+
+// Validate verifica assinatura, emissor, público, validade temporal e
+// revogação de um access token
+func (s *TokenService) Validate(tokenString string) (*Claims, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens de uso restrito não dão acesso à API
+	if claims.Purpose != "" {
+		return nil, ErrInvalidClaims
+	}
+
+	if err := s.checkRevoked(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// IssuePurpose emite um token de curta duração válido apenas para a finalidade informada
+func (s *TokenService) IssuePurpose(userID uint, purpose string, ttl time.Duration) (string, error) {
+	claims, err := s.newClaims(userID, purpose, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	return s.keys.Sign(claims)
+}
+
+// IssueDelegated emite um access token para um cliente OAuth2, restrito aos
+// escopos concedidos. userID é zero em tokens do fluxo client_credentials, que
+// não agem em nome de nenhum usuário.
+func (s *TokenService) IssueDelegated(userID uint, clientID string, scopes []string) (*AccessToken, error) {
+	claims, err := s.newClaims(userID, "", s.config.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+	claims.ClientID = clientID
+	claims.Scope = strings.Join(scopes, " ")
+	if userID == 0 {
+		claims.Subject = "client:" + clientID
+	}
+
+	return s.signAccessToken(claims)
+}
+
+// IssueEmailVerification emite o token de verificação do email informado.
+// O token deixa de valer se o email do usuário for alterado.
+func (s *TokenService) IssueEmailVerification(userID uint, email string) (string, error) {
+	claims, err := s.newClaims(userID, PurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return "", err
+	}
+	claims.Email = email
+
+	return s.keys.Sign(claims)
+}
+
+// ValidatePurpose valida um token emitido por IssuePurpose para a finalidade informada
+func (s *TokenService) ValidatePurpose(tokenString, purpose string) (*Claims, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != purpose {
+		return nil, ErrInvalidClaims
+	}
+
+	if err := s.checkRevoked(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// checkRevoked consulta a denylist, quando configurada
+func (s *TokenService) checkRevoked(claims *Claims) error {
+	if s.denylist == nil {
+		return nil
+	}
+
+	revoked, err := s.denylist.IsRevoked(claims.ID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// signAccessToken assina as claims de um access token
+func (s *TokenService) signAccessToken(claims *Claims) (*AccessToken, error) {
+	token, err := s.keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccessToken{
+		Token:     token,
+		ID:        claims.ID,
+		IssuedAt:  claims.IssuedAt.Time,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+// newClaims monta as claims de um novo token com jti, iat e nbf
+func (s *TokenService) newClaims(userID uint, purpose string, ttl time.Duration) (*Claims, error) {
+	id, err := generateTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &Claims{
+		UserID:  userID,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			Issuer:    s.config.Issuer,
+			Audience:  jwt.ClaimStrings{s.config.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}, nil
+}
+
+// parse verifica assinatura, emissor, público e validade temporal de um token
+func (s *TokenService) parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, s.keys.Keyfunc,
+		jwt.WithValidMethods(SupportedMethods),
+		jwt.WithIssuer(s.config.Issuer),
+		jwt.WithAudience(s.config.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(s.config.Leeway),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apenas tokens de clientes OAuth2 podem não ter usuário
+	if claims.ID == "" || (claims.UserID == 0 && claims.ClientID == "") {
+		return nil, ErrInvalidClaims
+	}
+
+	return claims, nil
+}
+
+// Revoke invalida um access token até a sua expiração
+func (s *TokenService) Revoke(jti string, expiresAt time.Time) error {
+	if s.denylist == nil {
+		return errors.New("revogação de tokens não configurada")
+	}
+	return s.denylist.Revoke(jti, expiresAt)
+}
+
+// generateTokenID gera o identificador único (jti) de um token
+func generateTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
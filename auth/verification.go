@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EmailVerificationPolicy define o que é bloqueado enquanto o email do usuário não é verificado
+type EmailVerificationPolicy string
+
+const (
+	// EmailVerificationOff não bloqueia nada
+	EmailVerificationOff EmailVerificationPolicy = "off"
+
+	// EmailVerificationRoutes bloqueia apenas as rotas marcadas com RequireVerifiedEmail
+	EmailVerificationRoutes EmailVerificationPolicy = "routes"
+
+	// EmailVerificationLogin bloqueia o login e as rotas marcadas
+	EmailVerificationLogin EmailVerificationPolicy = "login"
+)
+
+// EmailVerificationPolicyFromEnv lê a política de EMAIL_VERIFICATION_POLICY (padrão: off)
+func EmailVerificationPolicyFromEnv() (EmailVerificationPolicy, error) {
+	switch policy := EmailVerificationPolicy(strings.ToLower(os.Getenv("EMAIL_VERIFICATION_POLICY"))); policy {
+	case "":
+		return EmailVerificationOff, nil
+	case EmailVerificationOff, EmailVerificationRoutes, EmailVerificationLogin:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("EMAIL_VERIFICATION_POLICY inválida: %s", policy)
+	}
+}
+
+// BlocksLogin indica se usuários não verificados são impedidos de fazer login
+func (p EmailVerificationPolicy) BlocksLogin() bool {
+	return p == EmailVerificationLogin
+}
+
+// BlocksRoutes indica se usuários não verificados são impedidos de acessar as rotas marcadas
+func (p EmailVerificationPolicy) BlocksRoutes() bool {
+	return p == EmailVerificationRoutes || p == EmailVerificationLogin
+}
@@ -1,21 +1,49 @@
 package config
 
 import (
+	"time"
+
+	"life/account"
+	"life/auth"
 	"life/handlers"
 	"life/logger"
+	"life/mail"
+	"life/middleware"
+	"life/oidc"
+	"life/ratelimit"
 	"life/routes"
+	"life/usage"
+	"life/validator"
 
+	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 )
 
 // Container gerencia as dependências da aplicação
 type Container struct {
-	DB            *gorm.DB
-	UserHandler   *handlers.UserHandler
-	AuthHandler   *handlers.AuthHandler
-	APIKeyHandler *handlers.APIKeyHandler
-	HealthHandler *handlers.HealthHandler
-	Router        *routes.Router
+	DB                   *gorm.DB
+	Tokens               *auth.TokenService
+	Mailer               mail.Mailer
+	EmailPolicy          auth.EmailVerificationPolicy
+	LoginGuard           *auth.LoginGuard
+	PasswordPolicy       *validator.PasswordPolicy
+	PasswordHasher       *auth.PasswordHasher
+	OIDCProviders        map[string]*oidc.Provider
+	TrustedProxies       []string
+	APIKeyRotationGrace  time.Duration
+	AccountDeletionGrace time.Duration
+	RateLimiter          ratelimit.RateLimiter
+	RateLimitPolicies    map[string]middleware.RateLimitPolicy
+	Usage                *usage.Recorder
+	AccountPurger        *account.Purger
+	UserHandler          *handlers.UserHandler
+	AuthHandler          *handlers.AuthHandler
+	APIKeyHandler        *handlers.APIKeyHandler
+	HealthHandler        *handlers.HealthHandler
+	SessionHandler       *handlers.SessionHandler
+	MFAHandler           *handlers.MFAHandler
+	PasswordHandler      *handlers.PasswordHandler
+	Router               *routes.Router
 }
 
 // NewContainer cria uma nova instância do container
@@ -29,21 +57,150 @@ func NewContainer() (*Container, error) {
 		return nil, err
 	}
 
+	// Inicializa o serviço de tokens
+	tokens, err := auth.LoadTokenService(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// Inicializa o envio de emails e a política de verificação
+	mailer, err := mail.NewFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	emailPolicy, err := auth.EmailVerificationPolicyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Inicializa a proteção contra força bruta no login
+	guardConfig, err := auth.LoginGuardConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	loginGuard := auth.NewLoginGuard(db, guardConfig)
+
+	// Política e hash de senhas (PASSWORD_*)
+	passwordPolicy, passwordHasher, err := PasswordSettingsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Inicializa os provedores OpenID Connect
+	oidcProviders, err := oidc.LoadProvidersFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Proxies reversos confiáveis para o IP do cliente (TRUSTED_PROXIES)
+	trustedProxies, err := routes.TrustedProxiesFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Período de transição na rotação de API keys
+	apiKeyRotationGrace, err := auth.APIKeyRotationGraceFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Inicializa o limitador de requisições (RATE_LIMIT_BACKEND)
+	rateLimiter, err := ratelimit.NewFromEnv(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// Políticas de limite por rota (RATE_LIMIT_<NOME>)
+	rateLimitPolicies, err := routes.RateLimitPoliciesFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Inicializa o registro em lote do uso das API keys (USAGE_FLUSH_INTERVAL)
+	usageFlushInterval, err := usage.FlushIntervalFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	usageRecorder := usage.NewRecorder(db, usageFlushInterval)
+
+	// Inicializa a remoção definitiva das contas excluídas (ACCOUNT_DELETION_GRACE, ACCOUNT_PURGE_INTERVAL)
+	accountDeletionGrace, err := account.DeletionGraceFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	accountPurgeInterval, err := account.PurgeIntervalFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	accountPurger := account.NewPurger(db, accountDeletionGrace, accountPurgeInterval)
+
 	// Inicializa os handlers
-	userHandler := handlers.NewUserHandler(db)
-	authHandler := handlers.NewAuthHandler(db)
-	apiKeyHandler := handlers.NewAPIKeyHandler(db)
+	userHandler := handlers.NewUserHandler(db, tokens, mailer, passwordPolicy, passwordHasher)
+	authHandler := handlers.NewAuthHandler(db, tokens, emailPolicy, loginGuard, passwordHasher)
+	apiKeyHandler := handlers.NewAPIKeyHandler(db, apiKeyRotationGrace)
 	healthHandler := handlers.NewHealthHandler(db)
+	sessionHandler := handlers.NewSessionHandler(db, tokens)
+	mfaHandler := handlers.NewMFAHandler(db, passwordHasher)
+	passwordHandler := handlers.NewPasswordHandler(db, tokens, mailer, passwordPolicy, passwordHasher)
 
 	// Inicializa o router
 	router := routes.NewRouter(db, userHandler, authHandler, apiKeyHandler, healthHandler)
 
 	return &Container{
-		DB:            db,
-		UserHandler:   userHandler,
-		AuthHandler:   authHandler,
-		APIKeyHandler: apiKeyHandler,
-		HealthHandler: healthHandler,
-		Router:        router,
+		DB:                   db,
+		Tokens:               tokens,
+		Mailer:               mailer,
+		EmailPolicy:          emailPolicy,
+		LoginGuard:           loginGuard,
+		PasswordPolicy:       passwordPolicy,
+		PasswordHasher:       passwordHasher,
+		OIDCProviders:        oidcProviders,
+		TrustedProxies:       trustedProxies,
+		APIKeyRotationGrace:  apiKeyRotationGrace,
+		AccountDeletionGrace: accountDeletionGrace,
+		RateLimiter:          rateLimiter,
+		RateLimitPolicies:    rateLimitPolicies,
+		Usage:                usageRecorder,
+		AccountPurger:        accountPurger,
+		UserHandler:          userHandler,
+		AuthHandler:          authHandler,
+		APIKeyHandler:        apiKeyHandler,
+		HealthHandler:        healthHandler,
+		SessionHandler:       sessionHandler,
+		MFAHandler:           mfaHandler,
+		PasswordHandler:      passwordHandler,
+		Router:               router,
 	}, nil
 }
+
+// Close interrompe as tarefas em segundo plano, gravando antes o uso pendente
+// das API keys. Deve ser chamado depois que o servidor parar de receber requisições.
+func (c *Container) Close() {
+	if err := c.Usage.Close(); err != nil {
+		log.Error().Err(err).Msg("Erro ao gravar o uso pendente das API keys")
+	}
+	c.AccountPurger.Close()
+}
+
+// RouterDependencies retorna os serviços compartilhados usados na configuração das rotas
+func (c *Container) RouterDependencies() routes.Dependencies {
+	return routes.Dependencies{
+		DB:          c.DB,
+		Tokens:      c.Tokens,
+		Mailer:      c.Mailer,
+		EmailPolicy: c.EmailPolicy,
+		LoginGuard:  c.LoginGuard,
+
+		OIDCProviders:  c.OIDCProviders,
+		TrustedProxies: c.TrustedProxies,
+
+		PasswordPolicy:       c.PasswordPolicy,
+		PasswordHasher:       c.PasswordHasher,
+		APIKeyRotationGrace:  c.APIKeyRotationGrace,
+		AccountDeletionGrace: c.AccountDeletionGrace,
+		RateLimiter:          c.RateLimiter,
+		RateLimitPolicies:    c.RateLimitPolicies,
+		Usage:                c.Usage,
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 
+	"life/audit"
+	"life/auth"
 	"life/models"
 
 	"gorm.io/driver/postgres"
@@ -25,11 +27,102 @@ func InitDB() (*gorm.DB, error) {
 		return nil, err
 	}
 
+	// Converte credenciais antigas armazenadas em texto puro
+	if err := migrateLegacyCredentials(db); err != nil {
+		return nil, err
+	}
+
 	// Migra as tabelas
-	err = db.AutoMigrate(&models.User{}, &models.APIKey{}, &models.RefreshToken{})
+	err = db.AutoMigrate(&models.User{}, &models.APIKey{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.RecoveryCode{}, &models.PasswordResetToken{}, &models.LoginThrottle{}, &models.Identity{}, &models.OIDCState{}, &models.OAuthClient{}, &models.OAuthAuthorizationCode{}, &models.OAuthConsent{}, &models.DeviceCode{}, &models.RateLimitBucket{}, &models.APIKeyUsage{}, &models.AuditEvent{})
 	if err != nil {
 		return nil, err
 	}
 
+	// Impede alterações e exclusões no log de auditoria
+	if err := protectAuditLog(db); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
+
+// migrateLegacyCredentials substitui as colunas de refresh tokens e API keys
+// armazenados em texto puro pelos respectivos hashes SHA-256. Precisa rodar
+// antes do AutoMigrate, que não consegue criar as novas colunas NOT NULL em
+// tabelas já populadas.
+func migrateLegacyCredentials(db *gorm.DB) error {
+	migrator := db.Migrator()
+
+	var statements []string
+	if migrator.HasTable("refresh_tokens") && migrator.HasColumn("refresh_tokens", "token") {
+		statements = append(statements,
+			"ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS token_hash text",
+			"UPDATE refresh_tokens SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex')",
+			"ALTER TABLE refresh_tokens DROP COLUMN token",
+		)
+	}
+
+	if migrator.HasTable("api_keys") && migrator.HasColumn("api_keys", "key") {
+		statements = append(statements,
+			"ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS prefix text, ADD COLUMN IF NOT EXISTS key_hash text",
+			fmt.Sprintf("UPDATE api_keys SET prefix = left(key, %d), key_hash = encode(sha256(convert_to(key, 'UTF8')), 'hex')", auth.APIKeyLookupLength),
+			"ALTER TABLE api_keys DROP COLUMN key",
+		)
+	}
+
+	if len(statements) == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// protectAuditLog cria o trigger que torna audit_events apenas de inserção,
+// mesmo para consultas feitas fora da aplicação. A única alteração aceita é a
+// pseudonimização feita por audit.Pseudonymize na remoção de contas: com a
+// configuração da transação ativa, o IP e o user agent podem ser apagados e
+// chaves podem ser removidas dos metadados, sem mudar os demais campos.
+func protectAuditLog(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			fmt.Sprintf(`CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+			BEGIN
+				IF TG_OP = 'UPDATE'
+					AND current_setting('%s', true) = 'on'
+					AND NEW.id = OLD.id
+					AND NEW.created_at IS NOT DISTINCT FROM OLD.created_at
+					AND NEW.actor_id IS NOT DISTINCT FROM OLD.actor_id
+					AND NEW.action = OLD.action
+					AND NEW.target_type IS NOT DISTINCT FROM OLD.target_type
+					AND NEW.target_id IS NOT DISTINCT FROM OLD.target_id
+					AND NEW.success IS NOT DISTINCT FROM OLD.success
+					AND NEW.request_id IS NOT DISTINCT FROM OLD.request_id
+					AND NEW.ip = ''
+					AND NEW.user_agent = ''
+					AND (NEW.metadata IS NOT DISTINCT FROM OLD.metadata OR NEW.metadata::jsonb <@ OLD.metadata::jsonb)
+				THEN
+					RETURN NEW;
+				END IF;
+				RAISE EXCEPTION 'audit_events é apenas de inserção';
+			END;
+			$$ LANGUAGE plpgsql`, audit.PseudonymizeSetting),
+			"DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events",
+			"CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events FOR EACH ROW EXECUTE FUNCTION audit_events_append_only()",
+			"DROP TRIGGER IF EXISTS audit_events_no_truncate ON audit_events",
+			"CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only()",
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
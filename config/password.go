@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"life/auth"
+	"life/validator"
+)
+
+// PasswordSettingsFromEnv lê a política de senhas (PASSWORD_*) e o hash de
+// senhas (PASSWORD_HASH_ALGORITHM e parâmetros). O bcrypt ignora o que passar
+// de 72 bytes: com ele, o tamanho máximo padrão passa a ser 72, e um
+// PASSWORD_MAX_LENGTH maior definido explicitamente é recusado.
+func PasswordSettingsFromEnv() (*validator.PasswordPolicy, *auth.PasswordHasher, error) {
+	policy, err := validator.PasswordPolicyFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hasher, err := auth.PasswordHasherFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if hasher.Algorithm == auth.PasswordHashBcrypt && policy.MaxLength > auth.BcryptMaxPasswordLength {
+		if os.Getenv("PASSWORD_MAX_LENGTH") != "" {
+			return nil, nil, fmt.Errorf("com PASSWORD_HASH_ALGORITHM=bcrypt, PASSWORD_MAX_LENGTH deve ser no máximo %d", auth.BcryptMaxPasswordLength)
+		}
+		policy.MaxLength = auth.BcryptMaxPasswordLength
+		if policy.MinLength > policy.MaxLength {
+			return nil, nil, fmt.Errorf("com PASSWORD_HASH_ALGORITHM=bcrypt, PASSWORD_MIN_LENGTH deve ser no máximo %d", auth.BcryptMaxPasswordLength)
+		}
+	}
+
+	return policy, hasher, nil
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"life/account"
+	"life/audit"
+	"life/auth"
+	"life/mail"
+	"life/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// AccountHandler gerencia a exclusão da conta e a exportação dos dados pelo próprio usuário
+type AccountHandler struct {
+	db     *gorm.DB
+	tokens *auth.TokenService
+	mailer mail.Mailer
+	hasher *auth.PasswordHasher
+	guard  *auth.LoginGuard
+
+	// Período em que a exclusão pode ser cancelada antes da remoção definitiva
+	grace time.Duration
+}
+
+// NewAccountHandler cria uma nova instância do AccountHandler
+func NewAccountHandler(db *gorm.DB, tokens *auth.TokenService, mailer mail.Mailer, hasher *auth.PasswordHasher, guard *auth.LoginGuard, grace time.Duration) *AccountHandler {
+	return &AccountHandler{db: db, tokens: tokens, mailer: mailer, hasher: hasher, guard: guard, grace: grace}
+}
+
+// AccountDeletionResponse representa uma exclusão de conta agendada
+// @Description Exclusão agendada; a conta pode ser restaurada pelo link enviado por email até purge_after
+type AccountDeletionResponse struct {
+	// Data a partir da qual a conta e seus dados são removidos definitivamente
+	PurgeAfter time.Time `json:"purge_after" example:"2024-06-24T20:00:00Z"`
+}
+
+// DeleteAccount agenda a exclusão da conta do usuário autenticado
+// @Summary Exclui a própria conta
+// @Description Desativa a conta, encerra todas as sessões e envia por email um link para cancelar a exclusão. Após o período de cancelamento (ACCOUNT_DELETION_GRACE), a conta, as API keys e as sessões são removidas definitivamente. Exige a senha atual, quando a conta possui uma, e o código de 2FA, quando ativo; falhas contam para o bloqueio de login da conta.
+// @Tags profile
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param credentials body map[string]string false "Senha (password) e código de 2FA (code)"
+// @Success 202 {object} handlers.AccountDeletionResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /profile [delete]
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	var deleteData struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+
+	// Contas sem senha e sem 2FA (ex: criadas por login externo) podem enviar o corpo vazio
+	if err := c.ShouldBindJSON(&deleteData); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	// Um token roubado não pode ser usado para adivinhar a senha: as falhas
+	// contam para o mesmo bloqueio do login
+	if rejectLocked(c, h.guard, user.Username) {
+		return
+	}
+
+	if user.Password != "" {
+		if ok, _, _ := h.hasher.Verify(deleteData.Password, user.Password); !ok {
+			audit.Record(h.db, c, audit.Event{Action: audit.ActionAccountDeletion, TargetType: audit.TargetUser, TargetID: user.ID})
+			h.rejectCredentials(c, &user, "Credenciais inválidas")
+			return
+		}
+	}
+
+	if user.TOTPEnabled {
+		ok, err := verifySecondFactor(h.db, &user, deleteData.Code)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar código"})
+			return
+		}
+		if !ok {
+			h.rejectCredentials(c, &user, "Código inválido")
+			return
+		}
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+		return revokeRefreshTokens(tx, h.tokens, "user_id = ?", user.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao excluir conta"})
+		return
+	}
+
+	// O token atual pode não estar vinculado a nenhuma sessão
+	if err := h.tokens.Revoke(c.GetString("token_id"), c.GetTime("token_expires_at")); err != nil {
+		log.Error().Err(err).Uint("user_id", user.ID).Msg("Erro ao revogar token após exclusão da conta")
+	}
+
+	purgeAfter := time.Now().Add(h.grace)
+	h.sendRestoreEmail(&user, purgeAfter)
+
+	audit.Record(h.db, c, audit.Event{
+		Action:     audit.ActionAccountDeletion,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Success:    true,
+		Metadata:   map[string]interface{}{"purge_after": purgeAfter},
+	})
+	c.JSON(http.StatusAccepted, AccountDeletionResponse{PurgeAfter: purgeAfter})
+}
+
+// rejectCredentials registra a falha no bloqueio de login da conta e responde 401
+func (h *AccountHandler) rejectCredentials(c *gin.Context, user *models.User, message string) {
+	if err := h.guard.RecordFailure(user.Username, c.ClientIP()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar tentativa"})
+		return
+	}
+
+	c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+}
+
+// RestoreAccount cancela a exclusão da conta a partir do token recebido por email
+// @Summary Cancela a exclusão da conta
+// @Description Reativa uma conta excluída durante o período de cancelamento. As sessões encerradas na exclusão não são restauradas; o usuário deve entrar novamente.
+// @Tags profile
+// @Accept json
+// @Param token body map[string]string true "Token de restauração"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Router /account/restore [post]
+func (h *AccountHandler) RestoreAccount(c *gin.Context) {
+	var restoreData struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&restoreData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+
+	claims, err := h.tokens.ValidatePurpose(restoreData.Token, auth.PurposeAccountRestore)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token inválido ou expirado"})
+		return
+	}
+
+	// A atualização condicional impede que o mesmo token seja usado duas vezes
+	result := h.db.Unscoped().Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", claims.UserID).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao restaurar conta"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token inválido ou expirado"})
+		return
+	}
+
+	if err := h.tokens.Revoke(claims.ID, claims.ExpiresAt.Time); err != nil {
+		log.Error().Err(err).Uint("user_id", claims.UserID).Msg("Erro ao revogar token de restauração da conta")
+	}
+
+	audit.Record(h.db, c, audit.Event{ActorID: claims.UserID, Action: audit.ActionAccountRestored, TargetType: audit.TargetUser, TargetID: claims.UserID, Success: true})
+	c.Status(http.StatusNoContent)
+}
+
+// ExportData gera um arquivo com todos os dados armazenados sobre o usuário autenticado
+// @Summary Exporta os dados da conta
+// @Description Retorna um arquivo zip com um JSON para cada tipo de dado armazenado: perfil, sessões, API keys e seu uso, identidades externas, clientes OAuth2, consentimentos e eventos de auditoria. Hashes de senhas, tokens e chaves não são incluídos.
+// @Tags profile
+// @Security Bearer
+// @Produce application/zip
+// @Success 200 {file} file
+// @Failure 401 {object} map[string]string
+// @Router /profile/export [get]
+func (h *AccountHandler) ExportData(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	// O arquivo é montado em memória para que uma falha resulte em erro, e não em um zip truncado
+	var archive bytes.Buffer
+	if err := account.Export(c.Request.Context(), h.db, userID, &archive); err != nil {
+		log.Error().Err(err).Uint("user_id", userID).Msg("Erro ao exportar dados da conta")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao exportar dados"})
+		return
+	}
+
+	audit.Record(h.db, c, audit.Event{Action: audit.ActionAccountExported, TargetType: audit.TargetUser, TargetID: userID, Success: true})
+
+	filename := fmt.Sprintf("life-export-%s.zip", time.Now().UTC().Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/zip", archive.Bytes())
+}
+
+// sendRestoreEmail envia o link para cancelar a exclusão da conta. Falhas são
+// apenas registradas: a exclusão já foi concluída.
+func (h *AccountHandler) sendRestoreEmail(user *models.User, purgeAfter time.Time) {
+	if h.grace <= 0 {
+		return
+	}
+
+	token, err := h.tokens.IssuePurpose(user.ID, auth.PurposeAccountRestore, h.grace)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.ID).Msg("Erro ao gerar token de restauração da conta")
+		return
+	}
+
+	link := fmt.Sprintf("%s/restore-account?token=%s", mail.AppURL(), url.QueryEscape(token))
+	err = h.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Sua conta será excluída",
+		Body: fmt.Sprintf("Olá, %s!\n\nRecebemos o pedido de exclusão da sua conta. Ela e todos os seus dados serão removidos definitivamente em %s. Para cancelar a exclusão, acesse o link abaixo:\n\n%s\n\nSe você não fez este pedido, acesse o link e altere sua senha.\n",
+			user.DisplayName, purgeAfter.UTC().Format("02/01/2006 15:04 UTC"), link),
+	})
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.ID).Msg("Erro ao enviar email de exclusão da conta")
+	}
+}
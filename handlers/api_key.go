@@ -3,21 +3,41 @@ package handlers
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"life/audit"
+	"life/auth"
 	"life/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
+const (
+	// apiKeyPrefix identifica visualmente as chaves emitidas pela API
+	apiKeyPrefix = "life_"
+
+	// apiKeyGenerationAttempts limita as chaves geradas quando o prefixo de
+	// busca sorteado já pertence a outra chave (índice único)
+	apiKeyGenerationAttempts = 3
+)
+
+// APIKeyHandler gerencia as chaves de API dos usuários
 type APIKeyHandler struct {
 	db *gorm.DB
+
+	// Período em que a chave anterior continua válida após uma rotação
+	rotationGrace time.Duration
 }
 
-func NewAPIKeyHandler(db *gorm.DB) *APIKeyHandler {
-	return &APIKeyHandler{db: db}
+// NewAPIKeyHandler cria uma nova instância do APIKeyHandler
+func NewAPIKeyHandler(db *gorm.DB, rotationGrace time.Duration) *APIKeyHandler {
+	return &APIKeyHandler{db: db, rotationGrace: rotationGrace}
 }
 
 // generateAPIKey gera uma nova chave de API segura
@@ -26,12 +46,18 @@ func generateAPIKey() (string, error) {
 	if _, err := rand.Read(bytes); err != nil {
 		return "", err
 	}
-	return base64.URLEncoding.EncodeToString(bytes), nil
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// isUniqueViolation informa se o erro do banco é uma violação de índice único
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
 
 // CreateAPIKey cria uma nova chave de API
 // @Summary Cria uma nova chave de API
-// @Description Cria uma nova chave de API para o usuário autenticado
+// @Description Cria uma nova chave de API para o usuário autenticado, restrita aos escopos informados. A chave completa é retornada apenas nesta resposta.
 // @Tags api-keys
 // @Accept json
 // @Produce json
@@ -49,29 +75,51 @@ func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
 		return
 	}
 
-	// Gera uma nova chave
-	key, err := generateAPIKey()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar chave de API"})
+	scopes, ok := bindAPIKeyScopes(c, apiKey.Scopes)
+	if !ok {
 		return
 	}
+	apiKey.Scopes = scopes
 
-	apiKey.Key = key
 	apiKey.UserID = userID
 	apiKey.CreatedAt = time.Now()
 	apiKey.UpdatedAt = time.Now()
 
-	if err := h.db.Create(&apiKey).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar chave de API"})
-		return
+	// Gera uma nova chave; se o prefixo colidir com o de outra chave, gera outra
+	for attempt := 1; ; attempt++ {
+		key, err := generateAPIKey()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar chave de API"})
+			return
+		}
+
+		apiKey.Key = key
+		apiKey.Prefix = auth.APIKeyLookupPrefix(key)
+		apiKey.KeyHash = auth.HashSecret(key)
+
+		err = h.db.Create(&apiKey).Error
+		if err == nil {
+			break
+		}
+		if !isUniqueViolation(err) || attempt == apiKeyGenerationAttempts {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar chave de API"})
+			return
+		}
 	}
 
+	audit.Record(h.db, c, audit.Event{
+		Action:     audit.ActionAPIKeyCreated,
+		TargetType: audit.TargetAPIKey,
+		TargetID:   apiKey.ID,
+		Success:    true,
+		Metadata:   map[string]interface{}{"name": apiKey.Name, "prefix": apiKey.Prefix, "scopes": apiKey.Scopes},
+	})
 	c.JSON(http.StatusCreated, apiKey)
 }
 
 // ListAPIKeys lista todas as chaves de API do usuário
 // @Summary Lista chaves de API
-// @Description Retorna todas as chaves de API do usuário autenticado
+// @Description Retorna todas as chaves de API do usuário autenticado, identificadas apenas pelo prefixo
 // @Tags api-keys
 // @Produce json
 // @Success 200 {array} models.APIKey
@@ -113,12 +161,14 @@ func (h *APIKeyHandler) DeleteAPIKey(c *gin.Context) {
 		return
 	}
 
+	apiKeyID, _ := strconv.ParseUint(id, 10, 0)
+	audit.Record(h.db, c, audit.Event{Action: audit.ActionAPIKeyDeleted, TargetType: audit.TargetAPIKey, TargetID: uint(apiKeyID), Success: true})
 	c.Status(http.StatusNoContent)
 }
 
 // UpdateAPIKey atualiza uma chave de API
 // @Summary Atualiza chave de API
-// @Description Atualiza os dados de uma chave de API específica
+// @Description Atualiza os dados de uma chave de API específica. Os escopos só são alterados quando informados.
 // @Tags api-keys
 // @Accept json
 // @Produce json
@@ -139,14 +189,25 @@ func (h *APIKeyHandler) UpdateAPIKey(c *gin.Context) {
 		return
 	}
 
+	// A chave nunca é devolvida fora da criação
+	apiKey.Key = ""
+
+	// Select garante a atualização também dos valores zero (ex: is_active false)
+	columns := []string{"name", "expires_at", "rate_limit", "is_active", "updated_at"}
+
+	if apiKey.Scopes != nil {
+		scopes, ok := bindAPIKeyScopes(c, apiKey.Scopes)
+		if !ok {
+			return
+		}
+		apiKey.Scopes = scopes
+		columns = append(columns, "scopes")
+	}
+
 	result := h.db.Model(&models.APIKey{}).
 		Where("id = ? AND user_id = ?", id, userID).
-		Updates(map[string]interface{}{
-			"name":       apiKey.Name,
-			"expires_at": apiKey.ExpiresAt,
-			"rate_limit": apiKey.RateLimit,
-			"is_active":  apiKey.IsActive,
-		})
+		Select(columns).
+		Updates(&apiKey)
 
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar chave de API"})
@@ -158,5 +219,98 @@ func (h *APIKeyHandler) UpdateAPIKey(c *gin.Context) {
 		return
 	}
 
+	apiKeyID, _ := strconv.ParseUint(id, 10, 0)
+	audit.Record(h.db, c, audit.Event{
+		Action:     audit.ActionAPIKeyUpdated,
+		TargetType: audit.TargetAPIKey,
+		TargetID:   uint(apiKeyID),
+		Success:    true,
+		Metadata:   map[string]interface{}{"name": apiKey.Name, "expires_at": apiKey.ExpiresAt, "rate_limit": apiKey.RateLimit, "is_active": apiKey.IsActive, "scopes": apiKey.Scopes},
+	})
 	c.JSON(http.StatusOK, apiKey)
 }
+
+// RotateAPIKey gera uma nova chave mantendo a anterior válida durante o período de transição
+// @Summary Rotaciona chave de API
+// @Description Gera uma nova chave, retornada apenas nesta resposta. A chave anterior continua aceita até previous_expires_at (API_KEY_ROTATION_GRACE); uma nova rotação encerra a transição da chave anterior a ela.
+// @Tags api-keys
+// @Security Bearer
+// @Produce json
+// @Param id path int true "ID da chave de API"
+// @Success 200 {object} models.APIKey
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api-keys/{id}/rotate [post]
+func (h *APIKeyHandler) RotateAPIKey(c *gin.Context) {
+	var apiKey models.APIKey
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("user_id")).First(&apiKey).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chave de API não encontrada"})
+		return
+	}
+
+	now := time.Now()
+	previousExpiresAt := now.Add(h.rotationGrace)
+
+	// Se o prefixo da nova chave colidir com o de outra chave, gera outra
+	var key string
+	var result *gorm.DB
+	for attempt := 1; ; attempt++ {
+		var err error
+		if key, err = generateAPIKey(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar chave de API"})
+			return
+		}
+
+		// A condição sobre o hash atual impede que rotações simultâneas percam uma das chaves
+		result = h.db.Model(&models.APIKey{}).
+			Where("id = ? AND key_hash = ?", apiKey.ID, apiKey.KeyHash).
+			Updates(map[string]interface{}{
+				"previous_prefix":     apiKey.Prefix,
+				"previous_key_hash":   apiKey.KeyHash,
+				"previous_expires_at": previousExpiresAt,
+				"prefix":              auth.APIKeyLookupPrefix(key),
+				"key_hash":            auth.HashSecret(key),
+				"rotated_at":          now,
+			})
+		if result.Error == nil {
+			break
+		}
+		if !isUniqueViolation(result.Error) || attempt == apiKeyGenerationAttempts {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao rotacionar chave de API"})
+			return
+		}
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A chave foi rotacionada por outra requisição"})
+		return
+	}
+
+	apiKey.Key = key
+	apiKey.Prefix = auth.APIKeyLookupPrefix(key)
+	apiKey.PreviousExpiresAt = &previousExpiresAt
+	apiKey.RotatedAt = &now
+
+	audit.Record(h.db, c, audit.Event{
+		Action:     audit.ActionAPIKeyRotated,
+		TargetType: audit.TargetAPIKey,
+		TargetID:   apiKey.ID,
+		Success:    true,
+		Metadata:   map[string]interface{}{"prefix": apiKey.Prefix, "previous_expires_at": previousExpiresAt},
+	})
+	c.JSON(http.StatusOK, apiKey)
+}
+
+// bindAPIKeyScopes valida os escopos de uma API key e os devolve normalizados.
+// Responde 400 listando os escopos disponíveis quando a lista é vazia ou inválida.
+func bindAPIKeyScopes(c *gin.Context, scopes []string) ([]string, bool) {
+	if len(scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe ao menos um escopo", "available_scopes": auth.Scopes})
+		return nil, false
+	}
+	if err := auth.ValidateScopes(scopes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "available_scopes": auth.Scopes})
+		return nil, false
+	}
+	return auth.ParseScopes(strings.Join(scopes, " ")), true
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"life/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultUsageDays é o período retornado quando from não é informado
+	defaultUsageDays = 30
+
+	// maxUsageDays limita o período consultado de uma vez
+	maxUsageDays = 366
+)
+
+// APIKeyUsageDay resume as requisições de um dia
+type APIKeyUsageDay struct {
+	Date     string           `json:"date" example:"2024-05-25"`
+	Requests int64            `json:"requests" example:"120"`
+	Errors   int64            `json:"errors" example:"3"`
+	Statuses map[string]int64 `json:"statuses"`
+}
+
+// APIKeyUsageRoute resume as requisições de uma rota no período
+type APIKeyUsageRoute struct {
+	Route    string `json:"route" example:"/api/v1/integrations/profile"`
+	Requests int64  `json:"requests" example:"120"`
+	Errors   int64  `json:"errors" example:"3"`
+}
+
+// APIKeyUsageResponse representa o uso de uma API key no período consultado
+type APIKeyUsageResponse struct {
+	APIKeyID  uint               `json:"api_key_id" example:"1"`
+	From      string             `json:"from" example:"2024-04-26"`
+	To        string             `json:"to" example:"2024-05-25"`
+	Requests  int64              `json:"requests" example:"3600"`
+	Errors    int64              `json:"errors" example:"36"`
+	ErrorRate float64            `json:"error_rate" example:"0.01"`
+	Days      []APIKeyUsageDay   `json:"days"`
+	Routes    []APIKeyUsageRoute `json:"routes"`
+}
+
+// GetAPIKeyUsage retorna a série diária de uso de uma API key
+// @Summary Uso da chave de API
+// @Description Retorna, por dia e por rota, as requisições feitas com a chave e as respostas de erro (status >= 400). As datas são em UTC e o uso é gravado em lote, podendo levar alguns segundos para aparecer.
+// @Tags api-keys
+// @Security Bearer
+// @Produce json
+// @Param id path int true "ID da chave de API"
+// @Param from query string false "Primeiro dia (YYYY-MM-DD; padrão: 29 dias antes de to)"
+// @Param to query string false "Último dia (YYYY-MM-DD; padrão: hoje)"
+// @Success 200 {object} handlers.APIKeyUsageResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api-keys/{id}/usage [get]
+func (h *APIKeyHandler) GetAPIKeyUsage(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	id := c.Param("id")
+
+	var apiKey models.APIKey
+	if err := h.db.Where("id = ? AND user_id = ?", id, userID).First(&apiKey).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chave de API não encontrada"})
+		return
+	}
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Data final inválida; use YYYY-MM-DD"})
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -(defaultUsageDays - 1))
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Data inicial inválida; use YYYY-MM-DD"})
+			return
+		}
+		from = parsed
+	}
+	if from.After(to) || to.Sub(from) >= maxUsageDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Período inválido", "max_days": maxUsageDays})
+		return
+	}
+
+	var rows []models.APIKeyUsage
+	if err := h.db.Where("api_key_id = ? AND day BETWEEN ? AND ?", apiKey.ID, from.Format(time.DateOnly), to.Format(time.DateOnly)).
+		Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar uso da chave de API"})
+		return
+	}
+
+	// Série com todos os dias do período, inclusive os sem requisições
+	response := APIKeyUsageResponse{
+		APIKeyID: apiKey.ID,
+		From:     from.Format(time.DateOnly),
+		To:       to.Format(time.DateOnly),
+		Days:     []APIKeyUsageDay{},
+		Routes:   []APIKeyUsageRoute{},
+	}
+	days := make(map[string]int)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		days[day.Format(time.DateOnly)] = len(response.Days)
+		response.Days = append(response.Days, APIKeyUsageDay{Date: day.Format(time.DateOnly), Statuses: map[string]int64{}})
+	}
+
+	routes := make(map[string]*APIKeyUsageRoute)
+	for _, row := range rows {
+		failed := int64(0)
+		if row.Status >= http.StatusBadRequest {
+			failed = row.Count
+		}
+
+		if i, ok := days[row.Day.UTC().Format(time.DateOnly)]; ok {
+			day := &response.Days[i]
+			day.Requests += row.Count
+			day.Errors += failed
+			day.Statuses[strconv.Itoa(row.Status)] += row.Count
+		}
+
+		route, ok := routes[row.Route]
+		if !ok {
+			route = &APIKeyUsageRoute{Route: row.Route}
+			routes[row.Route] = route
+		}
+		route.Requests += row.Count
+		route.Errors += failed
+
+		response.Requests += row.Count
+		response.Errors += failed
+	}
+
+	for _, route := range routes {
+		response.Routes = append(response.Routes, *route)
+	}
+	sort.Slice(response.Routes, func(i, j int) bool {
+		if response.Routes[i].Requests != response.Routes[j].Requests {
+			return response.Routes[i].Requests > response.Routes[j].Requests
+		}
+		return response.Routes[i].Route < response.Routes[j].Route
+	})
+
+	if response.Requests > 0 {
+		response.ErrorRate = float64(response.Errors) / float64(response.Requests)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"life/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultAuditLimit é a quantidade de eventos retornada quando limit não é informado
+	defaultAuditLimit = 50
+
+	// maxAuditLimit limita a quantidade de eventos por página
+	maxAuditLimit = 500
+
+	// auditExportBatchSize é a quantidade de eventos lidos do banco por vez na exportação
+	auditExportBatchSize = 500
+)
+
+// AuditHandler permite que administradores consultem e exportem o log de auditoria
+type AuditHandler struct {
+	db *gorm.DB
+}
+
+// NewAuditHandler cria uma nova instância do AuditHandler
+func NewAuditHandler(db *gorm.DB) *AuditHandler {
+	return &AuditHandler{db: db}
+}
+
+// AuditEventsResponse representa uma página do log de auditoria
+type AuditEventsResponse struct {
+	Events []models.AuditEvent `json:"events"`
+
+	// Valor de before_id para obter a próxima página; ausente na última página
+	NextBeforeID *uint `json:"next_before_id,omitempty" example:"120"`
+}
+
+// ListEvents lista os eventos do log de auditoria
+// @Summary Lista eventos de auditoria
+// @Description Retorna os eventos mais recentes primeiro. Os filtros podem ser combinados; use next_before_id como before_id para paginar.
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param actor_id query int false "Usuário que executou a ação"
+// @Param action query string false "Ação (ex: auth.login)"
+// @Param target_type query string false "Tipo do recurso afetado (ex: api_key)"
+// @Param target_id query string false "Identificador do recurso afetado"
+// @Param request_id query string false "ID da requisição (cabeçalho X-Request-ID)"
+// @Param success query bool false "Somente ações concluídas (true) ou recusadas (false)"
+// @Param from query string false "Início do período (RFC 3339)"
+// @Param to query string false "Fim do período (RFC 3339)"
+// @Param before_id query int false "Retorna eventos com ID menor que o informado"
+// @Param limit query int false "Quantidade de eventos (padrão: 50, máximo: 500)"
+// @Success 200 {object} handlers.AuditEventsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/audit-events [get]
+func (h *AuditHandler) ListEvents(c *gin.Context) {
+	query, ok := h.filter(c)
+	if !ok {
+		return
+	}
+
+	limit := defaultAuditLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Limite inválido"})
+			return
+		}
+		limit = min(parsed, maxAuditLimit)
+	}
+
+	if value := c.Query("before_id"); value != "" {
+		beforeID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before_id inválido"})
+			return
+		}
+		query = query.Where("id < ?", beforeID)
+	}
+
+	// Busca um evento a mais para saber se existe uma próxima página
+	var events []models.AuditEvent
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao consultar o log de auditoria"})
+		return
+	}
+
+	response := AuditEventsResponse{Events: events}
+	if len(events) > limit {
+		response.Events = events[:limit]
+		nextBeforeID := events[limit-1].ID
+		response.NextBeforeID = &nextBeforeID
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ExportEvents exporta os eventos do log de auditoria em JSON lines
+// @Summary Exporta eventos de auditoria
+// @Description Retorna todos os eventos que atendem aos filtros, um objeto JSON por linha, em ordem de inserção. Aceita os mesmos filtros da listagem.
+// @Tags admin
+// @Security Bearer
+// @Produce application/x-ndjson
+// @Param actor_id query int false "Usuário que executou a ação"
+// @Param action query string false "Ação (ex: auth.login)"
+// @Param target_type query string false "Tipo do recurso afetado (ex: api_key)"
+// @Param target_id query string false "Identificador do recurso afetado"
+// @Param request_id query string false "ID da requisição (cabeçalho X-Request-ID)"
+// @Param success query bool false "Somente ações concluídas (true) ou recusadas (false)"
+// @Param from query string false "Início do período (RFC 3339)"
+// @Param to query string false "Fim do período (RFC 3339)"
+// @Success 200 {string} string "Um models.AuditEvent por linha"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/audit-events/export [get]
+func (h *AuditHandler) ExportEvents(c *gin.Context) {
+	query, ok := h.filter(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="audit-events.jsonl"`)
+	c.Status(http.StatusOK)
+
+	// Os eventos são lidos e enviados em lotes para não carregar o log inteiro em memória
+	encoder := json.NewEncoder(c.Writer)
+	var batch []models.AuditEvent
+	err := query.FindInBatches(&batch, auditExportBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := encoder.Encode(&batch[i]); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+		return nil
+	}).Error
+	if err != nil {
+		// O status já foi enviado; resta registrar a exportação incompleta
+		log.Error().Err(err).Msg("Falha ao exportar o log de auditoria")
+	}
+}
+
+// filter aplica à consulta os filtros comuns à listagem e à exportação
+func (h *AuditHandler) filter(c *gin.Context) (*gorm.DB, bool) {
+	query := h.db.Model(&models.AuditEvent{})
+
+	if value := c.Query("actor_id"); value != "" {
+		actorID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "actor_id inválido"})
+			return nil, false
+		}
+		query = query.Where("actor_id = ?", actorID)
+	}
+
+	for _, column := range []string{"action", "target_type", "target_id", "request_id"} {
+		if value := c.Query(column); value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+
+	if value := c.Query("success"); value != "" {
+		success, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "success inválido"})
+			return nil, false
+		}
+		query = query.Where("success = ?", success)
+	}
+
+	for _, bound := range []struct {
+		param, condition string
+	}{
+		{"from", "created_at >= ?"},
+		{"to", "created_at <= ?"},
+	} {
+		if value := c.Query(bound.param); value != "" {
+			at, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Data inválida em " + bound.param + "; use RFC 3339"})
+				return nil, false
+			}
+			query = query.Where(bound.condition, at)
+		}
+	}
+
+	return query, true
+}
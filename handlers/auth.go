@@ -1,26 +1,35 @@
 package handlers
 
 import (
-	"crypto/rand"
-	"encoding/base64"
+	"errors"
+	"life/audit"
 	"life/auth"
 	"life/models"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 )
 
+// errRefreshTokenReused indica que um refresh token já rotacionado foi apresentado novamente
+var errRefreshTokenReused = errors.New("refresh token reutilizado")
+
 // AuthHandler gerencia as operações de autenticação
 type AuthHandler struct {
-	db *gorm.DB
+	db          *gorm.DB
+	tokens      *auth.TokenService
+	emailPolicy auth.EmailVerificationPolicy
+	guard       *auth.LoginGuard
+	hasher      *auth.PasswordHasher
 }
 
 // NewAuthHandler cria uma nova instância do AuthHandler
-func NewAuthHandler(db *gorm.DB) *AuthHandler {
-	return &AuthHandler{db: db}
+func NewAuthHandler(db *gorm.DB, tokens *auth.TokenService, emailPolicy auth.EmailVerificationPolicy, guard *auth.LoginGuard, hasher *auth.PasswordHasher) *AuthHandler {
+	return &AuthHandler{db: db, tokens: tokens, emailPolicy: emailPolicy, guard: guard, hasher: hasher}
 }
 
 // LoginResponse representa a resposta do login
@@ -39,21 +48,35 @@ type LoginResponse struct {
 	ExpiresIn int64 `json:"expires_in" example:"3600"`
 }
 
+// newLoginResponse monta a resposta de autenticação a partir dos tokens emitidos
+func newLoginResponse(accessToken *auth.AccessToken, refreshToken string) LoginResponse {
+	return LoginResponse{
+		AccessToken:  accessToken.Token,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    accessToken.ExpiresIn(),
+	}
+}
+
 // Login autentica um usuário e retorna tokens
 // @Summary Realiza login
-// @Description Autentica um usuário e retorna tokens
+// @Description Autentica um usuário e retorna tokens. Se a verificação em duas etapas estiver ativa, retorna um desafio a ser concluído em /login/2fa.
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Param credentials body map[string]string true "Credenciais de login"
+// @Param credentials body map[string]string true "Credenciais de login (username, password e device_name opcional)"
 // @Success 200 {object} handlers.LoginResponse
+// @Success 200 {object} handlers.MFAChallengeResponse
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 429 {object} map[string]string
 // @Router /login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var loginData struct {
-		Username string `json:"username" binding:"required"`
-		Password string `json:"password" binding:"required"`
+		Username   string `json:"username" binding:"required"`
+		Password   string `json:"password" binding:"required"`
+		DeviceName string `json:"device_name"`
 	}
 
 	if err := c.ShouldBindJSON(&loginData); err != nil {
@@ -61,54 +84,216 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if rejectLocked(c, h.guard, loginData.Username) {
+		return
+	}
+
 	var user models.User
 	if err := h.db.Where("username = ?", loginData.Username).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Credenciais inválidas"})
+		// Mesmo custo da verificação de um usuário existente
+		h.hasher.VerifyDummy(loginData.Password)
+		audit.Record(h.db, c, audit.Event{
+			Action:   audit.ActionLogin,
+			Metadata: map[string]interface{}{"username": loginData.Username, "reason": "unknown_user"},
+		})
+		h.recordFailure(c, loginData.Username)
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginData.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Credenciais inválidas"})
+	if user.Password == "" {
+		h.hasher.VerifyDummy(loginData.Password)
+	}
+	ok, needsRehash, err := h.hasher.Verify(loginData.Password, user.Password)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.ID).Msg("Hash de senha inválido")
+	}
+	if !ok {
+		audit.Record(h.db, c, audit.Event{
+			ActorID:    user.ID,
+			Action:     audit.ActionLogin,
+			TargetType: audit.TargetUser,
+			TargetID:   user.ID,
+			Metadata:   map[string]interface{}{"reason": "invalid_password"},
+		})
+		h.recordFailure(c, loginData.Username)
 		return
 	}
 
-	// Gera access token
-	accessToken, err := auth.GenerateToken(user.ID)
+	// Hashes de algoritmos ou parâmetros antigos são atualizados enquanto a senha está disponível
+	if needsRehash {
+		h.rehashPassword(&user, loginData.Password)
+	}
+
+	// Com a 2FA ativa, a contagem de falhas só é zerada após o segundo fator
+	if !user.TOTPEnabled {
+		if err := h.guard.RecordSuccess(user.Username); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar tentativa"})
+			return
+		}
+	}
+
+	completeLogin(c, h.db, h.tokens, h.emailPolicy, &user, loginData.DeviceName)
+}
+
+// rehashPassword substitui o hash de senha desatualizado de um usuário que
+// acabou de se autenticar; falhas são registradas sem interromper o login
+func (h *AuthHandler) rehashPassword(user *models.User, password string) {
+	hash, err := h.hasher.Hash(password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar token"})
+		log.Error().Err(err).Uint("user_id", user.ID).Msg("Erro ao recalcular hash de senha")
+		return
+	}
+
+	// A condição sobre o hash anterior evita sobrescrever uma troca de senha concorrente
+	if err := h.db.Model(&models.User{}).Where("id = ? AND password = ?", user.ID, user.Password).
+		Update("password", hash).Error; err != nil {
+		log.Error().Err(err).Uint("user_id", user.ID).Msg("Erro ao salvar hash de senha recalculado")
+		return
+	}
+	user.Password = hash
+}
+
+// completeLogin conclui a autenticação primária de um usuário: aplica a
+// política de verificação de email, recusa contas suspensas ou banidas e, se a 2FA estiver ativa, responde com o
+// desafio a ser concluído em /login/2fa; caso contrário inicia a sessão
+func completeLogin(c *gin.Context, db *gorm.DB, tokens *auth.TokenService, emailPolicy auth.EmailVerificationPolicy, user *models.User, deviceName string) {
+	if rejectRestricted(c, user) {
+		audit.Record(db, c, audit.Event{
+			ActorID:    user.ID,
+			Action:     audit.ActionLogin,
+			TargetType: audit.TargetUser,
+			TargetID:   user.ID,
+			Metadata:   map[string]interface{}{"reason": "account_restricted"},
+		})
+		return
+	}
+
+	if emailPolicy.BlocksLogin() && !user.EmailVerified {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Email não verificado"})
+		return
+	}
+
+	if user.TOTPEnabled {
+		mfaToken, err := tokens.IssuePurpose(user.ID, auth.PurposeMFA, mfaChallengeTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar token"})
+			return
+		}
+
+		c.JSON(http.StatusOK, MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresIn:   int64(mfaChallengeTTL.Seconds()),
+		})
+		return
+	}
+
+	response, err := startSession(db, tokens, c, user.ID, deviceName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao iniciar sessão"})
+		return
+	}
+
+	audit.Record(db, c, audit.Event{ActorID: user.ID, Action: audit.ActionLogin, TargetType: audit.TargetUser, TargetID: user.ID, Success: true})
+	c.JSON(http.StatusOK, response)
+}
+
+// LoginMFA conclui o login de um usuário com verificação em duas etapas
+// @Summary Conclui login com 2FA
+// @Description Valida o token de desafio retornado por /login junto com um código TOTP ou de recuperação e retorna os tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param challenge body map[string]string true "Token de desafio (mfa_token), código (code) e device_name opcional"
+// @Success 200 {object} handlers.LoginResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} models.AccountRestriction
+// @Failure 429 {object} map[string]string
+// @Router /login/2fa [post]
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var mfaData struct {
+		MFAToken   string `json:"mfa_token" binding:"required"`
+		Code       string `json:"code" binding:"required"`
+		DeviceName string `json:"device_name"`
+	}
+
+	if err := c.ShouldBindJSON(&mfaData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
 		return
 	}
 
-	// Gera refresh token
-	refreshToken, err := generateRefreshToken()
+	claims, err := h.tokens.ValidatePurpose(mfaData.MFAToken, auth.PurposeMFA)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar refresh token"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Desafio inválido ou expirado"})
 		return
 	}
 
-	// Salva refresh token no banco
-	rt := models.RefreshToken{
-		Token:     refreshToken,
-		UserID:    user.ID,
-		ExpiresAt: time.Now().Add(24 * time.Hour * 7), // 7 dias
+	var user models.User
+	if err := h.db.First(&user, claims.UserID).Error; err != nil || !user.TOTPEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Desafio inválido ou expirado"})
+		return
 	}
 
-	if err := h.db.Create(&rt).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar refresh token"})
+	// Os códigos errados contam para o mesmo bloqueio das senhas erradas
+	if rejectLocked(c, h.guard, user.Username) {
 		return
 	}
 
-	c.JSON(http.StatusOK, LoginResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		TokenType:    "Bearer",
-		ExpiresIn:    3600, // 1 hora
-	})
+	// A conta pode ter sido suspensa após a primeira etapa
+	if rejectRestricted(c, &user) {
+		return
+	}
+
+	ok, err := verifySecondFactor(h.db, &user, mfaData.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar código"})
+		return
+	}
+	if !ok {
+		audit.Record(h.db, c, audit.Event{
+			ActorID:    user.ID,
+			Action:     audit.ActionLoginMFA,
+			TargetType: audit.TargetUser,
+			TargetID:   user.ID,
+			Metadata:   map[string]interface{}{"reason": "invalid_code"},
+		})
+		if err := h.guard.RecordFailure(user.Username, c.ClientIP()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar tentativa"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Código inválido"})
+		return
+	}
+
+	if err := h.guard.RecordSuccess(user.Username); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar tentativa"})
+		return
+	}
+
+	// O desafio é de uso único
+	if err := h.tokens.Revoke(claims.ID, claims.ExpiresAt.Time); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao revogar token"})
+		return
+	}
+
+	response, err := startSession(h.db, h.tokens, c, user.ID, mfaData.DeviceName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao iniciar sessão"})
+		return
+	}
+
+	audit.Record(h.db, c, audit.Event{ActorID: user.ID, Action: audit.ActionLoginMFA, TargetType: audit.TargetUser, TargetID: user.ID, Success: true})
+	c.JSON(http.StatusOK, response)
 }
 
 // Refresh atualiza o access token usando o refresh token
+//
+// Cada chamada rotaciona o refresh token: o token apresentado é revogado e um
+// novo token da mesma família é devolvido. Se um token já revogado for
+// apresentado novamente, toda a família é revogada.
 // @Summary Atualiza access token
-// @Description Atualiza o access token usando o refresh token
+// @Description Atualiza o access token e rotaciona o refresh token. Reapresentar um refresh token já utilizado revoga toda a sessão.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -116,6 +301,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 // @Success 200 {object} handlers.LoginResponse
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} models.AccountRestriction
 // @Router /refresh [post]
 func (h *AuthHandler) Refresh(c *gin.Context) {
 	var refreshData struct {
@@ -128,30 +314,159 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 	}
 
 	var rt models.RefreshToken
-	if err := h.db.Where("token = ? AND is_revoked = ? AND expires_at > ?",
-		refreshData.RefreshToken, false, time.Now()).First(&rt).Error; err != nil {
+	if err := h.db.Where("token_hash = ?", auth.HashSecret(refreshData.RefreshToken)).First(&rt).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token inválido ou expirado"})
+		return
+	}
+
+	// Um token revogado sendo reapresentado indica vazamento: encerra a família inteira
+	if rt.IsRevoked {
+		h.rejectReusedToken(c, rt)
+		return
+	}
+
+	if time.Now().After(rt.ExpiresAt) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token inválido ou expirado"})
 		return
 	}
 
+	var user models.User
+	if err := h.db.First(&user, rt.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token inválido ou expirado"})
+		return
+	}
+	if rejectRestricted(c, &user) {
+		return
+	}
+
 	// Gera novo access token
-	accessToken, err := auth.GenerateToken(rt.UserID)
+	accessToken, err := h.tokens.Issue(rt.UserID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, LoginResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshData.RefreshToken,
-		TokenType:    "Bearer",
-		ExpiresIn:    3600, // 1 hora
+	var newRefreshToken string
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Revoga o token atual; se outra requisição já o rotacionou, trata como reutilização
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND is_revoked = ?", rt.ID, false).
+			Update("is_revoked", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenReused
+		}
+
+		// Tokens emitidos antes da rotação não possuem família
+		familyID := rt.FamilyID
+		if familyID == "" {
+			var err error
+			if familyID, err = generateFamilyID(); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		newRefreshToken, err = issueRefreshToken(tx, &models.RefreshToken{
+			UserID:     rt.UserID,
+			FamilyID:   familyID,
+			ParentID:   &rt.ID,
+			DeviceName: rt.DeviceName,
+			UserAgent:  c.Request.UserAgent(),
+			IP:         c.ClientIP(),
+		}, accessToken)
+		return err
 	})
+
+	if errors.Is(err, errRefreshTokenReused) {
+		h.rejectReusedToken(c, rt)
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao rotacionar refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newLoginResponse(accessToken, newRefreshToken))
+}
+
+// rejectRestricted responde 403 com o motivo se a conta estiver suspensa ou
+// banida. Retorna se a requisição foi encerrada.
+func rejectRestricted(c *gin.Context, user *models.User) bool {
+	restriction := user.Restriction(time.Now())
+	if restriction == nil {
+		return false
+	}
+
+	c.JSON(http.StatusForbidden, restriction)
+	return true
+}
+
+// rejectLocked responde 429 com Retry-After se a conta ou o IP estiverem
+// bloqueados por excesso de tentativas. Retorna se a requisição foi encerrada.
+// O IP só vem de X-Forwarded-For atrás de um proxy em TRUSTED_PROXIES, então
+// trocar o cabeçalho não libera novas tentativas.
+func rejectLocked(c *gin.Context, guard *auth.LoginGuard, username string) bool {
+	retryAfter, err := guard.Check(username, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar tentativas de login"})
+		return true
+	}
+	if retryAfter <= 0 {
+		return false
+	}
+
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Muitas tentativas de login. Tente novamente mais tarde.",
+		"retry_after": seconds,
+	})
+	return true
+}
+
+// recordFailure registra uma tentativa de login malsucedida e responde 401
+func (h *AuthHandler) recordFailure(c *gin.Context, username string) {
+	if err := h.guard.RecordFailure(username, c.ClientIP()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar tentativa"})
+		return
+	}
+
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Credenciais inválidas"})
+}
+
+// rejectReusedToken revoga toda a família de um token reutilizado e responde 401
+func (h *AuthHandler) rejectReusedToken(c *gin.Context, rt models.RefreshToken) {
+	if err := h.revokeFamily(h.db, rt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao revogar sessão"})
+		return
+	}
+
+	audit.Record(h.db, c, audit.Event{
+		ActorID:    rt.UserID,
+		Action:     audit.ActionRefreshReuse,
+		TargetType: audit.TargetSession,
+		TargetID:   rt.ID,
+		Metadata:   map[string]interface{}{"family_id": rt.FamilyID},
+	})
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reutilizado; a sessão foi encerrada"})
+}
+
+// revokeFamily revoga todos os refresh tokens da mesma família de rt e os
+// access tokens emitidos junto com eles
+func (h *AuthHandler) revokeFamily(tx *gorm.DB, rt models.RefreshToken) error {
+	if rt.FamilyID == "" {
+		return revokeRefreshTokens(tx, h.tokens, "id = ?", rt.ID)
+	}
+	return revokeRefreshTokens(tx, h.tokens, "family_id = ?", rt.FamilyID)
 }
 
 // Logout revoga um refresh token
 // @Summary Realiza logout
-// @Description Revoga um refresh token
+// @Description Encerra a sessão do refresh token informado, revogando também os access tokens emitidos para ela
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -170,28 +485,43 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	result := h.db.Model(&models.RefreshToken{}).
-		Where("token = ?", logoutData.RefreshToken).
-		Update("is_revoked", true)
-
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao revogar token"})
+	var rt models.RefreshToken
+	if err := h.db.Where("token_hash = ?", auth.HashSecret(logoutData.RefreshToken)).First(&rt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token não encontrado"})
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Token não encontrado"})
+	if err := h.revokeFamily(h.db, rt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao revogar token"})
 		return
 	}
 
+	audit.Record(h.db, c, audit.Event{ActorID: rt.UserID, Action: audit.ActionLogout, TargetType: audit.TargetSession, TargetID: rt.ID, Success: true})
 	c.Status(http.StatusNoContent)
 }
 
-// generateRefreshToken gera um token de atualização seguro
-func generateRefreshToken() (string, error) {
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
-		return "", err
+// LogoutAll encerra todas as sessões do usuário autenticado
+// @Summary Encerra todas as sessões
+// @Description Revoga todos os refresh tokens do usuário e todos os access tokens ainda válidos, inclusive o usado nesta requisição
+// @Tags auth
+// @Security Bearer
+// @Success 204 "No Content"
+// @Failure 401 {object} map[string]string
+// @Router /logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	if err := revokeRefreshTokens(h.db, h.tokens, "user_id = ?", userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao revogar sessões"})
+		return
+	}
+
+	// O token atual pode não estar vinculado a nenhuma sessão
+	if err := h.tokens.Revoke(c.GetString("token_id"), c.GetTime("token_expires_at")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao revogar token"})
+		return
 	}
-	return base64.URLEncoding.EncodeToString(b), nil
+
+	audit.Record(h.db, c, audit.Event{Action: audit.ActionLogoutAll, TargetType: audit.TargetUser, TargetID: userID, Success: true})
+	c.Status(http.StatusNoContent)
 }
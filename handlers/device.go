@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"life/auth"
+	"life/mail"
+	"life/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// deviceCodeTTL define por quanto tempo o código exibido no dispositivo é válido
+	deviceCodeTTL = 10 * time.Minute
+
+	// devicePollInterval é o intervalo mínimo inicial entre consultas do dispositivo
+	devicePollInterval = 5 * time.Second
+)
+
+// DeviceHandler implementa o login de dispositivos sem teclado (consoles e TVs)
+// pelo fluxo device authorization grant (RFC 8628)
+type DeviceHandler struct {
+	db     *gorm.DB
+	tokens *auth.TokenService
+}
+
+// NewDeviceHandler cria uma nova instância do DeviceHandler
+func NewDeviceHandler(db *gorm.DB, tokens *auth.TokenService) *DeviceHandler {
+	return &DeviceHandler{db: db, tokens: tokens}
+}
+
+// DeviceCodeResponse representa a resposta da solicitação de código (RFC 8628, seção 3.2)
+type DeviceCodeResponse struct {
+	DeviceCode              string `json:"device_code" example:"GmRhmhcxhwAzkoEqiMEg_DnyEysNkuNhszIySk9eS"`
+	UserCode                string `json:"user_code" example:"WDJB-MJHT"`
+	VerificationURI         string `json:"verification_uri" example:"http://localhost:8080/device"`
+	VerificationURIComplete string `json:"verification_uri_complete" example:"http://localhost:8080/device?user_code=WDJB-MJHT"`
+	ExpiresIn               int64  `json:"expires_in" example:"600"`
+	Interval                int64  `json:"interval" example:"5"`
+}
+
+// DeviceAuthorizationInfo descreve o dispositivo que aguarda aprovação
+type DeviceAuthorizationInfo struct {
+	UserCode   string    `json:"user_code" example:"WDJB-MJHT"`
+	DeviceName string    `json:"device_name" example:"Console da sala"`
+	ExpiresAt  time.Time `json:"expires_at" example:"2024-05-25T20:10:00Z"`
+}
+
+// DeviceApprovalData representa a decisão do usuário sobre um dispositivo
+type DeviceApprovalData struct {
+	UserCode string `json:"user_code" binding:"required" example:"WDJB-MJHT"`
+	Approve  *bool  `json:"approve" binding:"required" example:"true"`
+}
+
+// RequestCode inicia o login de um dispositivo
+// @Summary Solicita código de dispositivo
+// @Description Gera o device_code consultado pelo dispositivo e o user_code que o jogador digita em verification_uri
+// @Tags device
+// @Accept json
+// @Produce json
+// @Param device body map[string]string false "Nome do dispositivo (device_name)"
+// @Success 200 {object} handlers.DeviceCodeResponse
+// @Failure 400 {object} map[string]string
+// @Router /device/code [post]
+func (h *DeviceHandler) RequestCode(c *gin.Context) {
+	var requestData struct {
+		DeviceName string `json:"device_name"`
+	}
+	if err := c.ShouldBindJSON(&requestData); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos", "details": err.Error()})
+		return
+	}
+
+	deviceCode, err := generateClientSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar código"})
+		return
+	}
+	userCode, err := auth.GenerateUserCode()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar código"})
+		return
+	}
+
+	// Aproveita para descartar autorizações expiradas
+	h.db.Where("expires_at <= ?", time.Now()).Delete(&models.DeviceCode{})
+
+	if err := h.db.Create(&models.DeviceCode{
+		DeviceCodeHash: auth.HashSecret(deviceCode),
+		UserCode:       userCode,
+		DeviceName:     requestData.DeviceName,
+		Status:         models.DeviceCodePending,
+		PollInterval:   int(devicePollInterval.Seconds()),
+		ExpiresAt:      time.Now().Add(deviceCodeTTL),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar dispositivo"})
+		return
+	}
+
+	verificationURI := mail.AppURL() + "/device"
+	c.JSON(http.StatusOK, DeviceCodeResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                auth.FormatUserCode(userCode),
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + url.QueryEscape(auth.FormatUserCode(userCode)),
+		ExpiresIn:               int64(deviceCodeTTL.Seconds()),
+		Interval:                int64(devicePollInterval.Seconds()),
+	})
+}
+
+// Token é consultado pelo dispositivo até que o jogador aprove o código
+// @Summary Consulta autorização do dispositivo
+// @Description Retorna os tokens após a aprovação. Enquanto isso responde 400 com error igual a authorization_pending, slow_down (consultas antes do intervalo), access_denied ou expired_token.
+// @Tags device
+// @Accept json
+// @Produce json
+// @Param device body map[string]string true "Código do dispositivo (device_code)"
+// @Success 200 {object} handlers.LoginResponse
+// @Failure 400 {object} map[string]string
+// @Router /device/token [post]
+func (h *DeviceHandler) Token(c *gin.Context) {
+	var tokenData struct {
+		DeviceCode string `json:"device_code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&tokenData); err != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_request", "device_code é obrigatório")
+		return
+	}
+
+	var device models.DeviceCode
+	if err := h.db.Where("device_code_hash = ?", auth.HashSecret(tokenData.DeviceCode)).First(&device).Error; err != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "device_code inválido")
+		return
+	}
+
+	now := time.Now()
+	if !device.ExpiresAt.After(now) {
+		oauthError(c, http.StatusBadRequest, "expired_token", "Código expirado; solicite um novo")
+		return
+	}
+
+	// Consultas antes do intervalo aumentam o intervalo em 5 segundos (RFC 8628, seção 3.5)
+	result := h.db.Model(&models.DeviceCode{}).
+		Where("id = ? AND (last_polled_at IS NULL OR last_polled_at <= ?)", device.ID, now.Add(-time.Duration(device.PollInterval)*time.Second)).
+		Update("last_polled_at", now)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao consultar autorização"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		h.db.Model(&models.DeviceCode{}).Where("id = ?", device.ID).Update("poll_interval", gorm.Expr("poll_interval + ?", int(devicePollInterval.Seconds())))
+		oauthError(c, http.StatusBadRequest, "slow_down", "Consultas muito frequentes")
+		return
+	}
+
+	switch device.Status {
+	case models.DeviceCodePending:
+		oauthError(c, http.StatusBadRequest, "authorization_pending", "Aguardando aprovação do usuário")
+		return
+	case models.DeviceCodeDenied:
+		oauthError(c, http.StatusBadRequest, "access_denied", "O usuário recusou o dispositivo")
+		return
+	case models.DeviceCodeApproved:
+	default:
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "device_code já utilizado")
+		return
+	}
+
+	// A conta pode ter sido suspensa após a aprovação
+	var user models.User
+	if device.UserID == nil || h.db.First(&user, *device.UserID).Error != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "device_code inválido")
+		return
+	}
+	if restriction := user.Restriction(time.Now()); restriction != nil {
+		oauthError(c, http.StatusBadRequest, "access_denied", restriction.Error)
+		return
+	}
+
+	// Cada aprovação inicia uma única sessão
+	result = h.db.Model(&models.DeviceCode{}).
+		Where("id = ? AND status = ?", device.ID, models.DeviceCodeApproved).
+		Update("status", models.DeviceCodeConsumed)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao consumir autorização"})
+		return
+	}
+	if result.RowsAffected == 0 || device.UserID == nil {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "device_code já utilizado")
+		return
+	}
+
+	deviceName := device.DeviceName
+	if deviceName == "" {
+		deviceName = "Dispositivo " + auth.FormatUserCode(device.UserCode)
+	}
+
+	response, err := startSession(h.db, h.tokens, c, *device.UserID, deviceName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao iniciar sessão"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetAuthorization retorna o dispositivo que aguarda aprovação com o código informado
+// @Summary Consulta dispositivo pendente
+// @Description Retorna os dados do dispositivo para confirmação antes da aprovação
+// @Tags device
+// @Security Bearer
+// @Produce json
+// @Param user_code query string true "Código exibido no dispositivo"
+// @Success 200 {object} handlers.DeviceAuthorizationInfo
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /device [get]
+func (h *DeviceHandler) GetAuthorization(c *gin.Context) {
+	var device models.DeviceCode
+	if err := h.pendingDevice(c.Query("user_code")).First(&device).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Código inválido ou expirado"})
+		return
+	}
+
+	c.JSON(http.StatusOK, DeviceAuthorizationInfo{
+		UserCode:   auth.FormatUserCode(device.UserCode),
+		DeviceName: device.DeviceName,
+		ExpiresAt:  device.ExpiresAt,
+	})
+}
+
+// Approve registra a decisão do usuário autenticado sobre um dispositivo
+// @Summary Aprova ou recusa dispositivo
+// @Description Vincula o dispositivo à conta autenticada (approve verdadeiro) ou recusa o login
+// @Tags device
+// @Security Bearer
+// @Accept json
+// @Param approval body handlers.DeviceApprovalData true "Código do dispositivo e decisão"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /device/approve [post]
+func (h *DeviceHandler) Approve(c *gin.Context) {
+	var approvalData DeviceApprovalData
+	if err := c.ShouldBindJSON(&approvalData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos", "details": err.Error()})
+		return
+	}
+
+	status := models.DeviceCodeDenied
+	if *approvalData.Approve {
+		status = models.DeviceCodeApproved
+	}
+
+	userID := c.GetUint("user_id")
+	result := h.pendingDevice(approvalData.UserCode).
+		Updates(map[string]interface{}{"status": status, "user_id": userID})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar decisão"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Código inválido ou expirado"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// pendingDevice filtra a autorização pendente e válida com o código digitado
+func (h *DeviceHandler) pendingDevice(userCode string) *gorm.DB {
+	return h.db.Model(&models.DeviceCode{}).
+		Where("user_code = ? AND status = ? AND expires_at > ?", auth.NormalizeUserCode(userCode), models.DeviceCodePending, time.Now())
+}
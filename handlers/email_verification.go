@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"life/audit"
+	"life/auth"
+	"life/mail"
+	"life/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// EmailVerificationHandler gerencia a confirmação do email dos usuários
+type EmailVerificationHandler struct {
+	db     *gorm.DB
+	tokens *auth.TokenService
+	mailer mail.Mailer
+}
+
+// NewEmailVerificationHandler cria uma nova instância do EmailVerificationHandler
+func NewEmailVerificationHandler(db *gorm.DB, tokens *auth.TokenService, mailer mail.Mailer) *EmailVerificationHandler {
+	return &EmailVerificationHandler{db: db, tokens: tokens, mailer: mailer}
+}
+
+// VerifyEmail confirma o email de um usuário
+// @Summary Confirma o email
+// @Description Confirma o email do usuário a partir do token enviado por email
+// @Tags auth
+// @Accept json
+// @Param token body map[string]string true "Token de verificação"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Router /verify-email [post]
+func (h *EmailVerificationHandler) VerifyEmail(c *gin.Context) {
+	var verifyData struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&verifyData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+
+	claims, err := h.tokens.ValidatePurpose(verifyData.Token, auth.PurposeEmailVerification)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token inválido ou expirado"})
+		return
+	}
+
+	// O token só vale para o email ao qual foi emitido
+	var user models.User
+	if err := h.db.First(&user, claims.UserID).Error; err != nil || !strings.EqualFold(user.Email, claims.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token inválido ou expirado"})
+		return
+	}
+
+	if !user.EmailVerified {
+		if err := h.db.Model(&user).Updates(map[string]interface{}{
+			"email_verified":    true,
+			"email_verified_at": time.Now(),
+		}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar email"})
+			return
+		}
+		audit.Record(h.db, c, audit.Event{
+			ActorID:    user.ID,
+			Action:     audit.ActionEmailVerified,
+			TargetType: audit.TargetUser,
+			TargetID:   user.ID,
+			Success:    true,
+			Metadata:   map[string]interface{}{"email": user.Email},
+		})
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ResendVerification reenvia o email de verificação
+// @Summary Reenvia o email de verificação
+// @Description Reenvia o link de verificação. A resposta é sempre 202 para não revelar quais emails estão cadastrados.
+// @Tags auth
+// @Accept json
+// @Param email body map[string]string true "Email cadastrado"
+// @Success 202 "Accepted"
+// @Failure 400 {object} map[string]string
+// @Router /verify-email/resend [post]
+func (h *EmailVerificationHandler) ResendVerification(c *gin.Context) {
+	var resendData struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&resendData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.Where("email = ?", resendData.Email).First(&user).Error; err == nil && !user.EmailVerified {
+		sendVerificationEmail(h.tokens, h.mailer, &user)
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// sendVerificationEmail envia o link de verificação para o email atual do
+// usuário. Falhas são apenas registradas: o usuário pode solicitar o reenvio.
+func sendVerificationEmail(tokens *auth.TokenService, mailer mail.Mailer, user *models.User) {
+	token, err := tokens.IssueEmailVerification(user.ID, user.Email)
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.ID).Msg("Erro ao gerar token de verificação de email")
+		return
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", mail.AppURL(), url.QueryEscape(token))
+	err = mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Confirme seu email",
+		Body: fmt.Sprintf("Olá, %s!\n\nPara confirmar seu email, acesse o link abaixo:\n\n%s\n\nO link expira em 24 horas. Se você não criou uma conta, ignore esta mensagem.\n",
+			user.DisplayName, link),
+	})
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.ID).Msg("Erro ao enviar email de verificação")
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"life/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler publica as chaves públicas usadas para verificar os JWTs
+type JWKSHandler struct {
+	keys *auth.KeySet
+}
+
+// NewJWKSHandler cria uma nova instância do JWKSHandler
+func NewJWKSHandler(keys *auth.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// JWKS retorna as chaves públicas de verificação
+// @Summary Chaves públicas de verificação
+// @Description Retorna o JWK Set com todas as chaves aceitas na verificação dos access tokens
+// @Tags auth
+// @Produce json
+// @Success 200 {object} auth.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
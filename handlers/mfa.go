@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"life/audit"
+	"life/auth"
+	"life/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// mfaChallengeTTL define por quanto tempo o desafio de 2FA do login é válido
+	mfaChallengeTTL = 5 * time.Minute
+
+	// recoveryCodeCount é a quantidade de códigos de recuperação gerados na ativação
+	recoveryCodeCount = 10
+)
+
+// MFAHandler gerencia a verificação em duas etapas (TOTP) do usuário
+type MFAHandler struct {
+	db     *gorm.DB
+	hasher *auth.PasswordHasher
+}
+
+// NewMFAHandler cria uma nova instância do MFAHandler
+func NewMFAHandler(db *gorm.DB, hasher *auth.PasswordHasher) *MFAHandler {
+	return &MFAHandler{db: db, hasher: hasher}
+}
+
+// MFASetupResponse representa os dados para cadastrar o segredo no aplicativo autenticador
+// @Description Segredo TOTP pendente de confirmação
+type MFASetupResponse struct {
+	// Segredo em base32 para digitação manual
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+
+	// URI otpauth:// para geração do QR code
+	OTPAuthURI string `json:"otpauth_uri" example:"otpauth://totp/Life:jogador?secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP&issuer=Life"`
+}
+
+// RecoveryCodesResponse representa os códigos de recuperação gerados
+// @Description Códigos de recuperação de uso único, exibidos apenas uma vez
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"ABCDE-FGHJK,LMNPQ-RSTUV"`
+}
+
+// MFAChallengeResponse representa a resposta do login quando a 2FA está ativa
+// @Description Desafio de verificação em duas etapas
+type MFAChallengeResponse struct {
+	// Sempre true; indica que o login deve ser concluído em /login/2fa
+	MFARequired bool `json:"mfa_required" example:"true"`
+
+	// Token de desafio a ser enviado junto com o código
+	MFAToken string `json:"mfa_token" example:"eyJhbGciOiJFZERTQSIsImtpZCI6IjIwMjQtMDUifQ..."`
+
+	// Tempo de expiração do desafio em segundos
+	ExpiresIn int64 `json:"expires_in" example:"300"`
+}
+
+// Setup gera um novo segredo TOTP para o usuário autenticado
+// @Summary Inicia a configuração da 2FA
+// @Description Gera um segredo TOTP e a URI otpauth. A 2FA só passa a valer após a confirmação em /2fa/enable.
+// @Tags 2fa
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} handlers.MFASetupResponse
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /2fa/setup [post]
+func (h *MFAHandler) Setup(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Verificação em duas etapas já está ativa"})
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar segredo"})
+		return
+	}
+
+	if err := h.db.Model(&user).Update("totp_secret", secret).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar segredo"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MFASetupResponse{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(auth.TOTPIssuer(), user.Username, secret),
+	})
+}
+
+// Enable confirma o segredo TOTP e ativa a 2FA
+// @Summary Ativa a 2FA
+// @Description Confirma o segredo gerado em /2fa/setup com um código do aplicativo e retorna os códigos de recuperação
+// @Tags 2fa
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param code body map[string]string true "Código TOTP atual"
+// @Success 200 {object} handlers.RecoveryCodesResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /2fa/enable [post]
+func (h *MFAHandler) Enable(c *gin.Context) {
+	var enableData struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&enableData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Verificação em duas etapas já está ativa"})
+		return
+	}
+
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Configuração da verificação em duas etapas não iniciada"})
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(user.TOTPSecret, strings.TrimSpace(enableData.Code), time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Código inválido"})
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar códigos de recuperação"})
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, user.ID, codes)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao ativar verificação em duas etapas"})
+		return
+	}
+
+	audit.Record(h.db, c, audit.Event{Action: audit.ActionMFAEnabled, TargetType: audit.TargetUser, TargetID: user.ID, Success: true})
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable desativa a 2FA do usuário autenticado
+// @Summary Desativa a 2FA
+// @Description Desativa a verificação em duas etapas. Exige a senha e um código TOTP ou de recuperação.
+// @Tags 2fa
+// @Security Bearer
+// @Accept json
+// @Param credentials body map[string]string true "Senha e código"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /2fa/disable [post]
+func (h *MFAHandler) Disable(c *gin.Context) {
+	var disableData struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&disableData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Verificação em duas etapas não está ativa"})
+		return
+	}
+
+	if ok, _, _ := h.hasher.Verify(disableData.Password, user.Password); !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Credenciais inválidas"})
+		return
+	}
+
+	ok, err := verifySecondFactor(h.db, &user, disableData.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar código"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Código inválido"})
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled":   false,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao desativar verificação em duas etapas"})
+		return
+	}
+
+	audit.Record(h.db, c, audit.Event{Action: audit.ActionMFADisabled, TargetType: audit.TargetUser, TargetID: user.ID, Success: true})
+	c.Status(http.StatusNoContent)
+}
+
+// replaceRecoveryCodes substitui os códigos de recuperação do usuário pelos informados
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	rows := make([]models.RecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		rows = append(rows, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: auth.HashSecret(auth.NormalizeRecoveryCode(code)),
+		})
+	}
+
+	return tx.Create(&rows).Error
+}
+
+// verifySecondFactor valida um código TOTP ou de recuperação do usuário.
+// Ambos são consumidos com atualizações condicionais, de modo que o mesmo
+// código não seja aceito duas vezes mesmo em requisições concorrentes.
+func verifySecondFactor(db *gorm.DB, user *models.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		result := db.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		return result.RowsAffected == 1, result.Error
+	}
+
+	result := db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, auth.HashSecret(auth.NormalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"life/audit"
+	"life/auth"
+	"life/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ModerationHandler permite que administradores suspendam, banam e
+// reabilitem jogadores
+type ModerationHandler struct {
+	db     *gorm.DB
+	tokens *auth.TokenService
+}
+
+// NewModerationHandler cria uma nova instância do ModerationHandler
+func NewModerationHandler(db *gorm.DB, tokens *auth.TokenService) *ModerationHandler {
+	return &ModerationHandler{db: db, tokens: tokens}
+}
+
+// SuspendData representa os dados de uma suspensão temporária
+type SuspendData struct {
+	Reason string    `json:"reason" binding:"required" example:"Linguagem ofensiva no chat"`
+	Until  time.Time `json:"until" binding:"required" example:"2024-06-01T00:00:00Z"`
+}
+
+// BanData representa os dados de um banimento permanente
+type BanData struct {
+	Reason string `json:"reason" binding:"required" example:"Uso de trapaças"`
+}
+
+// Suspend suspende um usuário até a data informada
+// @Summary Suspende usuário
+// @Description Bloqueia o login, a renovação de tokens e o uso de tokens e API keys do usuário até a data informada. Todas as sessões são encerradas.
+// @Tags admin
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path int true "ID do usuário"
+// @Param suspension body handlers.SuspendData true "Motivo e fim da suspensão"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id}/suspend [post]
+func (h *ModerationHandler) Suspend(c *gin.Context) {
+	var suspendData SuspendData
+	if err := c.ShouldBindJSON(&suspendData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos", "details": err.Error()})
+		return
+	}
+	if !suspendData.Until.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O fim da suspensão deve estar no futuro"})
+		return
+	}
+
+	until := suspendData.Until
+	h.restrict(c, models.UserStatusSuspended, suspendData.Reason, &until)
+}
+
+// Ban bane um usuário permanentemente
+// @Summary Bane usuário
+// @Description Bloqueia permanentemente o acesso do usuário e encerra todas as sessões. Pode ser desfeito com reinstate.
+// @Tags admin
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path int true "ID do usuário"
+// @Param ban body handlers.BanData true "Motivo do banimento"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id}/ban [post]
+func (h *ModerationHandler) Ban(c *gin.Context) {
+	var banData BanData
+	if err := c.ShouldBindJSON(&banData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos", "details": err.Error()})
+		return
+	}
+
+	h.restrict(c, models.UserStatusBanned, banData.Reason, nil)
+}
+
+// Reinstate encerra a suspensão ou o banimento de um usuário
+// @Summary Reabilita usuário
+// @Description Remove a suspensão ou o banimento; o usuário precisa fazer login novamente
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path int true "ID do usuário"
+// @Success 200 {object} models.User
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id}/reinstate [post]
+func (h *ModerationHandler) Reinstate(c *gin.Context) {
+	user, ok := h.findTarget(c)
+	if !ok {
+		return
+	}
+
+	previousStatus := user.Status
+	if err := h.db.Model(user).Updates(map[string]interface{}{
+		"status":            models.UserStatusActive,
+		"suspension_reason": "",
+		"suspended_until":   nil,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar usuário"})
+		return
+	}
+
+	audit.Record(h.db, c, audit.Event{
+		Action:     audit.ActionUserReinstated,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Success:    true,
+		Metadata:   map[string]interface{}{"previous_status": previousStatus},
+	})
+
+	// Remove a senha do response
+	user.Password = ""
+	c.JSON(http.StatusOK, user)
+}
+
+// restrict aplica a suspensão ou o banimento e encerra as sessões do usuário
+func (h *ModerationHandler) restrict(c *gin.Context, status, reason string, until *time.Time) {
+	user, ok := h.findTarget(c)
+	if !ok {
+		return
+	}
+
+	// Administradores precisam ser rebaixados antes de serem moderados
+	if auth.EffectiveRole(user.Role, user.Email, user.EmailVerified) == auth.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Administradores não podem ser suspensos ou banidos"})
+		return
+	}
+
+	if err := h.db.Model(user).Updates(map[string]interface{}{
+		"status":            status,
+		"suspension_reason": reason,
+		"suspended_until":   until,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar usuário"})
+		return
+	}
+
+	// Os access tokens também são recusados pelo AuthMiddleware enquanto a
+	// restrição valer; revogar as sessões impede que voltem a valer depois dela
+	if err := revokeRefreshTokens(h.db, h.tokens, "user_id = ?", user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao revogar sessões"})
+		return
+	}
+
+	action := audit.ActionUserSuspended
+	if status == models.UserStatusBanned {
+		action = audit.ActionUserBanned
+	}
+	audit.Record(h.db, c, audit.Event{
+		Action:     action,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Success:    true,
+		Metadata:   map[string]interface{}{"reason": reason, "suspended_until": until},
+	})
+
+	// Remove a senha do response
+	user.Password = ""
+	c.JSON(http.StatusOK, user)
+}
+
+// findTarget busca o usuário moderado, recusando o próprio administrador
+func (h *ModerationHandler) findTarget(c *gin.Context) (*models.User, bool) {
+	var user models.User
+	if err := h.db.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return nil, false
+	}
+
+	if user.ID == c.GetUint("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Não é possível moderar a própria conta"})
+		return nil, false
+	}
+
+	return &user, true
+}
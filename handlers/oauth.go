@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"life/auth"
+	"life/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// oauthCodeTTL define a validade dos códigos de autorização
+const oauthCodeTTL = 5 * time.Minute
+
+// OAuthHandler implementa o servidor de autorização OAuth2 (RFC 6749) para
+// aplicações de terceiros, com PKCE obrigatório no fluxo authorization code
+type OAuthHandler struct {
+	db     *gorm.DB
+	tokens *auth.TokenService
+}
+
+// NewOAuthHandler cria uma nova instância do OAuthHandler
+func NewOAuthHandler(db *gorm.DB, tokens *auth.TokenService) *OAuthHandler {
+	return &OAuthHandler{db: db, tokens: tokens}
+}
+
+// AuthorizeRequest representa os parâmetros de uma solicitação de autorização
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type" binding:"required" example:"code"`
+	ClientID            string `form:"client_id" json:"client_id" binding:"required" example:"3f6c1e0a9b2d4c7e8f1a2b3c4d5e6f70"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri" example:"https://stats.example.com/callback"`
+	Scope               string `form:"scope" json:"scope" example:"profile:read scores:read"`
+	State               string `form:"state" json:"state" example:"xyz"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge" example:"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method" example:"S256"`
+}
+
+// AuthorizeDecision representa a decisão do usuário sobre a autorização
+type AuthorizeDecision struct {
+	AuthorizeRequest
+	Approve bool `json:"approve" example:"true"`
+}
+
+// ScopeDescription descreve um escopo na tela de consentimento
+type ScopeDescription struct {
+	Scope       string `json:"scope" example:"profile:read"`
+	Description string `json:"description" example:"Ler seu perfil (nome, nome de exibição e email)"`
+}
+
+// ConsentResponse contém os dados exibidos na tela de consentimento
+type ConsentResponse struct {
+	ClientID        string             `json:"client_id" example:"3f6c1e0a9b2d4c7e8f1a2b3c4d5e6f70"`
+	ClientName      string             `json:"client_name" example:"Life Stats Tracker"`
+	Scopes          []ScopeDescription `json:"scopes"`
+	RedirectURI     string             `json:"redirect_uri" example:"https://stats.example.com/callback"`
+	State           string             `json:"state,omitempty" example:"xyz"`
+	ConsentRequired bool               `json:"consent_required" example:"true"`
+}
+
+// RedirectResponse indica para onde o navegador do usuário deve ser redirecionado
+type RedirectResponse struct {
+	RedirectTo string `json:"redirect_to" example:"https://stats.example.com/callback?code=...&state=xyz"`
+}
+
+// OAuthTokenResponse representa a resposta do endpoint de token (RFC 6749, seção 5.1)
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token" example:"eyJhbGciOiJFZERTQSIs..."`
+	TokenType   string `json:"token_type" example:"Bearer"`
+	ExpiresIn   int64  `json:"expires_in" example:"3600"`
+	Scope       string `json:"scope" example:"profile:read scores:read"`
+}
+
+// Authorize valida a solicitação de autorização e retorna os dados da tela de consentimento
+// @Summary Inicia uma autorização OAuth2
+// @Description Valida cliente, redirect_uri, escopos e PKCE (S256) e retorna o que deve ser exibido ao usuário. consent_required é falso quando um consentimento anterior já cobre os escopos.
+// @Tags oauth
+// @Security Bearer
+// @Produce json
+// @Param response_type query string true "Deve ser code"
+// @Param client_id query string true "Identificador do cliente"
+// @Param redirect_uri query string false "URI de retorno registrada"
+// @Param scope query string false "Escopos separados por espaço; padrão: todos os do cliente"
+// @Param state query string false "Valor devolvido no redirecionamento"
+// @Param code_challenge query string true "code_challenge PKCE"
+// @Param code_challenge_method query string true "Deve ser S256"
+// @Success 200 {object} handlers.ConsentResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /oauth/authorize [get]
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	var request AuthorizeRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_request", "Parâmetros de autorização inválidos")
+		return
+	}
+
+	client, redirectURI, scopes, ok := h.validateAuthorization(c, request)
+	if !ok {
+		return
+	}
+
+	descriptions := make([]ScopeDescription, len(scopes))
+	for i, scope := range scopes {
+		descriptions[i] = ScopeDescription{Scope: scope, Description: auth.Scopes[scope]}
+	}
+
+	var consent models.OAuthConsent
+	consentRequired := h.db.Where("user_id = ? AND client_id = ?", c.GetUint("user_id"), client.ClientID).
+		First(&consent).Error != nil || !auth.ContainsScopes(consent.Scopes, scopes)
+
+	c.JSON(http.StatusOK, ConsentResponse{
+		ClientID:        client.ClientID,
+		ClientName:      client.Name,
+		Scopes:          descriptions,
+		RedirectURI:     redirectURI,
+		State:           request.State,
+		ConsentRequired: consentRequired,
+	})
+}
+
+// Decide registra a decisão do usuário e emite o código de autorização
+// @Summary Conclui uma autorização OAuth2
+// @Description Registra o consentimento e retorna a URL de retorno com o código de autorização (válido por 5 minutos e uma única vez), ou com error=access_denied se o usuário recusou
+// @Tags oauth
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param decision body handlers.AuthorizeDecision true "Parâmetros da autorização e decisão do usuário"
+// @Success 200 {object} handlers.RedirectResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /oauth/authorize [post]
+func (h *OAuthHandler) Decide(c *gin.Context) {
+	var decision AuthorizeDecision
+	if err := c.ShouldBindJSON(&decision); err != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_request", "Parâmetros de autorização inválidos")
+		return
+	}
+
+	client, redirectURI, scopes, ok := h.validateAuthorization(c, decision.AuthorizeRequest)
+	if !ok {
+		return
+	}
+
+	query := url.Values{}
+	if decision.State != "" {
+		query.Set("state", decision.State)
+	}
+
+	if !decision.Approve {
+		query.Set("error", "access_denied")
+		c.JSON(http.StatusOK, RedirectResponse{RedirectTo: appendQuery(redirectURI, query)})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	code, err := generateClientSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar código de autorização"})
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var consent models.OAuthConsent
+		if err := tx.Where(models.OAuthConsent{UserID: userID, ClientID: client.ClientID}).FirstOrInit(&consent).Error; err != nil {
+			return err
+		}
+		consent.Scopes = auth.ParseScopes(strings.Join(append(consent.Scopes, scopes...), " "))
+		if err := tx.Save(&consent).Error; err != nil {
+			return err
+		}
+
+		// Aproveita para descartar códigos expirados
+		if err := tx.Where("expires_at <= ?", time.Now()).Delete(&models.OAuthAuthorizationCode{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.OAuthAuthorizationCode{
+			CodeHash:            auth.HashSecret(code),
+			ClientID:            client.ClientID,
+			UserID:              userID,
+			RedirectURI:         redirectURI,
+			RedirectURIProvided: decision.RedirectURI != "",
+			Scopes:              scopes,
+			CodeChallenge:       decision.CodeChallenge,
+			ExpiresAt:           time.Now().Add(oauthCodeTTL),
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar autorização"})
+		return
+	}
+
+	query.Set("code", code)
+	c.JSON(http.StatusOK, RedirectResponse{RedirectTo: appendQuery(redirectURI, query)})
+}
+
+// Token emite access tokens para clientes OAuth2
+// @Summary Endpoint de token OAuth2
+// @Description Suporta os grants authorization_code (com code_verifier PKCE) e client_credentials (apenas clientes confidenciais). O cliente se autentica via HTTP Basic ou client_id/client_secret no corpo; clientes públicos enviam apenas client_id. Não são emitidos refresh tokens.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code ou client_credentials"
+// @Param code formData string false "Código de autorização"
+// @Param redirect_uri formData string false "URI de retorno usada na autorização; obrigatória se foi informada nela"
+// @Param code_verifier formData string false "code_verifier PKCE"
+// @Param scope formData string false "Escopos (client_credentials)"
+// @Param client_id formData string false "Identificador do cliente"
+// @Param client_secret formData string false "Segredo do cliente"
+// @Success 200 {object} handlers.OAuthTokenResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /oauth/token [post]
+func (h *OAuthHandler) Token(c *gin.Context) {
+	// Tokens não devem ser guardados em cache (RFC 6749, seção 5.1)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	switch c.PostForm("grant_type") {
+	case "authorization_code":
+		h.exchangeCode(c, client)
+	case "client_credentials":
+		h.clientCredentials(c, client)
+	case "":
+		oauthError(c, http.StatusBadRequest, "invalid_request", "grant_type é obrigatório")
+	default:
+		oauthError(c, http.StatusBadRequest, "unsupported_grant_type", "grant_type não suportado")
+	}
+}
+
+// exchangeCode troca um código de autorização por um access token
+func (h *OAuthHandler) exchangeCode(c *gin.Context, client *models.OAuthClient) {
+	var code models.OAuthAuthorizationCode
+	if err := h.db.Where("code_hash = ?", auth.HashSecret(c.PostForm("code"))).First(&code).Error; err != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "Código de autorização inválido")
+		return
+	}
+
+	// O código é consumido antes das demais verificações, para que cada código
+	// admita uma única tentativa
+	result := h.db.Model(&models.OAuthAuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", code.ID, time.Now()).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao consumir código de autorização"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "Código de autorização expirado ou já utilizado")
+		return
+	}
+
+	// A redirect_uri só pode ser omitida se também foi omitida na autorização
+	redirectURI := c.PostForm("redirect_uri")
+	if code.RedirectURIProvided || redirectURI != "" {
+		if redirectURI != code.RedirectURI {
+			oauthError(c, http.StatusBadRequest, "invalid_grant", "Código de autorização emitido para outro cliente ou redirect_uri")
+			return
+		}
+	}
+	if code.ClientID != client.ClientID {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "Código de autorização emitido para outro cliente ou redirect_uri")
+		return
+	}
+	if !auth.VerifyPKCE(c.PostForm("code_verifier"), code.CodeChallenge) {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "code_verifier inválido")
+		return
+	}
+
+	accessToken, err := h.tokens.IssueDelegated(code.UserID, client.ClientID, code.Scopes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newOAuthTokenResponse(accessToken, code.Scopes))
+}
+
+// clientCredentials emite um access token para o próprio cliente, sem usuário associado
+func (h *OAuthHandler) clientCredentials(c *gin.Context, client *models.OAuthClient) {
+	if !client.Confidential {
+		oauthError(c, http.StatusBadRequest, "unauthorized_client", "Clientes públicos não podem usar client_credentials")
+		return
+	}
+
+	scopes := client.Scopes
+	if requested := auth.ParseScopes(c.PostForm("scope")); len(requested) > 0 {
+		if !auth.ContainsScopes(client.Scopes, requested) {
+			oauthError(c, http.StatusBadRequest, "invalid_scope", "Escopo não permitido para este cliente")
+			return
+		}
+		scopes = requested
+	}
+
+	accessToken, err := h.tokens.IssueDelegated(0, client.ClientID, scopes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newOAuthTokenResponse(accessToken, scopes))
+}
+
+// authenticateClient identifica o cliente via HTTP Basic (client_secret_basic)
+// ou pelos campos do formulário (client_secret_post). Clientes confidenciais
+// precisam apresentar o segredo.
+func (h *OAuthHandler) authenticateClient(c *gin.Context) (*models.OAuthClient, bool) {
+	clientID, secret, basic := c.Request.BasicAuth()
+	if basic {
+		// RFC 6749, seção 2.3.1: as credenciais são codificadas como formulário
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = c.PostForm("client_id")
+		secret = c.PostForm("client_secret")
+	}
+
+	var client models.OAuthClient
+	if clientID == "" || h.db.Where("client_id = ?", clientID).First(&client).Error != nil {
+		rejectClient(c, basic)
+		return nil, false
+	}
+
+	if client.Confidential && !auth.SecretMatches(secret, client.SecretHash) {
+		rejectClient(c, basic)
+		return nil, false
+	}
+
+	return &client, true
+}
+
+// validateAuthorization valida cliente, redirect_uri, escopos e PKCE de uma
+// solicitação de autorização e retorna o cliente, a URI de retorno e os escopos
+func (h *OAuthHandler) validateAuthorization(c *gin.Context, request AuthorizeRequest) (*models.OAuthClient, string, []string, bool) {
+	var client models.OAuthClient
+	if err := h.db.Where("client_id = ?", request.ClientID).First(&client).Error; err != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_client", "Cliente não encontrado")
+		return nil, "", nil, false
+	}
+
+	redirectURI := request.RedirectURI
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !containsString(client.RedirectURIs, redirectURI) {
+		oauthError(c, http.StatusBadRequest, "invalid_request", "redirect_uri não registrada para este cliente")
+		return nil, "", nil, false
+	}
+
+	if request.ResponseType != "code" {
+		oauthError(c, http.StatusBadRequest, "unsupported_response_type", "Apenas response_type=code é suportado")
+		return nil, "", nil, false
+	}
+
+	if request.CodeChallenge == "" || request.CodeChallengeMethod != "S256" {
+		oauthError(c, http.StatusBadRequest, "invalid_request", "PKCE é obrigatório (code_challenge com code_challenge_method=S256)")
+		return nil, "", nil, false
+	}
+
+	scopes := client.Scopes
+	if requested := auth.ParseScopes(request.Scope); len(requested) > 0 {
+		if err := auth.ValidateScopes(requested); err != nil || !auth.ContainsScopes(client.Scopes, requested) {
+			oauthError(c, http.StatusBadRequest, "invalid_scope", "Escopo não permitido para este cliente")
+			return nil, "", nil, false
+		}
+		scopes = requested
+	}
+
+	return &client, redirectURI, scopes, true
+}
+
+// newOAuthTokenResponse monta a resposta do endpoint de token
+func newOAuthTokenResponse(accessToken *auth.AccessToken, scopes []string) OAuthTokenResponse {
+	return OAuthTokenResponse{
+		AccessToken: accessToken.Token,
+		TokenType:   "Bearer",
+		ExpiresIn:   accessToken.ExpiresIn(),
+		Scope:       strings.Join(scopes, " "),
+	}
+}
+
+// oauthError responde no formato de erro da RFC 6749 (seção 5.2)
+func oauthError(c *gin.Context, status int, code, description string) {
+	c.JSON(status, gin.H{"error": code, "error_description": description})
+}
+
+// rejectClient responde a uma falha de autenticação do cliente
+func rejectClient(c *gin.Context, basic bool) {
+	if basic {
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	oauthError(c, http.StatusUnauthorized, "invalid_client", "Autenticação do cliente falhou")
+}
+
+// appendQuery acrescenta parâmetros a uma URI que pode já ter query string
+func appendQuery(uri string, query url.Values) string {
+	separator := "?"
+	if strings.Contains(uri, "?") {
+		separator = "&"
+	}
+	return uri + separator + query.Encode()
+}
+
+// containsString informa se o valor está na lista, comparando exatamente
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
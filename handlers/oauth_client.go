@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"life/auth"
+	"life/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// OAuthClientHandler gerencia o registro de clientes OAuth2 pelos usuários
+type OAuthClientHandler struct {
+	db *gorm.DB
+}
+
+// NewOAuthClientHandler cria uma nova instância do OAuthClientHandler
+func NewOAuthClientHandler(db *gorm.DB) *OAuthClientHandler {
+	return &OAuthClientHandler{db: db}
+}
+
+// CreateOAuthClientData representa os dados para registro de um cliente OAuth2
+type CreateOAuthClientData struct {
+	Name         string   `json:"name" binding:"required"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes" binding:"required,min=1"`
+	Confidential bool     `json:"confidential"`
+}
+
+// CreateClient registra um novo cliente OAuth2
+// @Summary Registra um cliente OAuth2
+// @Description Registra uma aplicação de terceiros. O client_secret de clientes confidenciais é retornado apenas nesta resposta.
+// @Tags oauth
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param client body handlers.CreateOAuthClientData true "Dados do cliente"
+// @Success 201 {object} models.OAuthClient
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /oauth/clients [post]
+func (h *OAuthClientHandler) CreateClient(c *gin.Context) {
+	var clientData CreateOAuthClientData
+	if err := c.ShouldBindJSON(&clientData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos", "details": err.Error()})
+		return
+	}
+
+	if err := auth.ValidateScopes(clientData.Scopes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Clientes públicos só usam o fluxo authorization code, que exige URI de retorno
+	if !clientData.Confidential && len(clientData.RedirectURIs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Clientes públicos exigem ao menos uma redirect_uri"})
+		return
+	}
+	for _, uri := range clientData.RedirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	clientID, err := generateClientID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar client_id"})
+		return
+	}
+
+	client := models.OAuthClient{
+		ClientID:     clientID,
+		Name:         clientData.Name,
+		RedirectURIs: clientData.RedirectURIs,
+		Scopes:       auth.ParseScopes(strings.Join(clientData.Scopes, " ")),
+		Confidential: clientData.Confidential,
+		OwnerID:      c.GetUint("user_id"),
+	}
+
+	if client.Confidential {
+		secret, err := generateClientSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar client_secret"})
+			return
+		}
+		client.ClientSecret = secret
+		client.SecretHash = auth.HashSecret(secret)
+	}
+
+	if err := h.db.Create(&client).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar cliente"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, client)
+}
+
+// ListClients lista os clientes OAuth2 registrados pelo usuário autenticado
+// @Summary Lista clientes OAuth2
+// @Description Retorna os clientes OAuth2 registrados pelo usuário autenticado
+// @Tags oauth
+// @Security Bearer
+// @Produce json
+// @Success 200 {array} models.OAuthClient
+// @Failure 401 {object} map[string]string
+// @Router /oauth/clients [get]
+func (h *OAuthClientHandler) ListClients(c *gin.Context) {
+	var clients []models.OAuthClient
+	if err := h.db.Where("owner_id = ?", c.GetUint("user_id")).Find(&clients).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar clientes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, clients)
+}
+
+// DeleteClient remove um cliente OAuth2 e os consentimentos dados a ele
+// @Summary Remove cliente OAuth2
+// @Description Remove um cliente OAuth2 do usuário autenticado. Os tokens já emitidos continuam válidos até expirar.
+// @Tags oauth
+// @Security Bearer
+// @Param id path int true "ID do cliente"
+// @Success 204 "No Content"
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /oauth/clients/{id} [delete]
+func (h *OAuthClientHandler) DeleteClient(c *gin.Context) {
+	var client models.OAuthClient
+	if err := h.db.Where("id = ? AND owner_id = ?", c.Param("id"), c.GetUint("user_id")).First(&client).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cliente não encontrado"})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("client_id = ?", client.ClientID).Delete(&models.OAuthConsent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("client_id = ?", client.ClientID).Delete(&models.OAuthAuthorizationCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&client).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao remover cliente"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// validateRedirectURI aceita URIs absolutas sem fragmento; http apenas para localhost
+func validateRedirectURI(uri string) error {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme == "" || parsed.Fragment != "" {
+		return fmt.Errorf("redirect_uri inválida: %s", uri)
+	}
+
+	if parsed.Scheme == "http" {
+		switch parsed.Hostname() {
+		case "localhost", "127.0.0.1", "::1":
+		default:
+			return fmt.Errorf("redirect_uri deve usar https: %s", uri)
+		}
+	}
+
+	// Esquemas próprios são permitidos para aplicativos nativos
+	if (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host == "" {
+		return fmt.Errorf("redirect_uri inválida: %s", uri)
+	}
+
+	return nil
+}
+
+// generateClientID gera o identificador público de um cliente
+func generateClientID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// generateClientSecret gera o segredo de um cliente confidencial
+func generateClientSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
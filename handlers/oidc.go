@@ -0,0 +1,464 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"life/audit"
+	"life/auth"
+	"life/models"
+	"life/oidc"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// oidcStateTTL define por quanto tempo uma autorização iniciada pode ser concluída
+	oidcStateTTL = 10 * time.Minute
+
+	// oidcLinkCookie liga o vínculo de um provedor ao navegador que o iniciou
+	oidcLinkCookie = "life_oidc_link"
+)
+
+// usernameInvalidChars remove os caracteres não aceitos em nomes de usuário gerados
+var usernameInvalidChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// OIDCHandler gerencia o login com provedores OpenID Connect e os vínculos de identidades
+type OIDCHandler struct {
+	db          *gorm.DB
+	tokens      *auth.TokenService
+	emailPolicy auth.EmailVerificationPolicy
+	providers   map[string]*oidc.Provider
+}
+
+// NewOIDCHandler cria uma nova instância do OIDCHandler
+func NewOIDCHandler(db *gorm.DB, tokens *auth.TokenService, emailPolicy auth.EmailVerificationPolicy, providers map[string]*oidc.Provider) *OIDCHandler {
+	return &OIDCHandler{db: db, tokens: tokens, emailPolicy: emailPolicy, providers: providers}
+}
+
+// AuthorizationResponse representa o início de uma autorização em um provedor externo
+// @Description URL para onde o usuário deve ser redirecionado
+type AuthorizationResponse struct {
+	AuthorizationURL string `json:"authorization_url" example:"https://accounts.google.com/o/oauth2/v2/auth?client_id=..."`
+}
+
+// Login inicia o login com um provedor OIDC
+// @Summary Inicia login com provedor externo
+// @Description Retorna a URL de autorização do provedor (authorization code + PKCE)
+// @Tags oidc
+// @Produce json
+// @Param provider path string true "Nome do provedor"
+// @Param device_name query string false "Nome do dispositivo"
+// @Success 200 {object} handlers.AuthorizationResponse
+// @Failure 404 {object} map[string]string
+// @Router /oidc/{provider}/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	h.authorize(c, nil, c.Query("device_name"))
+}
+
+// Link inicia o vínculo de um provedor OIDC à conta autenticada
+// @Summary Vincula provedor externo
+// @Description Retorna a URL de autorização e define um cookie HttpOnly que liga o vínculo a este navegador; o callback só vincula a identidade ao usuário autenticado se receber o mesmo cookie
+// @Tags oidc
+// @Security Bearer
+// @Produce json
+// @Param provider path string true "Nome do provedor"
+// @Success 200 {object} handlers.AuthorizationResponse
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /oidc/{provider}/link [post]
+func (h *OIDCHandler) Link(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	h.authorize(c, &userID, "")
+}
+
+// Callback conclui a autorização no retorno do provedor
+// @Summary Retorno do provedor externo
+// @Description Troca o código pelo id_token. Em fluxos de login retorna os tokens (ou o desafio de 2FA); em fluxos de vínculo retorna a identidade vinculada.
+// @Tags oidc
+// @Produce json
+// @Param provider path string true "Nome do provedor"
+// @Param code query string true "Código de autorização"
+// @Param state query string true "State da autorização"
+// @Success 200 {object} handlers.LoginResponse
+// @Success 201 {object} models.Identity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /oidc/{provider}/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	provider, ok := h.provider(c)
+	if !ok {
+		return
+	}
+
+	if providerError := c.Query("error"); providerError != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Autorização recusada pelo provedor", "details": providerError})
+		return
+	}
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+
+	pending, err := h.consumeState(provider.Name(), state)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Autorização inválida ou expirada"})
+		return
+	}
+
+	// Sem o cookie, a URL de um vínculo iniciado por outra pessoa ligaria a
+	// identidade de quem a abriu à conta de quem a gerou
+	if pending.UserID != nil && !linkStartedInBrowser(c, pending) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vínculo iniciado em outro navegador"})
+		return
+	}
+
+	identity, err := provider.Exchange(c.Request.Context(), code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		log.Warn().Err(err).Str("provider", provider.Name()).Msg("Falha ao concluir autorização OIDC")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Não foi possível autenticar com o provedor"})
+		return
+	}
+
+	if pending.UserID != nil {
+		h.linkIdentity(c, *pending.UserID, provider.Name(), identity)
+		return
+	}
+
+	h.loginWithIdentity(c, provider.Name(), identity, pending.DeviceName)
+}
+
+// ListIdentities lista as identidades externas vinculadas ao usuário autenticado
+// @Summary Lista provedores vinculados
+// @Description Retorna os provedores externos vinculados à conta
+// @Tags oidc
+// @Security Bearer
+// @Produce json
+// @Success 200 {array} models.Identity
+// @Failure 401 {object} map[string]string
+// @Router /identities [get]
+func (h *OIDCHandler) ListIdentities(c *gin.Context) {
+	var identities []models.Identity
+	if err := h.db.Where("user_id = ?", c.GetUint("user_id")).Order("created_at").Find(&identities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar identidades"})
+		return
+	}
+
+	c.JSON(http.StatusOK, identities)
+}
+
+// UnlinkIdentity remove o vínculo com um provedor externo
+// @Summary Desvincula provedor externo
+// @Description Remove o vínculo, exceto quando ele é o único método de login da conta
+// @Tags oidc
+// @Security Bearer
+// @Param id path int true "ID da identidade"
+// @Success 204 "No Content"
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /identities/{id} [delete]
+func (h *OIDCHandler) UnlinkIdentity(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var identity models.Identity
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&identity).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identidade não encontrada"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	var count int64
+	if err := h.db.Model(&models.Identity{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao remover identidade"})
+		return
+	}
+
+	// Contas criadas por um provedor não têm senha
+	if user.Password == "" && count <= 1 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Não é possível remover o único método de login da conta; defina uma senha antes"})
+		return
+	}
+
+	if err := h.db.Delete(&identity).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao remover identidade"})
+		return
+	}
+
+	audit.Record(h.db, c, audit.Event{
+		Action:     audit.ActionIdentityUnlinked,
+		TargetType: audit.TargetIdentity,
+		TargetID:   identity.ID,
+		Success:    true,
+		Metadata:   map[string]interface{}{"provider": identity.Provider},
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// authorize registra o state da autorização e responde com a URL do provedor
+func (h *OIDCHandler) authorize(c *gin.Context, userID *uint, deviceName string) {
+	provider, ok := h.provider(c)
+	if !ok {
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		value, err := oidc.RandomString()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao iniciar autorização"})
+			return
+		}
+		values[i] = value
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	var linkBinding string
+	if userID != nil {
+		var err error
+		if linkBinding, err = oidc.RandomString(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao iniciar autorização"})
+			return
+		}
+	}
+
+	authorizationURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Error().Err(err).Str("provider", provider.Name()).Msg("Erro na descoberta OIDC")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Provedor indisponível"})
+		return
+	}
+
+	pending := models.OIDCState{
+		StateHash:    auth.HashSecret(state),
+		Provider:     provider.Name(),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		UserID:       userID,
+		DeviceName:   deviceName,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	}
+	if linkBinding != "" {
+		pending.LinkBindingHash = auth.HashSecret(linkBinding)
+	}
+	if err := h.db.Create(&pending).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao iniciar autorização"})
+		return
+	}
+
+	if linkBinding != "" {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(oidcLinkCookie, linkBinding, int(oidcStateTTL.Seconds()), "/", "", c.Request.TLS != nil, true)
+	}
+
+	c.JSON(http.StatusOK, AuthorizationResponse{AuthorizationURL: authorizationURL})
+}
+
+// linkStartedInBrowser informa se o callback de um vínculo veio do navegador
+// que o iniciou, comparando o cookie com o hash guardado junto ao state.
+// O cookie é removido em seguida, pois cada vínculo é concluído uma única vez.
+func linkStartedInBrowser(c *gin.Context, pending *models.OIDCState) bool {
+	binding, err := c.Cookie(oidcLinkCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcLinkCookie, "", -1, "/", "", c.Request.TLS != nil, true)
+
+	return err == nil && pending.LinkBindingHash != "" && auth.SecretMatches(binding, pending.LinkBindingHash)
+}
+
+// consumeState busca e remove o state da autorização; cada state vale uma única vez
+func (h *OIDCHandler) consumeState(provider, state string) (*models.OIDCState, error) {
+	var pending models.OIDCState
+	if err := h.db.Where("state_hash = ? AND provider = ? AND expires_at > ?", auth.HashSecret(state), provider, time.Now()).
+		First(&pending).Error; err != nil {
+		return nil, err
+	}
+
+	result := h.db.Where("id = ?", pending.ID).Delete(&models.OIDCState{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	// Aproveita para descartar autorizações abandonadas
+	h.db.Where("expires_at <= ?", time.Now()).Delete(&models.OIDCState{})
+
+	return &pending, nil
+}
+
+// linkIdentity vincula a identidade ao usuário que iniciou o fluxo
+func (h *OIDCHandler) linkIdentity(c *gin.Context, userID uint, provider string, identity *oidc.Identity) {
+	var existing models.Identity
+	err := h.db.Where("provider = ? AND subject = ?", provider, identity.Subject).First(&existing).Error
+	if err == nil {
+		if existing.UserID != userID {
+			c.JSON(http.StatusConflict, gin.H{"error": "Esta identidade já está vinculada a outra conta"})
+			return
+		}
+		c.JSON(http.StatusOK, existing)
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao vincular identidade"})
+		return
+	}
+
+	linked := models.Identity{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+	if err := h.db.Create(&linked).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao vincular identidade"})
+		return
+	}
+
+	// O callback não é autenticado; o autor é o usuário que iniciou o vínculo
+	audit.Record(h.db, c, audit.Event{
+		ActorID:    userID,
+		Action:     audit.ActionIdentityLinked,
+		TargetType: audit.TargetIdentity,
+		TargetID:   linked.ID,
+		Success:    true,
+		Metadata:   map[string]interface{}{"provider": provider},
+	})
+	c.JSON(http.StatusCreated, linked)
+}
+
+// loginWithIdentity autentica o dono da identidade, criando a conta no primeiro acesso
+func (h *OIDCHandler) loginWithIdentity(c *gin.Context, provider string, identity *oidc.Identity, deviceName string) {
+	var linked models.Identity
+	err := h.db.Where("provider = ? AND subject = ?", provider, identity.Subject).First(&linked).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar identidade"})
+		return
+	}
+
+	var user models.User
+	if err == nil {
+		if err := h.db.First(&user, linked.UserID).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não encontrado"})
+			return
+		}
+	} else {
+		created, status, message := h.createUser(provider, identity)
+		if created == nil {
+			c.JSON(status, gin.H{"error": message})
+			return
+		}
+		user = *created
+		audit.Record(h.db, c, audit.Event{
+			ActorID:    user.ID,
+			Action:     audit.ActionRegister,
+			TargetType: audit.TargetUser,
+			TargetID:   user.ID,
+			Success:    true,
+			Metadata:   map[string]interface{}{"provider": provider},
+		})
+	}
+
+	completeLogin(c, h.db, h.tokens, h.emailPolicy, &user, deviceName)
+}
+
+// createUser cria a conta de um usuário no primeiro login por um provedor.
+// Contas existentes com o mesmo email nunca são vinculadas automaticamente:
+// o dono deve entrar com a senha e vincular o provedor explicitamente.
+func (h *OIDCHandler) createUser(provider string, identity *oidc.Identity) (*models.User, int, string) {
+	if identity.Email == "" {
+		return nil, http.StatusBadRequest, "O provedor não informou um email"
+	}
+
+	var count int64
+	if err := h.db.Unscoped().Model(&models.User{}).Where("email = ?", identity.Email).Count(&count).Error; err != nil {
+		return nil, http.StatusInternalServerError, "Erro ao criar usuário"
+	}
+	if count > 0 {
+		return nil, http.StatusConflict, "Já existe uma conta com este email; entre com a senha e vincule o provedor"
+	}
+
+	username, err := generateUsername(identity)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Erro ao criar usuário"
+	}
+
+	displayName := identity.Name
+	if displayName == "" {
+		displayName = username
+	}
+
+	user := models.User{
+		Username:      username,
+		DisplayName:   displayName,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		Role:          auth.RoleUser,
+		Status:        models.UserStatusActive,
+	}
+	if identity.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.Identity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Erro ao criar usuário"
+	}
+
+	return &user, 0, ""
+}
+
+// provider resolve o provedor do parâmetro da rota, respondendo 404 se não existir
+func (h *OIDCHandler) provider(c *gin.Context) (*oidc.Provider, bool) {
+	provider, ok := h.providers[strings.ToLower(c.Param("provider"))]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provedor não encontrado"})
+		return nil, false
+	}
+	return provider, true
+}
+
+// generateUsername deriva um nome de usuário único a partir do email da identidade
+func generateUsername(identity *oidc.Identity) (string, error) {
+	base := strings.ToLower(strings.SplitN(identity.Email, "@", 2)[0])
+	base = usernameInvalidChars.ReplaceAllString(base, "")
+	if len(base) > 20 {
+		base = base[:20]
+	}
+	if base == "" {
+		base = "player"
+	}
+
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base + "_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"life/audit"
+	"life/auth"
+	"life/mail"
+	"life/models"
+	"life/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// passwordResetTTL define a validade dos links de redefinição de senha
+const passwordResetTTL = time.Hour
+
+// errResetTokenInvalid indica que o token de redefinição foi consumido por outra
+// requisição ou que o usuário não existe mais
+var errResetTokenInvalid = errors.New("token de redefinição inválido")
+
+// errPasswordChanged indica que a senha foi alterada por outra requisição
+var errPasswordChanged = errors.New("senha alterada por outra requisição")
+
+// PasswordHandler gerencia a recuperação e a alteração de senha
+type PasswordHandler struct {
+	db        *gorm.DB
+	tokens    *auth.TokenService
+	mailer    mail.Mailer
+	passwords *validator.PasswordPolicy
+	hasher    *auth.PasswordHasher
+}
+
+// NewPasswordHandler cria uma nova instância do PasswordHandler
+func NewPasswordHandler(db *gorm.DB, tokens *auth.TokenService, mailer mail.Mailer, passwords *validator.PasswordPolicy, hasher *auth.PasswordHasher) *PasswordHandler {
+	return &PasswordHandler{db: db, tokens: tokens, mailer: mailer, passwords: passwords, hasher: hasher}
+}
+
+// ForgotPassword envia o link de redefinição de senha
+// @Summary Solicita redefinição de senha
+// @Description Envia um link de redefinição para o email informado. A resposta é sempre 202 para não revelar quais emails estão cadastrados.
+// @Tags auth
+// @Accept json
+// @Param email body map[string]string true "Email cadastrado"
+// @Success 202 "Accepted"
+// @Failure 400 {object} map[string]string
+// @Router /password/forgot [post]
+func (h *PasswordHandler) ForgotPassword(c *gin.Context) {
+	var forgotData struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&forgotData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.Where("email = ?", forgotData.Email).First(&user).Error; err == nil {
+		h.sendResetEmail(&user)
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ResetPassword redefine a senha a partir do token recebido por email
+// @Summary Redefine a senha
+// @Description Define uma nova senha usando o token enviado por email e encerra todas as sessões do usuário. A nova senha deve atender à política configurada.
+// @Tags auth
+// @Accept json
+// @Param reset body map[string]string true "Token (token) e nova senha (password)"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Router /password/reset [post]
+func (h *PasswordHandler) ResetPassword(c *gin.Context) {
+	var resetData struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&resetData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+
+	var prt models.PasswordResetToken
+	if err := h.db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", auth.HashSecret(resetData.Token), time.Now()).
+		First(&prt).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token inválido ou expirado"})
+		return
+	}
+
+	if !checkPassword(c, h.passwords, resetData.Password) {
+		return
+	}
+
+	hashedPassword, err := h.hasher.Hash(resetData.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Consome o token; uma requisição concorrente com o mesmo token não altera nada
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", prt.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errResetTokenInvalid
+		}
+
+		// Outros links pendentes deixam de valer
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", prt.UserID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		// Usuários excluídos (inclusive com exclusão pendente) não são alterados
+		result = tx.Model(&models.User{}).Where("id = ?", prt.UserID).
+			Update("password", hashedPassword)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errResetTokenInvalid
+		}
+
+		return revokeRefreshTokens(tx, h.tokens, "user_id = ?", prt.UserID)
+	})
+
+	if errors.Is(err, errResetTokenInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token inválido ou expirado"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao redefinir senha"})
+		return
+	}
+
+	audit.Record(h.db, c, audit.Event{ActorID: prt.UserID, Action: audit.ActionPasswordReset, TargetType: audit.TargetUser, TargetID: prt.UserID, Success: true})
+	c.Status(http.StatusNoContent)
+}
+
+// ChangePassword altera a senha do usuário autenticado
+// @Summary Altera a senha
+// @Description Altera a senha mediante a senha atual. A nova senha deve atender à política configurada. Todas as outras sessões do usuário são encerradas; a sessão que fez a requisição continua ativa.
+// @Tags profile
+// @Security Bearer
+// @Accept json
+// @Param passwords body map[string]string true "Senha atual (current_password) e nova senha (new_password)"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /profile/password [put]
+func (h *PasswordHandler) ChangePassword(c *gin.Context) {
+	var changeData struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&changeData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	// Contas sem senha (criadas por login externo) definem a primeira pela redefinição por email
+	if ok, _, _ := h.hasher.Verify(changeData.CurrentPassword, user.Password); !ok {
+		audit.Record(h.db, c, audit.Event{Action: audit.ActionPasswordChanged, TargetType: audit.TargetUser, TargetID: user.ID})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Credenciais inválidas"})
+		return
+	}
+
+	if changeData.NewPassword == changeData.CurrentPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A nova senha deve ser diferente da atual"})
+		return
+	}
+	if !checkPassword(c, h.passwords, changeData.NewPassword) {
+		return
+	}
+
+	hashedPassword, err := h.hasher.Hash(changeData.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
+		return
+	}
+
+	// A sessão atual é a família do refresh token emitido junto com o access
+	// token da requisição; sem ela, todas as sessões são encerradas
+	query, args := "user_id = ?", []interface{}{user.ID}
+	var current models.RefreshToken
+	if err := h.db.Where("user_id = ? AND access_token_id = ?", user.ID, c.GetString("token_id")).First(&current).Error; err == nil {
+		if current.FamilyID != "" {
+			// Sessões anteriores às famílias de tokens têm family_id nulo e também são encerradas
+			query, args = "user_id = ? AND (family_id IS NULL OR family_id <> ?)", []interface{}{user.ID, current.FamilyID}
+		} else {
+			query, args = "user_id = ? AND id <> ?", []interface{}{user.ID, current.ID}
+		}
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// A atualização condicional impede que duas alterações concorrentes usem a mesma senha atual
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND password = ?", user.ID, user.Password).
+			Update("password", hashedPassword)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errPasswordChanged
+		}
+
+		// Links de redefinição pendentes deixam de valer
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return revokeRefreshTokens(tx, h.tokens, query, args...)
+	})
+
+	if errors.Is(err, errPasswordChanged) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Credenciais inválidas"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao alterar senha"})
+		return
+	}
+
+	audit.Record(h.db, c, audit.Event{Action: audit.ActionPasswordChanged, TargetType: audit.TargetUser, TargetID: user.ID, Success: true})
+	c.Status(http.StatusNoContent)
+}
+
+// sendResetEmail gera um novo token de redefinição e o envia ao usuário.
+// Falhas são apenas registradas para que a resposta não revele se o email existe.
+func (h *PasswordHandler) sendResetEmail(user *models.User) {
+	token, err := generateResetToken()
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.ID).Msg("Erro ao gerar token de redefinição de senha")
+		return
+	}
+
+	if err := h.db.Create(&models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: auth.HashSecret(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}).Error; err != nil {
+		log.Error().Err(err).Uint("user_id", user.ID).Msg("Erro ao salvar token de redefinição de senha")
+		return
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", mail.AppURL(), url.QueryEscape(token))
+	err = h.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Redefinição de senha",
+		Body: fmt.Sprintf("Olá, %s!\n\nRecebemos uma solicitação para redefinir sua senha. Para escolher uma nova senha, acesse o link abaixo:\n\n%s\n\nO link expira em 1 hora e só pode ser usado uma vez. Se você não fez esta solicitação, ignore esta mensagem.\n",
+			user.DisplayName, link),
+	})
+	if err != nil {
+		log.Error().Err(err).Uint("user_id", user.ID).Msg("Erro ao enviar email de redefinição de senha")
+	}
+}
+
+// generateResetToken gera um token de redefinição seguro para uso em links
+func generateResetToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// checkPassword aplica a política de senhas e, se a senha for recusada,
+// responde com o motivo e os requisitos da política
+func checkPassword(c *gin.Context, policy *validator.PasswordPolicy, password string) bool {
+	err := policy.Validate(password)
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, validator.ErrBreachedUnavailable) {
+		log.Error().Err(err).Msg("Erro ao consultar o corpus de senhas vazadas")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   "Senha não atende à política",
+		"details": err.Error(),
+		"policy":  policy.Requirements(),
+	})
+	return false
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"life/audit"
+	"life/auth"
+	"life/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// refreshTokenTTL define a validade de cada refresh token emitido
+const refreshTokenTTL = 7 * 24 * time.Hour
+
+// SessionHandler gerencia as sessões (famílias de refresh tokens) do usuário
+type SessionHandler struct {
+	db     *gorm.DB
+	tokens *auth.TokenService
+}
+
+// NewSessionHandler cria uma nova instância do SessionHandler
+func NewSessionHandler(db *gorm.DB, tokens *auth.TokenService) *SessionHandler {
+	return &SessionHandler{db: db, tokens: tokens}
+}
+
+// SessionResponse representa uma sessão ativa do usuário
+// @Description Sessão ativa (dispositivo conectado)
+type SessionResponse struct {
+	// ID da sessão, usado para encerrá-la
+	ID uint `json:"id" example:"12"`
+
+	// Nome do dispositivo informado no login
+	DeviceName string `json:"device_name" example:"PC da sala"`
+
+	// User agent da última utilização
+	UserAgent string `json:"user_agent" example:"LifeClient/1.4 (Windows)"`
+
+	// IP da última utilização
+	IP string `json:"ip" example:"203.0.113.10"`
+
+	// Data da última utilização
+	LastUsedAt time.Time `json:"last_used_at" example:"2024-05-25T20:00:00Z"`
+
+	// Data de expiração da sessão caso não seja renovada
+	ExpiresAt time.Time `json:"expires_at" example:"2024-06-01T20:00:00Z"`
+
+	// Indica se é a sessão que fez esta requisição
+	Current bool `json:"current" example:"true"`
+}
+
+// ListSessions lista as sessões ativas do usuário autenticado
+// @Summary Lista sessões ativas
+// @Description Retorna os dispositivos em que o usuário está conectado
+// @Tags auth
+// @Security Bearer
+// @Produce json
+// @Success 200 {array} handlers.SessionResponse
+// @Failure 401 {object} map[string]string
+// @Router /sessions [get]
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	// Cada família ativa possui exatamente um refresh token não revogado
+	var rts []models.RefreshToken
+	if err := h.db.Where("user_id = ? AND is_revoked = ? AND expires_at > ?", userID, false, time.Now()).
+		Order("last_used_at DESC NULLS LAST").
+		Find(&rts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar sessões"})
+		return
+	}
+
+	tokenID := c.GetString("token_id")
+	sessions := make([]SessionResponse, 0, len(rts))
+	for _, rt := range rts {
+		sessions = append(sessions, SessionResponse{
+			ID:         rt.ID,
+			DeviceName: rt.DeviceName,
+			UserAgent:  rt.UserAgent,
+			IP:         rt.IP,
+			LastUsedAt: rt.LastUsedAt,
+			ExpiresAt:  rt.ExpiresAt,
+			Current:    rt.AccessTokenID != "" && rt.AccessTokenID == tokenID,
+		})
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// DeleteSession encerra uma sessão do usuário autenticado
+// @Summary Encerra uma sessão
+// @Description Revoga o refresh token da sessão e os access tokens emitidos para ela
+// @Tags auth
+// @Security Bearer
+// @Param id path int true "ID da sessão"
+// @Success 204 "No Content"
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /sessions/{id} [delete]
+func (h *SessionHandler) DeleteSession(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	id := c.Param("id")
+
+	var rt models.RefreshToken
+	if err := h.db.Where("id = ? AND user_id = ? AND is_revoked = ?", id, userID, false).First(&rt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sessão não encontrada"})
+		return
+	}
+
+	query, args := "id = ?", []interface{}{rt.ID}
+	if rt.FamilyID != "" {
+		query, args = "family_id = ?", []interface{}{rt.FamilyID}
+	}
+
+	if err := revokeRefreshTokens(h.db, h.tokens, query, args...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao encerrar sessão"})
+		return
+	}
+
+	audit.Record(h.db, c, audit.Event{Action: audit.ActionSessionRevoked, TargetType: audit.TargetSession, TargetID: rt.ID, Success: true})
+	c.Status(http.StatusNoContent)
+}
+
+// startSession emite o access token e o primeiro refresh token de uma nova
+// sessão, registrando o dispositivo que fez a requisição
+func startSession(db *gorm.DB, tokens *auth.TokenService, c *gin.Context, userID uint, deviceName string) (LoginResponse, error) {
+	accessToken, err := tokens.Issue(userID)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	// Cada sessão é uma nova família de refresh tokens
+	familyID, err := generateFamilyID()
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	refreshToken, err := issueRefreshToken(db, &models.RefreshToken{
+		UserID:     userID,
+		FamilyID:   familyID,
+		DeviceName: deviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+	}, accessToken)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	return newLoginResponse(accessToken, refreshToken), nil
+}
+
+// issueRefreshToken gera e persiste um novo refresh token a partir de rt,
+// vinculado ao access token emitido na mesma operação. Apenas o hash é
+// armazenado; o token em claro é devolvido para o cliente.
+func issueRefreshToken(tx *gorm.DB, rt *models.RefreshToken, accessToken *auth.AccessToken) (string, error) {
+	token, err := generateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	rt.TokenHash = auth.HashSecret(token)
+	rt.ExpiresAt = now.Add(refreshTokenTTL)
+	rt.LastUsedAt = now
+	rt.AccessTokenID = accessToken.ID
+	rt.AccessTokenExpiresAt = accessToken.ExpiresAt
+
+	if err := tx.Create(rt).Error; err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// revokeRefreshTokens revoga os refresh tokens que atendem à condição e
+// adiciona à denylist os access tokens ainda válidos emitidos junto com eles
+func revokeRefreshTokens(db *gorm.DB, tokens *auth.TokenService, query interface{}, args ...interface{}) error {
+	var live []models.RefreshToken
+	if err := db.Where(query, args...).
+		Where("access_token_id <> '' AND access_token_expires_at > ?", time.Now()).
+		Find(&live).Error; err != nil {
+		return err
+	}
+
+	for _, rt := range live {
+		if err := tokens.Revoke(rt.AccessTokenID, rt.AccessTokenExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	return db.Model(&models.RefreshToken{}).
+		Where(query, args...).
+		Where("is_revoked = ?", false).
+		Update("is_revoked", true).Error
+}
+
+// generateRefreshToken gera um token de atualização seguro
+func generateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
+// generateFamilyID gera o identificador de uma nova família de refresh tokens
+func generateFamilyID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -1,38 +1,54 @@
 package handlers
 
 import (
+	"life/audit"
+	"life/auth"
+	"life/mail"
 	"life/models"
+	"life/validator"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 // UserHandler gerencia as operações de usuário
 type UserHandler struct {
-	db *gorm.DB
+	db        *gorm.DB
+	tokens    *auth.TokenService
+	mailer    mail.Mailer
+	passwords *validator.PasswordPolicy
+	hasher    *auth.PasswordHasher
 }
 
 // NewUserHandler cria uma nova instância do UserHandler
-func NewUserHandler(db *gorm.DB) *UserHandler {
-	return &UserHandler{db: db}
+func NewUserHandler(db *gorm.DB, tokens *auth.TokenService, mailer mail.Mailer, passwords *validator.PasswordPolicy, hasher *auth.PasswordHasher) *UserHandler {
+	return &UserHandler{db: db, tokens: tokens, mailer: mailer, passwords: passwords, hasher: hasher}
+}
+
+// RegisterData representa os dados aceitos no registro de usuário
+type RegisterData struct {
+	Username    string `json:"username" binding:"required"`
+	DisplayName string `json:"display_name" binding:"required"`
+	Email       string `json:"email" binding:"required,email"`
+	Password    string `json:"password" binding:"required"`
 }
 
 // Register registra um novo usuário
 // @Summary Registra um novo usuário
-// @Description Cria uma nova conta de usuário
+// @Description Cria uma nova conta de usuário e envia o link de verificação para o email informado. A senha deve atender à política configurada e não pode constar no corpus de senhas vazadas.
 // @Tags users
 // @Accept json
 // @Produce json
-// @Param user body models.User true "Dados do usuário"
+// @Param user body handlers.RegisterData true "Dados do usuário"
 // @Success 201 {object} models.User
 // @Failure 400 {object} map[string]string
 // @Failure 409 {object} map[string]string
 // @Router /register [post]
 func (h *UserHandler) Register(c *gin.Context) {
-	var user models.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	var registerData RegisterData
+	if err := c.ShouldBindJSON(&registerData); err != nil {
 		// Adiciona mais detalhes ao erro de validação
 		validationErrors := make(map[string]string)
 		if err.Error() == "EOF" {
@@ -47,20 +63,42 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Verifica se o usuário já existe
+	if err := validator.ValidateUsername(registerData.Username); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos", "details": map[string]string{"username": err.Error()}})
+		return
+	}
+	if err := validator.ValidateEmail(registerData.Email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos", "details": map[string]string{"email": err.Error()}})
+		return
+	}
+	if !checkPassword(c, h.passwords, registerData.Password) {
+		return
+	}
+
+	// Apenas os campos do registro são aceitos; os demais (ex: 2FA) mantêm o padrão
+	user := models.User{
+		Username:    registerData.Username,
+		DisplayName: registerData.DisplayName,
+		Email:       registerData.Email,
+		Password:    registerData.Password,
+		Role:        auth.RoleUser,
+		Status:      models.UserStatusActive,
+	}
+
+	// Verifica se o usuário já existe, incluindo contas com exclusão pendente
 	var existingUser models.User
-	if err := h.db.Where("username = ? OR email = ?", user.Username, user.Email).First(&existingUser).Error; err == nil {
+	if err := h.db.Unscoped().Where("username = ? OR email = ?", user.Username, user.Email).First(&existingUser).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "Usuário ou email já existe"})
 		return
 	}
 
 	// Hash da senha
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPassword, err := h.hasher.Hash(user.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
 		return
 	}
-	user.Password = string(hashedPassword)
+	user.Password = hashedPassword
 
 	// Cria o usuário
 	if err := h.db.Create(&user).Error; err != nil {
@@ -68,6 +106,9 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
+	sendVerificationEmail(h.tokens, h.mailer, &user)
+	audit.Record(h.db, c, audit.Event{ActorID: user.ID, Action: audit.ActionRegister, TargetType: audit.TargetUser, TargetID: user.ID, Success: true})
+
 	// Remove a senha do response
 	user.Password = ""
 	c.JSON(http.StatusCreated, user)
@@ -139,14 +180,26 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	}
 
 	// Atualiza apenas campos permitidos
+	previous := user
+	emailChanged := h.setEmail(&user, updateData.Email)
 	user.DisplayName = updateData.DisplayName
-	user.Email = updateData.Email
 
-	if err := h.db.Save(&user).Error; err != nil {
+	if err := h.saveProfile(&user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar usuário"})
 		return
 	}
 
+	if emailChanged {
+		sendVerificationEmail(h.tokens, h.mailer, &user)
+	}
+	audit.Record(h.db, c, audit.Event{
+		Action:     audit.ActionProfileUpdated,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Success:    true,
+		Metadata:   userChanges(&previous, &user),
+	})
+
 	// Remove a senha do response
 	user.Password = ""
 	c.JSON(http.StatusOK, user)
@@ -154,13 +207,14 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 
 // GetUser retorna um usuário específico
 // @Summary Obtém um usuário específico
-// @Description Retorna os dados de um usuário específico
+// @Description Retorna os dados de um usuário específico. Apenas o próprio usuário ou administradores (users.read).
 // @Tags users
 // @Security Bearer
 // @Produce json
 // @Param id path int true "ID do usuário"
 // @Success 200 {object} models.User
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /users/{id} [get]
 func (h *UserHandler) GetUser(c *gin.Context) {
@@ -184,7 +238,7 @@ type UpdateUserData struct {
 
 // UpdateUser atualiza um usuário específico
 // @Summary Atualiza um usuário específico
-// @Description Atualiza os dados de um usuário específico
+// @Description Atualiza os dados de um usuário específico. Apenas o próprio usuário ou administradores (users.write).
 // @Tags users
 // @Security Bearer
 // @Accept json
@@ -194,6 +248,7 @@ type UpdateUserData struct {
 // @Success 200 {object} models.User
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /users/{id} [put]
 func (h *UserHandler) UpdateUser(c *gin.Context) {
@@ -220,14 +275,26 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	}
 
 	// Atualiza apenas campos permitidos
+	previous := user
+	emailChanged := h.setEmail(&user, updateData.Email)
 	user.DisplayName = updateData.DisplayName
-	user.Email = updateData.Email
 
-	if err := h.db.Save(&user).Error; err != nil {
+	if err := h.saveProfile(&user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar usuário"})
 		return
 	}
 
+	if emailChanged {
+		sendVerificationEmail(h.tokens, h.mailer, &user)
+	}
+	audit.Record(h.db, c, audit.Event{
+		Action:     audit.ActionUserUpdated,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Success:    true,
+		Metadata:   userChanges(&previous, &user),
+	})
+
 	// Remove a senha do response
 	user.Password = ""
 	c.JSON(http.StatusOK, user)
@@ -235,12 +302,13 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 
 // ListUsers retorna todos os usuários
 // @Summary Lista todos os usuários
-// @Description Retorna uma lista de todos os usuários
+// @Description Retorna uma lista de todos os usuários. Exige a permissão users.list (administradores).
 // @Tags users
 // @Security Bearer
 // @Produce json
 // @Success 200 {array} models.User
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /users [get]
 func (h *UserHandler) ListUsers(c *gin.Context) {
 	var users []models.User
@@ -256,3 +324,102 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 
 	c.JSON(http.StatusOK, users)
 }
+
+// UpdateRoleData representa o novo papel de um usuário
+type UpdateRoleData struct {
+	Role string `json:"role" binding:"required" example:"admin"`
+}
+
+// UpdateRole altera o papel de um usuário
+// @Summary Altera o papel de um usuário
+// @Description Define o papel (user ou admin) de um usuário. Exige a permissão roles.manage; administradores não podem alterar o próprio papel.
+// @Tags users
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path int true "ID do usuário"
+// @Param role body handlers.UpdateRoleData true "Novo papel"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/{id}/role [put]
+func (h *UserHandler) UpdateRole(c *gin.Context) {
+	var roleData UpdateRoleData
+	if err := c.ShouldBindJSON(&roleData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos", "details": err.Error()})
+		return
+	}
+	if !auth.ValidRole(roleData.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Papel inválido", "available_roles": auth.Roles()})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	// Evita que o último administrador perca o acesso por engano
+	if user.ID == c.GetUint("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Não é possível alterar o próprio papel"})
+		return
+	}
+
+	previousRole := user.Role
+	if err := h.db.Model(&user).Update("role", roleData.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar usuário"})
+		return
+	}
+
+	audit.Record(h.db, c, audit.Event{
+		Action:     audit.ActionUserRoleChanged,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Success:    true,
+		Metadata:   map[string]interface{}{"previous_role": previousRole, "role": roleData.Role},
+	})
+
+	// Remove a senha do response
+	user.Password = ""
+	c.JSON(http.StatusOK, user)
+}
+
+// setEmail altera o email do usuário, exigindo uma nova verificação quando
+// ele muda. Retorna se houve alteração.
+func (h *UserHandler) setEmail(user *models.User, email string) bool {
+	if strings.EqualFold(user.Email, email) {
+		user.Email = email
+		return false
+	}
+
+	user.Email = email
+	user.EmailVerified = false
+	user.EmailVerifiedAt = nil
+	return true
+}
+
+// saveProfile grava apenas as colunas editáveis do perfil. Regravar o registro
+// inteiro desfaria alterações concorrentes feitas em outras requisições, como
+// uma suspensão, uma troca de senha ou a ativação do 2FA.
+func (h *UserHandler) saveProfile(user *models.User) error {
+	return h.db.Model(user).
+		Select("display_name", "email", "email_verified", "email_verified_at", "updated_at").
+		Updates(user).Error
+}
+
+// userChanges descreve os campos alterados para o log de auditoria
+func userChanges(previous, current *models.User) map[string]interface{} {
+	changes := make(map[string]interface{})
+	if previous.Email != current.Email {
+		changes["previous_email"] = previous.Email
+		changes["email"] = current.Email
+	}
+	if previous.DisplayName != current.DisplayName {
+		changes["previous_display_name"] = previous.DisplayName
+		changes["display_name"] = current.DisplayName
+	}
+	return changes
+}
@@ -68,7 +68,8 @@ func LogRequest() gin.HandlerFunc {
 			Str("query", raw).
 			Int("status", statusCode).
 			Dur("latency", latency).
-			Str("user_agent", c.Request.UserAgent())
+			Str("user_agent", c.Request.UserAgent()).
+			Str("request_id", c.GetString(RequestIDKey))
 
 		if errorMessage != "" {
 			event.Str("error", errorMessage)
@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequestIDKey é a chave do contexto com o identificador da requisição
+	RequestIDKey = "request_id"
+
+	// RequestIDHeader é o header que recebe e devolve o identificador
+	RequestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength limita o identificador recebido de proxies e clientes
+	maxRequestIDLength = 128
+)
+
+// RequestID identifica cada requisição, reaproveitando o X-Request-ID enviado
+// por um proxy quando válido, e o devolve no header da resposta
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		c.Set(RequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// validRequestID aceita apenas caracteres visíveis ASCII, evitando injeção em logs
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID gera um identificador aleatório de 128 bits
+func newRequestID() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,59 @@
+package mail
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// LogMailer apenas registra os emails no log; destinado ao desenvolvimento local
+type LogMailer struct{}
+
+// NewLogMailer cria uma nova instância do LogMailer
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send registra a mensagem no log em vez de enviá-la
+func (m *LogMailer) Send(msg Message) error {
+	log.Info().
+		Str("to", msg.To).
+		Str("subject", msg.Subject).
+		Str("body", msg.Body).
+		Msg("Email não enviado (MAIL_DRIVER=log)")
+	return nil
+}
+
+// FileMailer grava cada email como uma linha JSON em um arquivo, permitindo
+// que testes automatizados leiam as mensagens enviadas
+type FileMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileMailer cria uma nova instância do FileMailer
+func NewFileMailer(path string) *FileMailer {
+	return &FileMailer{path: path}
+}
+
+// Send acrescenta a mensagem ao arquivo
+func (m *FileMailer) Send(msg Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
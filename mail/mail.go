@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// defaultAppURL é a URL usada nos links dos emails quando APP_URL não está definida
+	defaultAppURL = "http://localhost:8080"
+
+	// defaultFrom é o remetente usado quando MAIL_FROM não está definido
+	defaultFrom = "Life <no-reply@life.local>"
+)
+
+// Message representa um email a ser enviado
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Mailer envia emails transacionais
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// NewFromEnv cria o Mailer configurado em MAIL_DRIVER (smtp, file ou log)
+func NewFromEnv() (Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = defaultFrom
+	}
+
+	switch driver := strings.ToLower(os.Getenv("MAIL_DRIVER")); driver {
+	case "", "log":
+		return NewLogMailer(), nil
+	case "file":
+		path := os.Getenv("MAIL_FILE")
+		if path == "" {
+			return nil, fmt.Errorf("MAIL_FILE é obrigatória com MAIL_DRIVER=file")
+		}
+		return NewFileMailer(path), nil
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("SMTP_HOST é obrigatória com MAIL_DRIVER=smtp")
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return NewSMTPMailer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from), nil
+	default:
+		return nil, fmt.Errorf("MAIL_DRIVER desconhecido: %s", driver)
+	}
+}
+
+// AppURL retorna a URL pública da aplicação usada nos links dos emails (APP_URL)
+func AppURL() string {
+	if url := os.Getenv("APP_URL"); url != "" {
+		return strings.TrimRight(url, "/")
+	}
+	return defaultAppURL
+}
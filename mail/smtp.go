@@ -0,0 +1,59 @@
+package mail
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPMailer envia emails através de um servidor SMTP
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer cria uma nova instância do SMTPMailer. Sem usuário, a
+// autenticação é omitida (útil para relays internos e ferramentas como o MailHog).
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	m := &SMTPMailer{
+		addr: net.JoinHostPort(host, port),
+		from: from,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send envia a mensagem em texto puro
+func (m *SMTPMailer) Send(msg Message) error {
+	sender := m.from
+	if addr, err := parseAddress(m.from); err == nil {
+		sender = addr
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", m.from)
+	fmt.Fprintf(&body, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&body, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return smtp.SendMail(m.addr, m.auth, sender, []string{msg.To}, []byte(body.String()))
+}
+
+// parseAddress extrai o endereço de um remetente no formato "Nome <email>"
+func parseAddress(from string) (string, error) {
+	start := strings.LastIndex(from, "<")
+	end := strings.LastIndex(from, ">")
+	if start == -1 || end < start {
+		return "", fmt.Errorf("remetente sem endereço entre <>: %s", from)
+	}
+	return from[start+1 : end], nil
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"life/config"
 	_ "life/docs"
 	"life/logger"
 	"life/routes"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
@@ -51,6 +57,12 @@ import (
 // @tag.name api-keys
 // @tag.description Gerenciamento de chaves de API
 
+// @tag.name admin
+// @tag.description Moderação de jogadores (apenas administradores)
+
+// shutdownTimeout limita a espera pelas requisições em andamento no encerramento
+const shutdownTimeout = 30 * time.Second
+
 // requiredEnvVars lista todas as variáveis de ambiente necessárias
 var requiredEnvVars = []string{
 	"DB_HOST",
@@ -58,7 +70,6 @@ var requiredEnvVars = []string{
 	"DB_USER",
 	"DB_PASSWORD",
 	"DB_NAME",
-	"JWT_SECRET",
 }
 
 func validateEnvVars() error {
@@ -98,7 +109,7 @@ func main() {
 	}
 
 	// Configura o router
-	r := routes.SetupRouter(container.DB)
+	r := routes.SetupRouter(container.RouterDependencies())
 
 	// Inicia o servidor
 	port := os.Getenv("PORT")
@@ -106,7 +117,33 @@ func main() {
 		port = "8080"
 	}
 
-	if err := r.Run(":" + port); err != nil {
-		logger.Fatal("Erro ao iniciar servidor: " + err.Error())
+	server := &http.Server{Addr: ":" + port, Handler: r}
+
+	// Encerra ao receber SIGINT ou SIGTERM (ex: deploys e reinícios)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			container.Close()
+			logger.Fatal("Erro ao iniciar servidor: " + err.Error())
+		}
+	case <-ctx.Done():
+		log.Info().Msg("Encerrando servidor")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("Erro ao aguardar as requisições em andamento")
+		}
 	}
+
+	// Grava o uso pendente das API keys e interrompe as tarefas em segundo plano
+	container.Close()
+	log.Info().Msg("Servidor encerrado")
 }
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"life/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// rejectRestrictedAccount responde 403 se a conta estiver suspensa ou banida,
+// ou 401 se ela não existir mais. Retorna se a requisição foi encerrada.
+func rejectRestrictedAccount(c *gin.Context, db *gorm.DB, userID uint) bool {
+	var user models.User
+	if err := db.Select("id", "status", "suspension_reason", "suspended_until").First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não encontrado"})
+		c.Abort()
+		return true
+	}
+
+	if restriction := user.Restriction(time.Now()); restriction != nil {
+		c.JSON(http.StatusForbidden, restriction)
+		c.Abort()
+		return true
+	}
+
+	return false
+}
+
+// clientOwnerID retorna o usuário que registrou o cliente OAuth2
+func clientOwnerID(db *gorm.DB, clientID string) (uint, bool) {
+	var client models.OAuthClient
+	if err := db.Select("owner_id").Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		return 0, false
+	}
+	return client.OwnerID, true
+}
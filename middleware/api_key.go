@@ -2,25 +2,24 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
+	"life/auth"
 	"life/models"
+	"life/ratelimit"
+	"life/usage"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 )
 
-// RateLimiter é uma estrutura simples para controle de rate limiting
-type RateLimiter struct {
-	requests map[string][]time.Time
-}
-
-var limiter = &RateLimiter{
-	requests: make(map[string][]time.Time),
-}
+// apiKeyRateWindow é a janela do limite de requisições das API keys (RateLimit por minuto)
+const apiKeyRateWindow = time.Minute
 
 // APIKeyAuth é um middleware para autenticação via API Key
-func APIKeyAuth(db *gorm.DB) gin.HandlerFunc {
+func APIKeyAuth(db *gorm.DB, limiter ratelimit.RateLimiter, recorder *usage.Recorder) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		apiKey := c.GetHeader("X-API-Key")
 		if apiKey == "" {
@@ -29,8 +28,10 @@ func APIKeyAuth(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		var key models.APIKey
-		if err := db.Where("key = ? AND is_active = ?", apiKey, true).First(&key).Error; err != nil {
+		// Busca pelo prefixo e compara o hash da chave completa; após uma
+		// rotação, a chave anterior é aceita até o fim do período de transição
+		key, ok := findAPIKey(db, apiKey)
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "API Key inválida"})
 			c.Abort()
 			return
@@ -43,45 +44,60 @@ func APIKeyAuth(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Rate limiting
-		if !checkRateLimit(apiKey, key.RateLimit) {
-			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Limite de requisições excedido"})
-			c.Abort()
+		// Chaves de contas suspensas ou banidas são recusadas
+		if rejectRestrictedAccount(c, db, key.UserID) {
 			return
 		}
 
-		// Atualiza último uso
-		now := time.Now()
-		db.Model(&key).Update("last_used_at", now)
+		// Registra o uso (inclusive respostas bloqueadas ou com erro) e o
+		// último uso da chave; a gravação no banco é feita em lote
+		defer func() {
+			recorder.Record(key.ID, c.FullPath(), c.Writer.Status(), time.Now())
+		}()
+
+		// Rate limiting; uma falha do limitador não deve derrubar as integrações
+		result, err := limiter.Allow(c.Request.Context(), "api_key:"+strconv.FormatUint(uint64(key.ID), 10), key.RateLimit, apiKeyRateWindow)
+		if err != nil {
+			log.Warn().Err(err).Uint("api_key_id", key.ID).Msg("Falha no rate limiting; requisição liberada")
+		} else if !applyRateLimit(c, result, apiKeyRateWindow) {
+			return
+		}
 
 		// Adiciona informações ao contexto
 		c.Set("user_id", key.UserID)
 		c.Set("api_key_id", key.ID)
 
+		// API keys são sempre restritas aos seus escopos, mesmo que vazios
+		scopes := key.Scopes
+		if scopes == nil {
+			scopes = []string{}
+		}
+		c.Set(ScopesKey, scopes)
+
 		c.Next()
 	}
 }
 
-// checkRateLimit verifica se a requisição está dentro do limite
-func checkRateLimit(key string, limit int) bool {
-	now := time.Now()
-	window := now.Add(-time.Minute)
+// findAPIKey localiza a chave ativa correspondente à chave atual ou, durante
+// o período de transição de uma rotação, à chave anterior
+func findAPIKey(db *gorm.DB, apiKey string) (*models.APIKey, bool) {
+	prefix := auth.APIKeyLookupPrefix(apiKey)
 
-	// Limpa requisições antigas
-	var validRequests []time.Time
-	for _, t := range limiter.requests[key] {
-		if t.After(window) {
-			validRequests = append(validRequests, t)
-		}
+	var candidates []models.APIKey
+	if err := db.Where("is_active = ? AND (prefix = ? OR (previous_prefix = ? AND previous_expires_at > ?))", true, prefix, prefix, time.Now()).
+		Find(&candidates).Error; err != nil {
+		return nil, false
 	}
-	limiter.requests[key] = validRequests
 
-	// Verifica limite
-	if len(validRequests) >= limit {
-		return false
+	for i := range candidates {
+		key := &candidates[i]
+		if key.Prefix == prefix && auth.SecretMatches(apiKey, key.KeyHash) {
+			return key, true
+		}
+		if key.PreviousPrefix == prefix && auth.SecretMatches(apiKey, key.PreviousKeyHash) {
+			return key, true
+		}
 	}
 
-	// Adiciona nova requisição
-	limiter.requests[key] = append(limiter.requests[key], now)
-	return true
+	return nil, false
 }
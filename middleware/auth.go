@@ -3,14 +3,18 @@ package middleware
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 
+	"life/auth"
+
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware valida o access token do header Authorization com o TokenService
+// e recusa contas suspensas ou banidas, inclusive em tokens emitidos antes da
+// suspensão
+func AuthMiddleware(tokens *auth.TokenService, db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -26,32 +30,37 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := parts[1]
-		fmt.Printf("Token recebido: %s\n", tokenString)
-		fmt.Printf("JWT_SECRET: %s\n", os.Getenv("JWT_SECRET"))
-
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-
+		claims, err := tokens.Validate(parts[1])
 		if err != nil {
-			fmt.Printf("Erro ao validar token: %v\n", err)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": fmt.Sprintf("Invalid token: %v", err)})
 			c.Abort()
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			fmt.Printf("Claims do token: %v\n", claims)
-			c.Set("user_id", uint(claims["user_id"].(float64)))
-			c.Next()
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
+		// Tokens client_credentials não têm usuário; vale a conta do dono do cliente
+		accountID := claims.UserID
+		if accountID == 0 && claims.Delegated() {
+			ownerID, ok := clientOwnerID(db, claims.ClientID)
+			if !ok {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Cliente OAuth2 não encontrado"})
+				c.Abort()
+				return
+			}
+			accountID = ownerID
+		}
+		if rejectRestrictedAccount(c, db, accountID) {
 			return
 		}
+
+		c.Set("user_id", claims.UserID)
+		c.Set("token_id", claims.ID)
+		c.Set("token_expires_at", claims.ExpiresAt.Time)
+
+		// Tokens emitidos para clientes OAuth2 ficam restritos aos escopos concedidos
+		if claims.Delegated() {
+			c.Set("client_id", claims.ClientID)
+			c.Set(ScopesKey, claims.Scopes())
+		}
+		c.Next()
 	}
 }
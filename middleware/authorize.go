@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"life/auth"
+	"life/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RoleKey é a chave do contexto com o papel efetivo do usuário autorizado
+const RoleKey = "role"
+
+// Subject identifica quem faz a requisição na verificação das políticas
+type Subject struct {
+	// ID do usuário autenticado; zero em tokens client_credentials, que não
+	// agem em nome de nenhum usuário
+	UserID uint
+
+	// Papel efetivo do usuário ou auth.RoleClient em tokens client_credentials
+	Role string
+}
+
+// Policy decide se o Subject pode acessar a rota
+type Policy func(c *gin.Context, subject Subject) bool
+
+// Authorize carrega o papel do usuário autenticado e aplica a política,
+// respondendo 403 quando ela nega o acesso. Deve ser usado após o
+// AuthMiddleware ou o APIKeyAuth.
+func Authorize(db *gorm.DB, policy Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject, ok := loadSubject(db, c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não encontrado"})
+			c.Abort()
+			return
+		}
+		c.Set(RoleKey, subject.Role)
+
+		if !policy(c, subject) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Acesso negado"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// HasPermission permite o acesso a quem tem a permissão
+func HasPermission(permission auth.Permission) Policy {
+	return func(c *gin.Context, subject Subject) bool {
+		return auth.RoleHasPermission(subject.Role, permission)
+	}
+}
+
+// SelfOrPermission permite o acesso ao próprio usuário, identificado pelo
+// parâmetro da rota, ou a quem tem a permissão
+func SelfOrPermission(param string, permission auth.Permission) Policy {
+	return func(c *gin.Context, subject Subject) bool {
+		if subject.UserID != 0 && c.Param(param) == strconv.FormatUint(uint64(subject.UserID), 10) {
+			return true
+		}
+		return auth.RoleHasPermission(subject.Role, permission)
+	}
+}
+
+// loadSubject busca o papel atual no banco, para que alterações de papel
+// valham imediatamente, sem esperar a expiração dos tokens. Tokens
+// client_credentials recebem sempre auth.RoleClient, sem consultar o dono do
+// cliente (o AuthMiddleware já recusa clientes de contas restritas).
+func loadSubject(db *gorm.DB, c *gin.Context) (Subject, bool) {
+	subject := Subject{UserID: c.GetUint("user_id")}
+
+	if subject.UserID == 0 {
+		if c.GetString("client_id") == "" {
+			return subject, false
+		}
+		subject.Role = auth.RoleClient
+		return subject, true
+	}
+
+	var user models.User
+	if err := db.Select("id", "role", "email", "email_verified").First(&user, subject.UserID).Error; err != nil {
+		return subject, false
+	}
+
+	subject.Role = auth.EffectiveRole(user.Role, user.Email, user.EmailVerified)
+	return subject, true
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	"life/auth"
+	"life/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RequireVerifiedEmail bloqueia usuários com email não verificado quando a
+// política configurada exige verificação nas rotas. Deve ser usado após o
+// AuthMiddleware.
+func RequireVerifiedEmail(db *gorm.DB, policy auth.EmailVerificationPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !policy.BlocksRoutes() {
+			c.Next()
+			return
+		}
+
+		var user models.User
+		if err := db.Select("id", "email_verified").First(&user, c.GetUint("user_id")).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não encontrado"})
+			c.Abort()
+			return
+		}
+
+		if !user.EmailVerified {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Email não verificado"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -11,13 +11,29 @@ func MethodNotAllowed() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Define os métodos permitidos para cada rota
 		allowedMethods := map[string][]string{
-			"/api/v1/register":  {"POST"},
-			"/api/v1/login":     {"POST"},
-			"/api/v1/refresh":   {"POST"},
-			"/api/v1/logout":    {"POST"},
-			"/api/v1/profile":   {"GET", "PUT"},
-			"/api/v1/users":     {"GET"},
-			"/api/v1/users/:id": {"GET", "PUT"},
+			"/api/v1/register":            {"POST"},
+			"/api/v1/login":               {"POST"},
+			"/api/v1/login/2fa":           {"POST"},
+			"/api/v1/refresh":             {"POST"},
+			"/api/v1/logout":              {"POST"},
+			"/api/v1/verify-email":        {"POST"},
+			"/api/v1/verify-email/resend": {"POST"},
+			"/api/v1/password/forgot":     {"POST"},
+			"/api/v1/password/reset":      {"POST"},
+			"/api/v1/logout-all":          {"POST"},
+			"/api/v1/2fa/setup":           {"POST"},
+			"/api/v1/2fa/enable":          {"POST"},
+			"/api/v1/2fa/disable":         {"POST"},
+			"/api/v1/identities":          {"GET"},
+			"/api/v1/oauth/token":         {"POST"},
+			"/api/v1/oauth/authorize":     {"GET", "POST"},
+			"/api/v1/oauth/clients":       {"GET", "POST"},
+			"/api/v1/device/code":         {"POST"},
+			"/api/v1/device/token":        {"POST"},
+			"/api/v1/device/approve":      {"POST"},
+			"/api/v1/profile":             {"GET", "PUT"},
+			"/api/v1/users":               {"GET"},
+			"/api/v1/users/:id":           {"GET", "PUT"},
 		}
 
 		// Obtém os métodos permitidos para a rota atual
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"life/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// RateLimitKey define como as requisições são agrupadas em uma política
+type RateLimitKey int
+
+const (
+	// RateLimitByIP agrupa as requisições pelo IP do cliente
+	RateLimitByIP RateLimitKey = iota
+
+	// RateLimitByUser agrupa pelo usuário autenticado (ou cliente OAuth2),
+	// recorrendo ao IP quando não há autenticação
+	RateLimitByUser
+)
+
+// RateLimitPolicy define o limite de requisições de uma rota
+type RateLimitPolicy struct {
+	// Nome da política, usado na chave do limitador e na configuração
+	Name string
+
+	// Requisições permitidas por janela
+	Limit int
+
+	// Janela em que o limite é reposto
+	Window time.Duration
+
+	// Agrupamento das requisições
+	By RateLimitKey
+}
+
+// RateLimitRoutes aplica as políticas de limite às rotas correspondentes.
+// As políticas são indexadas por "MÉTODO /caminho/:param", como registradas no
+// router; rotas sem política não são limitadas. Deve ser registrado após o
+// middleware de autenticação para agrupar por usuário.
+func RateLimitRoutes(limiter ratelimit.RateLimiter, policies map[string]RateLimitPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy, exists := policies[c.Request.Method+" "+c.FullPath()]
+		if !exists {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.Allow(c.Request.Context(), policy.Name+":"+rateLimitSubject(c, policy.By), policy.Limit, policy.Window)
+		if err != nil {
+			log.Warn().Err(err).Str("policy", policy.Name).Msg("Falha no rate limiting; requisição liberada")
+			c.Next()
+			return
+		}
+
+		if !applyRateLimit(c, result, policy.Window) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// applyRateLimit escreve os cabeçalhos RateLimit-* e, se o limite foi
+// excedido, responde 429 com Retry-After e interrompe a requisição
+func applyRateLimit(c *gin.Context, result ratelimit.Result, window time.Duration) bool {
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", result.Limit, ceilSeconds(window)))
+
+	if result.Allowed {
+		return true
+	}
+
+	retryAfter := ceilSeconds(result.RetryAfter)
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Limite de requisições excedido",
+		"retry_after": retryAfter,
+	})
+	c.Abort()
+	return false
+}
+
+// rateLimitSubject identifica quem está sendo limitado
+func rateLimitSubject(c *gin.Context, by RateLimitKey) string {
+	if by == RateLimitByUser {
+		if userID := c.GetUint("user_id"); userID != 0 {
+			return "user:" + strconv.FormatUint(uint64(userID), 10)
+		}
+		if clientID := c.GetString("client_id"); clientID != "" {
+			return "client:" + clientID
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// ceilSeconds converte a duração em segundos inteiros, arredondando para cima
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+
+	"life/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScopesKey é a chave do contexto com os escopos de uma credencial restrita.
+// Sem ela, a requisição foi autenticada pelo próprio usuário e tem acesso total.
+const ScopesKey = "scopes"
+
+// RequireScope exige que credenciais restritas possuam o escopo informado.
+// Responde 403 indicando o escopo ausente.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, restricted := c.Get(ScopesKey)
+		if !restricted {
+			c.Next()
+			return
+		}
+
+		scopes, _ := value.([]string)
+		if !auth.HasScope(scopes, scope) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":         "Escopo insuficiente",
+				"missing_scope": scope,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireFirstParty bloqueia credenciais restritas por escopo, reservando a
+// rota para o próprio usuário (ex: sessões, 2FA, API keys e clientes OAuth2)
+func RequireFirstParty() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, restricted := c.Get(ScopesKey); restricted {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Esta rota não aceita tokens emitidos para aplicações de terceiros"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -15,11 +15,11 @@ func RequestValidation() gin.HandlerFunc {
 				"username":     "joaosilva",
 				"display_name": "João Silva",
 				"email":        "joao@email.com",
-				"password":     "senha123",
+				"password":     "Senha-Forte-2024",
 			},
 			"/login": map[string]interface{}{
 				"username": "joaosilva",
-				"password": "senha123",
+				"password": "Senha-Forte-2024",
 			},
 			"/refresh": map[string]interface{}{
 				"refresh_token": "seu_refresh_token_aqui",
@@ -15,8 +15,26 @@ type APIKey struct {
 	// Nome da chave para identificação
 	Name string `json:"name" gorm:"not null" example:"Frontend App"`
 
-	// Chave de API (hash)
-	Key string `json:"key" gorm:"unique;not null"`
+	// Chave de API em texto puro, retornada apenas na criação e nunca armazenada
+	Key string `json:"key,omitempty" gorm:"-" example:"life_Ab3dE9xQ..."`
+
+	// Prefixo da chave, exibido no lugar da chave completa e usado na busca
+	Prefix string `json:"prefix" gorm:"uniqueIndex;not null" example:"life_Ab3dE9x"`
+
+	// Hash SHA-256 da chave
+	KeyHash string `json:"-" gorm:"not null"`
+
+	// Prefixo da chave anterior, aceita até PreviousExpiresAt após uma rotação
+	PreviousPrefix string `json:"-" gorm:"index"`
+
+	// Hash SHA-256 da chave anterior
+	PreviousKeyHash string `json:"-"`
+
+	// Fim do período de transição da chave anterior
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty" example:"2024-05-26T20:00:00Z"`
+
+	// Data da última rotação
+	RotatedAt *time.Time `json:"rotated_at,omitempty" example:"2024-05-25T20:00:00Z"`
 
 	// ID do usuário dono da chave
 	UserID uint `json:"user_id" gorm:"not null"`
@@ -27,6 +45,9 @@ type APIKey struct {
 	// Último uso da chave
 	LastUsedAt *time.Time `json:"last_used_at" example:"2024-05-25T20:00:00Z"`
 
+	// Escopos concedidos à chave; rotas que exigem outros escopos respondem 403
+	Scopes []string `json:"scopes" gorm:"serializer:json" example:"profile:read,scores:write"`
+
 	// Limite de requisições por minuto
 	RateLimit int `json:"rate_limit" gorm:"default:60" example:"60"`
 
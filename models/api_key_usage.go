@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKeyUsage acumula as requisições de uma API key por dia, rota e status
+type APIKeyUsage struct {
+	// ID único do registro
+	ID uint `json:"-" gorm:"primaryKey"`
+
+	// ID da API key
+	APIKeyID uint `json:"api_key_id" gorm:"not null;uniqueIndex:idx_api_key_usage"`
+
+	// Dia (UTC) das requisições
+	Day time.Time `json:"day" gorm:"type:date;not null;uniqueIndex:idx_api_key_usage"`
+
+	// Rota acessada, como registrada no router (ex: /api/v1/integrations/users/:id)
+	Route string `json:"route" gorm:"not null;uniqueIndex:idx_api_key_usage"`
+
+	// Status HTTP da resposta
+	Status int `json:"status" gorm:"not null;uniqueIndex:idx_api_key_usage"`
+
+	// Quantidade de requisições
+	Count int64 `json:"count" gorm:"not null"`
+
+	// Data da última atualização
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditEvent registra uma ação relevante para a segurança. A tabela é apenas
+// de inserção: alterações e exclusões são bloqueadas por trigger no banco.
+// @Description Evento do log de auditoria
+type AuditEvent struct {
+	// ID único do evento, crescente na ordem de inserção
+	ID uint `json:"id" gorm:"primaryKey" example:"1"`
+
+	// Data do evento
+	CreatedAt time.Time `json:"created_at" gorm:"index" example:"2024-05-25T20:00:00Z"`
+
+	// Usuário que executou a ação; nulo em ações anônimas (ex: login com usuário inexistente)
+	ActorID *uint `json:"actor_id" gorm:"index" example:"1"`
+
+	// Ação executada (ex: auth.login, api_key.created, user.updated)
+	Action string `json:"action" gorm:"index;not null" example:"api_key.created"`
+
+	// Tipo e identificador do recurso afetado
+	TargetType string `json:"target_type,omitempty" gorm:"index:idx_audit_target" example:"api_key"`
+	TargetID   string `json:"target_id,omitempty" gorm:"index:idx_audit_target" example:"42"`
+
+	// Indica se a ação foi concluída ou recusada (ex: senha incorreta)
+	Success bool `json:"success" example:"true"`
+
+	// Origem da requisição
+	IP        string `json:"ip" example:"203.0.113.7"`
+	UserAgent string `json:"user_agent" example:"Mozilla/5.0"`
+	RequestID string `json:"request_id" gorm:"index" example:"4f9c2b7e1a3d5c6b"`
+
+	// Detalhes específicos da ação (ex: email anterior, escopos)
+	Metadata map[string]interface{} `json:"metadata,omitempty" gorm:"serializer:json"`
+}
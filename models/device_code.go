@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// Estados de uma autorização de dispositivo
+const (
+	DeviceCodePending  = "pending"
+	DeviceCodeApproved = "approved"
+	DeviceCodeDenied   = "denied"
+	DeviceCodeConsumed = "consumed"
+)
+
+// DeviceCode representa uma autorização de dispositivo (RFC 8628) em andamento
+type DeviceCode struct {
+	// ID único do registro
+	ID uint `gorm:"primaryKey"`
+
+	// Hash SHA-256 do device_code usado pelo dispositivo na consulta
+	DeviceCodeHash string `gorm:"uniqueIndex;not null"`
+
+	// Código digitado pelo usuário, normalizado (sem separador)
+	UserCode string `gorm:"uniqueIndex;not null"`
+
+	// Nome do dispositivo, usado na sessão criada
+	DeviceName string
+
+	// Estado da autorização (pending, approved, denied ou consumed)
+	Status string `gorm:"not null;default:pending"`
+
+	// Usuário que aprovou o dispositivo
+	UserID *uint
+
+	// Intervalo mínimo entre consultas, em segundos
+	PollInterval int `gorm:"not null"`
+
+	// Data da última consulta do dispositivo
+	LastPolledAt *time.Time
+
+	// Data de expiração
+	ExpiresAt time.Time `gorm:"index"`
+
+	// Data de criação
+	CreatedAt time.Time
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// Identity representa uma identidade externa (OpenID Connect) vinculada a um usuário
+// @Description Provedor de login externo vinculado à conta
+type Identity struct {
+	// ID único do vínculo
+	ID uint `json:"id" gorm:"primaryKey" example:"1"`
+
+	// ID do usuário dono da identidade
+	UserID uint `json:"user_id" gorm:"index;not null" example:"1"`
+
+	// Nome do provedor
+	Provider string `json:"provider" gorm:"not null;uniqueIndex:idx_identity_provider_subject" example:"google"`
+
+	// Identificador do usuário no provedor (claim "sub")
+	Subject string `json:"-" gorm:"not null;uniqueIndex:idx_identity_provider_subject"`
+
+	// Email informado pelo provedor no momento do vínculo
+	Email string `json:"email" example:"john@example.com"`
+
+	// Data de criação
+	CreatedAt time.Time `json:"created_at" example:"2024-05-25T20:00:00Z"`
+
+	// Data da última atualização
+	UpdatedAt time.Time `json:"updated_at" example:"2024-05-25T20:00:00Z"`
+}
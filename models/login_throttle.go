@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginThrottle registra as falhas de login recentes de uma conta ou de um IP
+// @Description Estado de bloqueio por tentativas de login malsucedidas
+type LoginThrottle struct {
+	// Chave controlada, no formato "user:<username>" ou "ip:<endereço>"
+	Key string `json:"key" gorm:"primaryKey" example:"user:johndoe"`
+
+	// Falhas consecutivas dentro da janela de contagem
+	Failures int `json:"failures" gorm:"not null;default:0" example:"3"`
+
+	// Data da última falha
+	LastFailureAt time.Time `json:"last_failure_at" gorm:"index" example:"2024-05-25T20:00:00Z"`
+
+	// Bloqueado até esta data (nulo quando não há bloqueio)
+	LockedUntil *time.Time `json:"locked_until" example:"2024-05-25T20:05:00Z"`
+}
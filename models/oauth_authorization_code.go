@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// OAuthAuthorizationCode representa um código de autorização OAuth2 emitido após o consentimento
+type OAuthAuthorizationCode struct {
+	// ID único do código
+	ID uint `gorm:"primaryKey"`
+
+	// Hash SHA-256 do código
+	CodeHash string `gorm:"uniqueIndex;not null"`
+
+	// Cliente para o qual o código foi emitido
+	ClientID string `gorm:"index;not null"`
+
+	// Usuário que autorizou o cliente
+	UserID uint `gorm:"not null"`
+
+	// URI de retorno usada na autorização
+	RedirectURI string `gorm:"not null"`
+
+	// Indica se a redirect_uri foi informada na autorização; só nesse caso ela
+	// é exigida novamente na troca (RFC 6749, seção 4.1.3)
+	RedirectURIProvided bool
+
+	// Escopos concedidos
+	Scopes []string `gorm:"serializer:json"`
+
+	// code_challenge S256 do PKCE
+	CodeChallenge string `gorm:"not null"`
+
+	// Data de expiração
+	ExpiresAt time.Time `gorm:"index"`
+
+	// Data de utilização (nulo enquanto disponível)
+	UsedAt *time.Time
+
+	// Data de criação
+	CreatedAt time.Time
+}
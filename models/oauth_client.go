@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OAuthClient representa um cliente OAuth2 registrado (ferramenta de terceiros)
+// @Description Cliente OAuth2 registrado
+type OAuthClient struct {
+	// ID único do cliente
+	ID uint `json:"id" gorm:"primaryKey" example:"1"`
+
+	// Identificador público do cliente (client_id)
+	ClientID string `json:"client_id" gorm:"uniqueIndex;not null" example:"3f6c1e0a9b2d4c7e8f1a2b3c4d5e6f70"`
+
+	// Segredo em texto puro, retornado apenas na criação e nunca armazenado
+	ClientSecret string `json:"client_secret,omitempty" gorm:"-" example:"Xo2k9..."`
+
+	// Hash SHA-256 do segredo; vazio em clientes públicos
+	SecretHash string `json:"-"`
+
+	// Nome exibido na tela de consentimento
+	Name string `json:"name" gorm:"not null" example:"Life Stats Tracker"`
+
+	// URIs de retorno aceitas, comparadas exatamente
+	RedirectURIs []string `json:"redirect_uris" gorm:"serializer:json" example:"https://stats.example.com/callback"`
+
+	// Escopos que o cliente pode solicitar
+	Scopes []string `json:"scopes" gorm:"serializer:json" example:"profile:read,scores:read"`
+
+	// Indica se o cliente guarda um segredo (aplicações servidor) ou é público (SPAs e apps nativos)
+	Confidential bool `json:"confidential" example:"true"`
+
+	// ID do usuário que registrou o cliente
+	OwnerID uint `json:"owner_id" gorm:"index;not null" example:"1"`
+
+	// Data de criação
+	CreatedAt time.Time `json:"created_at" example:"2024-05-25T20:00:00Z"`
+
+	// Data da última atualização
+	UpdatedAt time.Time `json:"updated_at" example:"2024-05-25T20:00:00Z"`
+
+	// Data de exclusão (soft delete)
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// OAuthConsent registra os escopos que um usuário já autorizou para um cliente
+// @Description Consentimento dado a um cliente OAuth2
+type OAuthConsent struct {
+	// ID único do consentimento
+	ID uint `json:"id" gorm:"primaryKey" example:"1"`
+
+	// ID do usuário
+	UserID uint `json:"user_id" gorm:"not null;uniqueIndex:idx_oauth_consent_user_client" example:"1"`
+
+	// Cliente autorizado
+	ClientID string `json:"client_id" gorm:"not null;uniqueIndex:idx_oauth_consent_user_client" example:"3f6c1e0a9b2d4c7e8f1a2b3c4d5e6f70"`
+
+	// Escopos autorizados
+	Scopes []string `json:"scopes" gorm:"serializer:json" example:"profile:read"`
+
+	// Data de criação
+	CreatedAt time.Time `json:"created_at" example:"2024-05-25T20:00:00Z"`
+
+	// Data da última atualização
+	UpdatedAt time.Time `json:"updated_at" example:"2024-05-25T20:00:00Z"`
+}
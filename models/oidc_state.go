@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// OIDCState guarda os dados de uma autorização OpenID Connect em andamento
+type OIDCState struct {
+	// ID único do registro
+	ID uint `gorm:"primaryKey"`
+
+	// Hash SHA-256 do parâmetro state enviado ao provedor
+	StateHash string `gorm:"uniqueIndex;not null"`
+
+	// Nome do provedor
+	Provider string `gorm:"not null"`
+
+	// code_verifier do PKCE
+	CodeVerifier string `gorm:"not null"`
+
+	// Nonce esperado no id_token
+	Nonce string `gorm:"not null"`
+
+	// Usuário que está vinculando o provedor; nulo em fluxos de login
+	UserID *uint
+
+	// Hash SHA-256 do cookie que identifica o navegador que iniciou o vínculo;
+	// vazio em fluxos de login
+	LinkBindingHash string
+
+	// Nome do dispositivo informado no início do login
+	DeviceName string
+
+	// Data de expiração
+	ExpiresAt time.Time `gorm:"index"`
+
+	// Data de criação
+	CreatedAt time.Time
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordResetToken representa uma solicitação de redefinição de senha
+// @Description Token de redefinição de senha de uso único
+type PasswordResetToken struct {
+	// ID único do token
+	ID uint `json:"id" gorm:"primaryKey" example:"1"`
+
+	// ID do usuário que solicitou a redefinição
+	UserID uint `json:"user_id" gorm:"index;not null" example:"1"`
+
+	// Hash SHA-256 do token enviado por email
+	TokenHash string `json:"-" gorm:"uniqueIndex;not null"`
+
+	// Data de expiração do token
+	ExpiresAt time.Time `json:"expires_at" example:"2024-05-25T21:00:00Z"`
+
+	// Data de utilização (nulo enquanto disponível)
+	UsedAt *time.Time `json:"used_at" example:"2024-05-25T20:30:00Z"`
+
+	// Data de criação
+	CreatedAt time.Time `json:"created_at" example:"2024-05-25T20:00:00Z"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// RateLimitBucket guarda o token bucket de uma chave no limitador compartilhado (RATE_LIMIT_BACKEND=postgres)
+type RateLimitBucket struct {
+	// Chave controlada (ex: "api_key:42")
+	Key string `gorm:"primaryKey"`
+
+	// Fichas disponíveis após a última requisição
+	Tokens float64 `gorm:"not null"`
+
+	// Resultado da última requisição
+	Allowed bool `gorm:"not null"`
+
+	// Data da última requisição, usada para repor as fichas
+	UpdatedAt time.Time `gorm:"index"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode representa um código de recuperação da verificação em duas etapas
+// @Description Código de recuperação de uso único
+type RecoveryCode struct {
+	// ID único do código
+	ID uint `json:"id" gorm:"primaryKey" example:"1"`
+
+	// ID do usuário dono do código
+	UserID uint `json:"user_id" gorm:"index;not null" example:"1"`
+
+	// Hash SHA-256 do código normalizado
+	CodeHash string `json:"-" gorm:"not null"`
+
+	// Data de utilização (nulo enquanto disponível)
+	UsedAt *time.Time `json:"used_at" example:"2024-05-25T20:00:00Z"`
+
+	// Data de criação
+	CreatedAt time.Time `json:"created_at" example:"2024-05-25T20:00:00Z"`
+}
@@ -12,12 +12,36 @@ type RefreshToken struct {
 	// ID único do token
 	ID uint `json:"id" gorm:"primaryKey" example:"1"`
 
-	// Token de atualização
-	Token string `json:"token" gorm:"uniqueIndex;not null" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	// Hash SHA-256 do token de atualização (o token em si nunca é armazenado)
+	TokenHash string `json:"-" gorm:"uniqueIndex;not null"`
 
 	// ID do usuário dono do token
 	UserID uint `json:"user_id" gorm:"not null" example:"1"`
 
+	// Identificador da família de tokens (todos os tokens gerados a partir do mesmo login)
+	FamilyID string `json:"family_id" gorm:"index" example:"3f2b8c1d9e7a4b6c"`
+
+	// ID do token que originou este na rotação (nulo para o primeiro token da família)
+	ParentID *uint `json:"parent_id" example:"1"`
+
+	// Identificador (jti) do access token emitido junto com este refresh token
+	AccessTokenID string `json:"-" gorm:"index"`
+
+	// Expiração do access token emitido junto com este refresh token
+	AccessTokenExpiresAt time.Time `json:"-" gorm:"index"`
+
+	// Nome do dispositivo informado pelo cliente no login
+	DeviceName string `json:"device_name" example:"PC da sala"`
+
+	// User agent da requisição que gerou o token
+	UserAgent string `json:"user_agent" example:"LifeClient/1.4 (Windows)"`
+
+	// IP da requisição que gerou o token
+	IP string `json:"ip" example:"203.0.113.10"`
+
+	// Data da última utilização da sessão
+	LastUsedAt time.Time `json:"last_used_at" example:"2024-05-25T20:00:00Z"`
+
 	// Data de expiração do token
 	ExpiresAt time.Time `json:"expires_at" gorm:"not null" example:"2024-12-31T23:59:59Z"`
 
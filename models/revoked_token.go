@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// RevokedToken representa um access token revogado antes da expiração
+// @Description Entrada da denylist de access tokens
+type RevokedToken struct {
+	// ID único da entrada
+	ID uint `json:"id" gorm:"primaryKey" example:"1"`
+
+	// Identificador (jti) do access token revogado
+	JTI string `json:"jti" gorm:"uniqueIndex;not null" example:"9f86d081884c7d659a2feaa0c55ad015"`
+
+	// Expiração original do token; depois dela a entrada pode ser descartada
+	ExpiresAt time.Time `json:"expires_at" gorm:"index;not null" example:"2024-05-25T21:00:00Z"`
+
+	// Data da revogação
+	CreatedAt time.Time `json:"created_at" gorm:"index" example:"2024-05-25T20:00:00Z"`
+}
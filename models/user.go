@@ -6,6 +6,13 @@ import (
 	"gorm.io/gorm"
 )
 
+// Situações da conta de um usuário
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusBanned    = "banned"
+)
+
 // User representa um usuário no sistema
 // @Description Informações do usuário
 type User struct {
@@ -21,9 +28,36 @@ type User struct {
 	// Email do usuário
 	Email string `json:"email" binding:"required,email" gorm:"unique;not null" example:"john@example.com"`
 
+	// Indica se o email foi confirmado pelo usuário
+	EmailVerified bool `json:"email_verified" gorm:"default:false" example:"true"`
+
+	// Data da confirmação do email
+	EmailVerifiedAt *time.Time `json:"email_verified_at" example:"2024-05-25T20:00:00Z"`
+
 	// Senha do usuário (não serializada)
 	Password string `json:"password" binding:"required,min=6" gorm:"not null"`
 
+	// Papel do usuário (user ou admin), que define as permissões sobre recursos de outros usuários
+	Role string `json:"role" gorm:"not null;default:user" example:"user"`
+
+	// Situação da conta (active, suspended ou banned)
+	Status string `json:"status" gorm:"not null;default:active" example:"active"`
+
+	// Motivo da suspensão ou do banimento
+	SuspensionReason string `json:"suspension_reason,omitempty" example:"Linguagem ofensiva no chat"`
+
+	// Fim da suspensão; nulo em banimentos
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty" example:"2024-06-01T00:00:00Z"`
+
+	// Segredo TOTP da verificação em duas etapas (pendente até a ativação)
+	TOTPSecret string `json:"-"`
+
+	// Indica se a verificação em duas etapas está ativa
+	TOTPEnabled bool `json:"totp_enabled" gorm:"default:false" example:"false"`
+
+	// Último passo de tempo TOTP aceito, impede a reutilização de códigos
+	TOTPLastStep int64 `json:"-"`
+
 	// Data de criação
 	CreatedAt time.Time `json:"created_at" example:"2024-05-25T20:00:00Z"`
 
@@ -33,3 +67,24 @@ type User struct {
 	// Data de exclusão (soft delete)
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
+
+// AccountRestriction descreve a suspensão ou o banimento em vigor de uma conta
+type AccountRestriction struct {
+	Error          string     `json:"error" example:"Conta suspensa"`
+	Status         string     `json:"status" example:"suspended"`
+	Reason         string     `json:"reason,omitempty" example:"Linguagem ofensiva no chat"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty" example:"2024-06-01T00:00:00Z"`
+}
+
+// Restriction retorna o banimento ou a suspensão em vigor, ou nil se a conta
+// estiver liberada. Suspensões expiradas deixam de valer sem alteração no banco.
+func (u *User) Restriction(now time.Time) *AccountRestriction {
+	switch {
+	case u.Status == UserStatusBanned:
+		return &AccountRestriction{Error: "Conta banida", Status: u.Status, Reason: u.SuspensionReason}
+	case u.Status == UserStatusSuspended && u.SuspendedUntil != nil && u.SuspendedUntil.After(now):
+		return &AccountRestriction{Error: "Conta suspensa", Status: u.Status, Reason: u.SuspensionReason, SuspendedUntil: u.SuspendedUntil}
+	default:
+		return nil
+	}
+}
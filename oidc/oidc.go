@@ -0,0 +1,87 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"life/auth"
+)
+
+// providerHTTPTimeout limita a duração das chamadas aos provedores
+const providerHTTPTimeout = 10 * time.Second
+
+// defaultScopes são os escopos solicitados quando OIDC_<NOME>_SCOPES não está definida
+var defaultScopes = []string{"openid", "email", "profile"}
+
+// Config define um provedor OpenID Connect
+type Config struct {
+	// Nome usado nas rotas (ex: "google")
+	Name string
+
+	// Emissor; o documento de descoberta é buscado em <Issuer>/.well-known/openid-configuration
+	Issuer string
+
+	ClientID     string
+	ClientSecret string
+
+	// URL de retorno registrada no provedor
+	RedirectURL string
+
+	Scopes []string
+}
+
+// LoadProvidersFromEnv carrega os provedores listados em OIDC_PROVIDERS
+// (ex: "google,microsoft"). Cada provedor é configurado com OIDC_<NOME>_ISSUER,
+// OIDC_<NOME>_CLIENT_ID, OIDC_<NOME>_CLIENT_SECRET, OIDC_<NOME>_REDIRECT_URL e,
+// opcionalmente, OIDC_<NOME>_SCOPES (separados por espaço).
+func LoadProvidersFromEnv() (map[string]*Provider, error) {
+	providers := make(map[string]*Provider)
+	client := &http.Client{Timeout: providerHTTPTimeout}
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		config := Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       defaultScopes,
+		}
+		if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+			config.Scopes = strings.Fields(scopes)
+		}
+
+		if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+			return nil, fmt.Errorf("provedor OIDC %q incompleto: %sISSUER, %sCLIENT_ID e %sREDIRECT_URL são obrigatórias", name, prefix, prefix, prefix)
+		}
+
+		providers[name] = NewProvider(config, client)
+	}
+
+	return providers, nil
+}
+
+// RandomString gera um valor aleatório seguro para state, nonce e code_verifier
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge calcula o code_challenge S256 de um code_verifier (RFC 7636)
+func CodeChallenge(verifier string) string {
+	return auth.PKCEChallenge(verifier)
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const (
+	// memoryShards é a quantidade de partições do limitador em memória,
+	// reduzindo a disputa pelo lock entre requisições concorrentes
+	memoryShards = 32
+
+	// maxEntriesPerShard limita a memória usada por partição
+	maxEntriesPerShard = 10000
+
+	// memorySweepInterval define de quanto em quanto tempo cada partição descarta chaves ociosas
+	memorySweepInterval = time.Minute
+)
+
+// MemoryLimiter implementa o RateLimiter em memória. É seguro para uso
+// concorrente, mas o estado vale apenas para a instância atual.
+type MemoryLimiter struct {
+	shards [memoryShards]*memoryShard
+}
+
+// memoryShard guarda os baldes de uma partição
+type memoryShard struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+// memoryBucket guarda as fichas de uma chave
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+
+	// Momento em que o balde volta a ficar cheio e pode ser descartado
+	fullAt time.Time
+}
+
+// NewMemoryLimiter cria uma nova instância do MemoryLimiter
+func NewMemoryLimiter() *MemoryLimiter {
+	l := &MemoryLimiter{}
+	for i := range l.shards {
+		l.shards[i] = &memoryShard{buckets: make(map[string]*memoryBucket), lastSweep: time.Now()}
+	}
+	return l
+}
+
+// Allow implementa RateLimiter
+func (l *MemoryLimiter) Allow(_ context.Context, key string, limit int, window time.Duration) (Result, error) {
+	if limit <= 0 {
+		return denied(limit, window), nil
+	}
+
+	shard := l.shard(key)
+	now := time.Now()
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	shard.sweepIfStale(now)
+
+	bucket, ok := shard.buckets[key]
+	if !ok {
+		shard.evictIfFull()
+		bucket = &memoryBucket{tokens: float64(limit), updatedAt: now}
+		shard.buckets[key] = bucket
+	}
+
+	tokens := refill(bucket.tokens, now.Sub(bucket.updatedAt), limit, window)
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+
+	bucket.tokens = tokens
+	bucket.updatedAt = now
+	result := newResult(allowed, tokens, limit, window)
+	bucket.fullAt = now.Add(result.ResetAfter)
+
+	return result, nil
+}
+
+// shard retorna a partição da chave
+func (l *MemoryLimiter) shard(key string) *memoryShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return l.shards[h.Sum32()%memoryShards]
+}
+
+// sweepIfStale descarta os baldes que já voltaram a ficar cheios, pois
+// equivalem a uma chave nunca vista. Deve ser chamado com o lock da partição.
+func (s *memoryShard) sweepIfStale(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, bucket := range s.buckets {
+		if !bucket.fullAt.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// evictIfFull abre espaço para uma nova chave quando a partição está cheia,
+// descartando o balde mais próximo de ficar cheio. Deve ser chamado com o lock da partição.
+func (s *memoryShard) evictIfFull() {
+	if len(s.buckets) < maxEntriesPerShard {
+		return
+	}
+
+	var victim string
+	var earliest time.Time
+	for key, bucket := range s.buckets {
+		if victim == "" || bucket.fullAt.Before(earliest) {
+			victim, earliest = key, bucket.fullAt
+		}
+	}
+	delete(s.buckets, victim)
+}
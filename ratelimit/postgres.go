@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"life/models"
+
+	"gorm.io/gorm"
+)
+
+// postgresPruneInterval define de quanto em quanto tempo os baldes ociosos são removidos
+const postgresPruneInterval = 10 * time.Minute
+
+// PostgresLimiter implementa o RateLimiter no Postgres, compartilhando o
+// estado entre todas as instâncias. Cada decisão é um único upsert atômico,
+// e o relógio usado é o do banco, evitando divergências entre instâncias.
+type PostgresLimiter struct {
+	db *gorm.DB
+
+	pruneMu   sync.Mutex
+	lastPrune time.Time
+
+	// Maior janela já usada; baldes ociosos por mais tempo já estão cheios
+	maxWindow time.Duration
+}
+
+// NewPostgresLimiter cria uma nova instância do PostgresLimiter
+func NewPostgresLimiter(db *gorm.DB) *PostgresLimiter {
+	return &PostgresLimiter{db: db, lastPrune: time.Now()}
+}
+
+// Allow implementa RateLimiter
+func (l *PostgresLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	if limit <= 0 {
+		return denied(limit, window), nil
+	}
+
+	l.pruneIfStale(window)
+
+	// As expressões do SET enxergam os valores anteriores da linha, então
+	// allowed e tokens são calculados sobre as mesmas fichas repostas
+	var row struct {
+		Tokens  float64
+		Allowed bool
+	}
+	err := l.db.WithContext(ctx).Raw(`
+		INSERT INTO rate_limit_buckets ("key", tokens, allowed, updated_at) VALUES (@key, @limit - 1, true, now())
+		ON CONFLICT ("key") DO UPDATE SET
+			allowed = LEAST(@limit, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at) * @rate) >= 1,
+			tokens = LEAST(@limit, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at) * @rate)
+				- CASE WHEN LEAST(@limit, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM now() - rate_limit_buckets.updated_at) * @rate) >= 1 THEN 1 ELSE 0 END,
+			updated_at = now()
+		RETURNING tokens, allowed`,
+		map[string]interface{}{"key": key, "limit": float64(limit), "rate": rate(limit, window)}).
+		Scan(&row).Error
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newResult(row.Allowed, row.Tokens, limit, window), nil
+}
+
+// pruneIfStale remove periodicamente os baldes ociosos há mais tempo que a maior janela
+func (l *PostgresLimiter) pruneIfStale(window time.Duration) {
+	l.pruneMu.Lock()
+	if window > l.maxWindow {
+		l.maxWindow = window
+	}
+	if time.Since(l.lastPrune) < postgresPruneInterval {
+		l.pruneMu.Unlock()
+		return
+	}
+	l.lastPrune = time.Now()
+	maxWindow := l.maxWindow
+	l.pruneMu.Unlock()
+
+	l.db.Where("updated_at < ?", time.Now().Add(-maxWindow)).Delete(&models.RateLimitBucket{})
+}
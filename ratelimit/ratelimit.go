@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Result descreve a decisão do limitador para uma requisição
+type Result struct {
+	// Indica se a requisição está dentro do limite
+	Allowed bool
+
+	// Limite de requisições por janela
+	Limit int
+
+	// Requisições ainda disponíveis de imediato
+	Remaining int
+
+	// Tempo até que uma nova requisição seja permitida; zero quando permitida
+	RetryAfter time.Duration
+
+	// Tempo até que o limite seja totalmente restabelecido
+	ResetAfter time.Duration
+}
+
+// RateLimiter controla a taxa de requisições por chave com um token bucket:
+// cada chave dispõe de até limit fichas, repostas continuamente ao longo da janela
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
+
+// NewFromEnv cria o RateLimiter configurado em RATE_LIMIT_BACKEND: memory
+// (padrão, apenas para uma instância) ou postgres (compartilhado entre instâncias)
+func NewFromEnv(db *gorm.DB) (RateLimiter, error) {
+	switch backend := strings.ToLower(os.Getenv("RATE_LIMIT_BACKEND")); backend {
+	case "", "memory":
+		return NewMemoryLimiter(), nil
+	case "postgres":
+		return NewPostgresLimiter(db), nil
+	default:
+		return nil, fmt.Errorf("RATE_LIMIT_BACKEND desconhecido: %s", backend)
+	}
+}
+
+// refill calcula as fichas disponíveis após o tempo decorrido desde a última atualização
+func refill(tokens float64, elapsed time.Duration, limit int, window time.Duration) float64 {
+	if elapsed > 0 {
+		tokens += elapsed.Seconds() * rate(limit, window)
+	}
+	return math.Min(tokens, float64(limit))
+}
+
+// rate retorna a reposição de fichas por segundo
+func rate(limit int, window time.Duration) float64 {
+	return float64(limit) / window.Seconds()
+}
+
+// newResult monta o resultado a partir das fichas restantes após a decisão
+func newResult(allowed bool, tokens float64, limit int, window time.Duration) Result {
+	result := Result{
+		Allowed:    allowed,
+		Limit:      limit,
+		Remaining:  int(math.Max(0, math.Floor(tokens))),
+		ResetAfter: secondsToDuration((float64(limit) - tokens) / rate(limit, window)),
+	}
+	if !allowed {
+		result.RetryAfter = secondsToDuration((1 - tokens) / rate(limit, window))
+	}
+	return result
+}
+
+// denied é o resultado para limites não positivos, que bloqueiam todas as requisições
+func denied(limit int, window time.Duration) Result {
+	return Result{Allowed: false, Limit: limit, RetryAfter: window, ResetAfter: window}
+}
+
+// secondsToDuration converte segundos fracionários, arredondando para cima
+func secondsToDuration(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
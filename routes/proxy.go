@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// TrustedProxiesFromEnv lê TRUSTED_PROXIES: IPs ou faixas CIDR, separados por
+// vírgula, dos proxies reversos cujo X-Forwarded-For é aceito. Vazio (padrão)
+// não confia em nenhum proxy e usa sempre o endereço da conexão.
+func TrustedProxiesFromEnv() ([]string, error) {
+	value := os.Getenv("TRUSTED_PROXIES")
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var proxies []string
+	for _, proxy := range strings.Split(value, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES inválido: %q", proxy)
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
+
+// NewEngine cria o engine do Gin confiando apenas nos proxies informados para
+// determinar o IP do cliente (c.ClientIP), usado nos limites por IP, no
+// bloqueio de login e no log de auditoria. Sem proxies, cabeçalhos como
+// X-Forwarded-For são ignorados, pois qualquer cliente pode forjá-los.
+func NewEngine(trustedProxies []string) *gin.Engine {
+	r := gin.Default()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Error().Err(err).Strs("proxies", trustedProxies).Msg("Proxies confiáveis inválidos; nenhum proxy será considerado")
+		_ = r.SetTrustedProxies(nil)
+	}
+	return r
+}
@@ -0,0 +1,74 @@
+package routes
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"life/middleware"
+)
+
+// defaultRateLimitPolicies declara os limites de cada rota, indexados por
+// "MÉTODO /caminho" como registrados no router. Rotas públicas são limitadas
+// por IP; rotas autenticadas, pelo usuário. Rotas ausentes não são limitadas.
+var defaultRateLimitPolicies = map[string]middleware.RateLimitPolicy{
+	// Rotas públicas
+	"POST /api/v1/register":            {Name: "register", Limit: 5, Window: time.Hour, By: middleware.RateLimitByIP},
+	"POST /api/v1/login":               {Name: "login", Limit: 20, Window: time.Minute, By: middleware.RateLimitByIP},
+	"POST /api/v1/login/2fa":           {Name: "login_2fa", Limit: 10, Window: time.Minute, By: middleware.RateLimitByIP},
+	"POST /api/v1/refresh":             {Name: "refresh", Limit: 30, Window: time.Minute, By: middleware.RateLimitByIP},
+	"POST /api/v1/verify-email/resend": {Name: "verify_email_resend", Limit: 5, Window: time.Hour, By: middleware.RateLimitByIP},
+	"POST /api/v1/password/forgot":     {Name: "password_forgot", Limit: 5, Window: time.Hour, By: middleware.RateLimitByIP},
+	"POST /api/v1/password/reset":      {Name: "password_reset", Limit: 10, Window: time.Hour, By: middleware.RateLimitByIP},
+	"POST /api/v1/oauth/token":         {Name: "oauth_token", Limit: 60, Window: time.Minute, By: middleware.RateLimitByIP},
+	"POST /api/v1/device/code":         {Name: "device_code", Limit: 10, Window: time.Minute, By: middleware.RateLimitByIP},
+
+	// Rotas autenticadas por JWT
+	"PUT /api/v1/profile":          {Name: "profile_update", Limit: 30, Window: time.Minute, By: middleware.RateLimitByUser},
+	"PUT /api/v1/profile/password": {Name: "password_change", Limit: 10, Window: time.Hour, By: middleware.RateLimitByUser},
+	"DELETE /api/v1/profile":       {Name: "account_delete", Limit: 10, Window: time.Hour, By: middleware.RateLimitByUser},
+	"GET /api/v1/users":            {Name: "users_list", Limit: 60, Window: time.Minute, By: middleware.RateLimitByUser},
+	"POST /api/v1/device/approve":  {Name: "device_approve", Limit: 10, Window: time.Minute, By: middleware.RateLimitByUser},
+	"POST /api/v1/2fa/enable":      {Name: "mfa_enable", Limit: 10, Window: time.Minute, By: middleware.RateLimitByUser},
+	"POST /api/v1/api-keys":        {Name: "api_key_create", Limit: 10, Window: time.Hour, By: middleware.RateLimitByUser},
+	"POST /api/v1/oauth/clients":   {Name: "oauth_client_create", Limit: 10, Window: time.Hour, By: middleware.RateLimitByUser},
+}
+
+// DefaultRateLimitPolicies retorna uma cópia das políticas padrão de limite por rota
+func DefaultRateLimitPolicies() map[string]middleware.RateLimitPolicy {
+	policies := make(map[string]middleware.RateLimitPolicy, len(defaultRateLimitPolicies))
+	for route, policy := range defaultRateLimitPolicies {
+		policies[route] = policy
+	}
+	return policies
+}
+
+// RateLimitPoliciesFromEnv retorna as políticas padrão com os limites
+// sobrescritos por RATE_LIMIT_<NOME> no formato "limite/janela" (ex:
+// RATE_LIMIT_REGISTER=10/1h)
+func RateLimitPoliciesFromEnv() (map[string]middleware.RateLimitPolicy, error) {
+	policies := DefaultRateLimitPolicies()
+	for route, policy := range policies {
+		name := "RATE_LIMIT_" + strings.ToUpper(policy.Name)
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+
+		limit, window, ok := strings.Cut(value, "/")
+		parsedLimit, err := strconv.Atoi(strings.TrimSpace(limit))
+		if !ok || err != nil || parsedLimit <= 0 {
+			return nil, fmt.Errorf("%s inválido: %q", name, value)
+		}
+		parsedWindow, err := time.ParseDuration(strings.TrimSpace(window))
+		if err != nil || parsedWindow <= 0 {
+			return nil, fmt.Errorf("%s inválido: %q", name, value)
+		}
+
+		policy.Limit, policy.Window = parsedLimit, parsedWindow
+		policies[route] = policy
+	}
+	return policies, nil
+}
@@ -1,9 +1,17 @@
 package routes
 
 import (
+	"time"
+
+	"life/auth"
 	"life/handlers"
 	"life/logger"
+	"life/mail"
 	"life/middleware"
+	"life/oidc"
+	"life/ratelimit"
+	"life/usage"
+	"life/validator"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -33,18 +41,75 @@ func NewRouter(db *gorm.DB, user *handlers.UserHandler, auth *handlers.AuthHandl
 	}
 }
 
+// Dependencies reúne os serviços compartilhados usados pelas rotas
+type Dependencies struct {
+	DB          *gorm.DB
+	Tokens      *auth.TokenService
+	Mailer      mail.Mailer
+	EmailPolicy auth.EmailVerificationPolicy
+	LoginGuard  *auth.LoginGuard
+
+	// Requisitos das senhas no registro e nas alterações de senha
+	PasswordPolicy *validator.PasswordPolicy
+
+	// Algoritmo e parâmetros dos hashes de senha
+	PasswordHasher *auth.PasswordHasher
+
+	// Período em que a chave anterior continua válida após uma rotação de API key
+	APIKeyRotationGrace time.Duration
+
+	// Período em que a exclusão da conta pode ser cancelada antes da remoção definitiva
+	AccountDeletionGrace time.Duration
+
+	// Limitador de requisições das API keys e das rotas
+	RateLimiter ratelimit.RateLimiter
+
+	// Registro em lote do uso das API keys
+	Usage *usage.Recorder
+
+	// Políticas de limite por rota; nil usa DefaultRateLimitPolicies
+	RateLimitPolicies map[string]middleware.RateLimitPolicy
+
+	// Proxies reversos cujo X-Forwarded-For é aceito; nil ignora o cabeçalho
+	TrustedProxies []string
+
+	// Provedores OpenID Connect habilitados, indexados pelo nome
+	OIDCProviders map[string]*oidc.Provider
+}
+
 // SetupRouter configura todas as rotas da aplicação
-func SetupRouter(db *gorm.DB) *gin.Engine {
-	r := gin.Default()
+func SetupRouter(deps Dependencies) *gin.Engine {
+	r := NewEngine(deps.TrustedProxies)
+	db, tokens := deps.DB, deps.Tokens
 
 	// Inicializa handlers
-	userHandler := handlers.NewUserHandler(db)
-	apiKeyHandler := handlers.NewAPIKeyHandler(db)
-	authHandler := handlers.NewAuthHandler(db)
+	userHandler := handlers.NewUserHandler(db, tokens, deps.Mailer, deps.PasswordPolicy, deps.PasswordHasher)
+	apiKeyHandler := handlers.NewAPIKeyHandler(db, deps.APIKeyRotationGrace)
+	authHandler := handlers.NewAuthHandler(db, tokens, deps.EmailPolicy, deps.LoginGuard, deps.PasswordHasher)
 	healthHandler := handlers.NewHealthHandler(db)
+	sessionHandler := handlers.NewSessionHandler(db, tokens)
+	jwksHandler := handlers.NewJWKSHandler(tokens.Keys())
+	mfaHandler := handlers.NewMFAHandler(db, deps.PasswordHasher)
+	emailHandler := handlers.NewEmailVerificationHandler(db, tokens, deps.Mailer)
+	passwordHandler := handlers.NewPasswordHandler(db, tokens, deps.Mailer, deps.PasswordPolicy, deps.PasswordHasher)
+	oidcHandler := handlers.NewOIDCHandler(db, tokens, deps.EmailPolicy, deps.OIDCProviders)
+	oauthHandler := handlers.NewOAuthHandler(db, tokens)
+	oauthClientHandler := handlers.NewOAuthClientHandler(db)
+	deviceHandler := handlers.NewDeviceHandler(db, tokens)
+	moderationHandler := handlers.NewModerationHandler(db, tokens)
+	auditHandler := handlers.NewAuditHandler(db)
+	accountHandler := handlers.NewAccountHandler(db, tokens, deps.Mailer, deps.PasswordHasher, deps.LoginGuard, deps.AccountDeletionGrace)
+
+	// Limites por rota
+	policies := deps.RateLimitPolicies
+	if policies == nil {
+		policies = DefaultRateLimitPolicies()
+	}
+	rateLimit := middleware.RateLimitRoutes(deps.RateLimiter, policies)
 
 	// Middleware global
 	r.Use(gin.Recovery())
+	r.Use(logger.RequestID())
 	r.Use(logger.LogRequest())
 
 	// Documentação Swagger
@@ -53,24 +118,28 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 	// Health checks
 	setupHealthRoutes(r, healthHandler)
 
+	// Chaves públicas para verificação dos tokens por serviços externos
+	setupWellKnownRoutes(r, jwksHandler)
+
 	// Rotas públicas
 	public := r.Group("/api/v1")
+	public.Use(rateLimit)
 	{
-		setupPublicRoutes(public, userHandler, authHandler)
+		setupPublicRoutes(public, userHandler, authHandler, emailHandler, passwordHandler, oidcHandler, oauthHandler, deviceHandler, accountHandler)
 	}
 
 	// Rotas protegidas por JWT
 	protected := r.Group("/api/v1")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(tokens, db), rateLimit)
 	{
-		setupProtectedRoutes(protected, userHandler, apiKeyHandler)
+		setupProtectedRoutes(protected, db, userHandler, authHandler, sessionHandler, mfaHandler, oidcHandler, oauthHandler, oauthClientHandler, deviceHandler, apiKeyHandler, moderationHandler, auditHandler, accountHandler, passwordHandler, middleware.RequireVerifiedEmail(db, deps.EmailPolicy))
 	}
 
 	// Rotas protegidas por API Key
 	apiProtected := r.Group("/api/v1")
-	apiProtected.Use(middleware.APIKeyAuth(db))
+	apiProtected.Use(middleware.APIKeyAuth(db, deps.RateLimiter, deps.Usage))
 	{
-		setupAPIProtectedRoutes(apiProtected)
+		setupAPIProtectedRoutes(apiProtected, db, userHandler)
 	}
 
 	return r
@@ -105,8 +174,19 @@ func setupHealthRoutes(router *gin.Engine, healthHandler *handlers.HealthHandler
 	router.GET("/live", healthHandler.LivenessCheck)
 }
 
+// setupWellKnownRoutes configura as rotas de descoberta em /.well-known
+func setupWellKnownRoutes(router *gin.Engine, jwksHandler *handlers.JWKSHandler) {
+	// @Summary Chaves públicas de verificação
+	// @Description Retorna o JWK Set com as chaves aceitas na verificação dos access tokens
+	// @Tags auth
+	// @Produce json
+	// @Success 200 {object} auth.JWKS
+	// @Router /.well-known/jwks.json [get]
+	router.GET("/.well-known/jwks.json", jwksHandler.JWKS)
+}
+
 // setupPublicRoutes configura as rotas públicas
-func setupPublicRoutes(router *gin.RouterGroup, userHandler *handlers.UserHandler, authHandler *handlers.AuthHandler) {
+func setupPublicRoutes(router *gin.RouterGroup, userHandler *handlers.UserHandler, authHandler *handlers.AuthHandler, emailHandler *handlers.EmailVerificationHandler, passwordHandler *handlers.PasswordHandler, oidcHandler *handlers.OIDCHandler, oauthHandler *handlers.OAuthHandler, deviceHandler *handlers.DeviceHandler, accountHandler *handlers.AccountHandler) {
 	// Middleware para rotas de autenticação
 	router.Use(middleware.MethodNotAllowed())
 	router.Use(middleware.RequestValidation())
@@ -135,6 +215,18 @@ func setupPublicRoutes(router *gin.RouterGroup, userHandler *handlers.UserHandle
 		// @Router /login [post]
 		router.POST("/login", authHandler.Login)
 
+		// @Summary Conclui login com 2FA
+		// @Description Valida o desafio de /login com um código TOTP ou de recuperação e retorna os tokens
+		// @Tags auth
+		// @Accept json
+		// @Produce json
+		// @Param challenge body map[string]string true "Token de desafio e código"
+		// @Success 200 {object} handlers.LoginResponse
+		// @Failure 400 {object} map[string]string
+		// @Failure 401 {object} map[string]string
+		// @Router /login/2fa [post]
+		router.POST("/login/2fa", authHandler.LoginMFA)
+
 		// @Summary Atualiza access token
 		// @Description Atualiza o access token usando o refresh token
 		// @Tags auth
@@ -158,12 +250,34 @@ func setupPublicRoutes(router *gin.RouterGroup, userHandler *handlers.UserHandle
 		// @Failure 404 {object} map[string]string
 		// @Router /logout [post]
 		router.POST("/logout", authHandler.Logout)
+
+		// Verificação de email
+		router.POST("/verify-email", emailHandler.VerifyEmail)
+		router.POST("/verify-email/resend", emailHandler.ResendVerification)
+
+		// Recuperação de senha
+		router.POST("/password/forgot", passwordHandler.ForgotPassword)
+		router.POST("/password/reset", passwordHandler.ResetPassword)
+
+		// Cancelamento da exclusão da conta
+		router.POST("/account/restore", accountHandler.RestoreAccount)
+
+		// Login com provedores OpenID Connect
+		router.GET("/oidc/:provider/login", oidcHandler.Login)
+		router.GET("/oidc/:provider/callback", oidcHandler.Callback)
+
+		// Endpoint de token OAuth2 (autenticação pelo próprio cliente)
+		router.POST("/oauth/token", oauthHandler.Token)
+
+		// Login de dispositivos sem teclado (consoles e TVs)
+		router.POST("/device/code", deviceHandler.RequestCode)
+		router.POST("/device/token", deviceHandler.Token)
 	}
 }
 
 // setupProtectedRoutes configura as rotas protegidas por JWT
-func setupProtectedRoutes(router *gin.RouterGroup, userHandler *handlers.UserHandler, apiKeyHandler *handlers.APIKeyHandler) {
-	// Rotas de perfil
+func setupProtectedRoutes(router *gin.RouterGroup, db *gorm.DB, userHandler *handlers.UserHandler, authHandler *handlers.AuthHandler, sessionHandler *handlers.SessionHandler, mfaHandler *handlers.MFAHandler, oidcHandler *handlers.OIDCHandler, oauthHandler *handlers.OAuthHandler, oauthClientHandler *handlers.OAuthClientHandler, deviceHandler *handlers.DeviceHandler, apiKeyHandler *handlers.APIKeyHandler, moderationHandler *handlers.ModerationHandler, auditHandler *handlers.AuditHandler, accountHandler *handlers.AccountHandler, passwordHandler *handlers.PasswordHandler, requireVerifiedEmail gin.HandlerFunc) {
+	// Rotas de perfil (aceitam tokens de terceiros com o escopo correspondente)
 	// @Summary Obtém perfil do usuário
 	// @Description Retorna os dados do perfil do usuário autenticado
 	// @Tags profile
@@ -173,7 +287,7 @@ func setupProtectedRoutes(router *gin.RouterGroup, userHandler *handlers.UserHan
 	// @Failure 401 {object} map[string]string
 	// @Failure 404 {object} map[string]string
 	// @Router /profile [get]
-	router.GET("/profile", userHandler.GetProfile)
+	router.GET("/profile", middleware.RequireScope(auth.ScopeProfileRead), userHandler.GetProfile)
 
 	// @Summary Atualiza perfil do usuário
 	// @Description Atualiza os dados do perfil do usuário autenticado
@@ -187,69 +301,151 @@ func setupProtectedRoutes(router *gin.RouterGroup, userHandler *handlers.UserHan
 	// @Failure 401 {object} map[string]string
 	// @Failure 404 {object} map[string]string
 	// @Router /profile [put]
-	router.PUT("/profile", userHandler.UpdateProfile)
+	router.PUT("/profile", middleware.RequireScope(auth.ScopeProfileWrite), userHandler.UpdateProfile)
 
-	// Rotas de usuário
-	router.GET("/users", userHandler.ListUsers)
-	router.GET("/users/:id", userHandler.GetUser)
-	router.PUT("/users/:id", userHandler.UpdateUser)
+	// Rotas de usuário: a listagem é restrita a administradores e cada usuário
+	// só acessa o próprio cadastro, exceto administradores
+	router.GET("/users", middleware.RequireScope(auth.ScopeUsersRead), middleware.Authorize(db, middleware.HasPermission(auth.PermissionUsersList)), userHandler.ListUsers)
+	router.GET("/users/:id", middleware.RequireScope(auth.ScopeUsersRead), middleware.Authorize(db, middleware.SelfOrPermission("id", auth.PermissionUsersRead)), userHandler.GetUser)
 
-	// Rotas de API Key
-	apiKeys := router.Group("/api-keys")
+	// Rotas restritas ao próprio usuário: tokens emitidos para aplicações de
+	// terceiros não podem gerenciar a conta nem conceder novos acessos
+	firstParty := router.Group("", middleware.RequireFirstParty())
 	{
-		// @Summary Cria uma nova chave de API
-		// @Description Cria uma nova chave de API para o usuário autenticado
-		// @Tags api-keys
+		// @Summary Encerra todas as sessões
+		// @Description Revoga todos os refresh tokens e access tokens do usuário autenticado
+		// @Tags auth
 		// @Security Bearer
-		// @Accept json
-		// @Produce json
-		// @Param apiKey body models.APIKey true "Dados da chave de API"
-		// @Success 201 {object} models.APIKey
-		// @Failure 400 {object} map[string]string
+		// @Success 204 "No Content"
 		// @Failure 401 {object} map[string]string
-		// @Router /api-keys [post]
-		apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+		// @Router /logout-all [post]
+		firstParty.POST("/logout-all", authHandler.LogoutAll)
 
-		// @Summary Lista chaves de API
-		// @Description Retorna todas as chaves de API do usuário autenticado
-		// @Tags api-keys
-		// @Security Bearer
-		// @Produce json
-		// @Success 200 {array} models.APIKey
-		// @Failure 401 {object} map[string]string
-		// @Router /api-keys [get]
-		apiKeys.GET("", apiKeyHandler.ListAPIKeys)
+		// Alteração de senha (encerra as outras sessões)
+		firstParty.PUT("/profile/password", passwordHandler.ChangePassword)
 
-		// @Summary Remove chave de API
-		// @Description Remove uma chave de API específica
-		// @Tags api-keys
-		// @Security Bearer
-		// @Param id path int true "ID da chave de API"
-		// @Success 204 "No Content"
-		// @Failure 401 {object} map[string]string
-		// @Failure 404 {object} map[string]string
-		// @Router /api-keys/{id} [delete]
-		apiKeys.DELETE("/:id", apiKeyHandler.DeleteAPIKey)
+		// Exclusão da conta e exportação dos dados pessoais
+		firstParty.DELETE("/profile", accountHandler.DeleteAccount)
+		firstParty.GET("/profile/export", accountHandler.ExportData)
 
-		// @Summary Atualiza chave de API
-		// @Description Atualiza os dados de uma chave de API específica
-		// @Tags api-keys
-		// @Security Bearer
-		// @Accept json
-		// @Produce json
-		// @Param id path int true "ID da chave de API"
-		// @Param apiKey body models.APIKey true "Dados da chave de API"
-		// @Success 200 {object} models.APIKey
-		// @Failure 400 {object} map[string]string
-		// @Failure 401 {object} map[string]string
-		// @Failure 404 {object} map[string]string
-		// @Router /api-keys/{id} [put]
-		apiKeys.PUT("/:id", apiKeyHandler.UpdateAPIKey)
+		// Rotas de sessão
+		firstParty.GET("/sessions", sessionHandler.ListSessions)
+		firstParty.DELETE("/sessions/:id", sessionHandler.DeleteSession)
+
+		// Rotas de verificação em duas etapas
+		twoFactor := firstParty.Group("/2fa")
+		{
+			twoFactor.POST("/setup", mfaHandler.Setup)
+			twoFactor.POST("/enable", mfaHandler.Enable)
+			twoFactor.POST("/disable", mfaHandler.Disable)
+		}
+
+		// Rotas de identidades externas
+		firstParty.POST("/oidc/:provider/link", oidcHandler.Link)
+		firstParty.GET("/identities", oidcHandler.ListIdentities)
+		firstParty.DELETE("/identities/:id", oidcHandler.UnlinkIdentity)
+
+		// Aprovação de dispositivos
+		firstParty.GET("/device", deviceHandler.GetAuthorization)
+		firstParty.POST("/device/approve", deviceHandler.Approve)
+
+		// Alteração de usuários e de papéis
+		firstParty.PUT("/users/:id", middleware.Authorize(db, middleware.SelfOrPermission("id", auth.PermissionUsersWrite)), userHandler.UpdateUser)
+		firstParty.PUT("/users/:id/role", middleware.Authorize(db, middleware.HasPermission(auth.PermissionRolesManage)), userHandler.UpdateRole)
+
+		// Rotas de API Key (exigem email verificado conforme EMAIL_VERIFICATION_POLICY)
+		apiKeys := firstParty.Group("/api-keys")
+		apiKeys.Use(requireVerifiedEmail)
+		{
+			// @Summary Cria uma nova chave de API
+			// @Description Cria uma nova chave de API para o usuário autenticado
+			// @Tags api-keys
+			// @Security Bearer
+			// @Accept json
+			// @Produce json
+			// @Param apiKey body models.APIKey true "Dados da chave de API"
+			// @Success 201 {object} models.APIKey
+			// @Failure 400 {object} map[string]string
+			// @Failure 401 {object} map[string]string
+			// @Router /api-keys [post]
+			apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+
+			// @Summary Lista chaves de API
+			// @Description Retorna todas as chaves de API do usuário autenticado
+			// @Tags api-keys
+			// @Security Bearer
+			// @Produce json
+			// @Success 200 {array} models.APIKey
+			// @Failure 401 {object} map[string]string
+			// @Router /api-keys [get]
+			apiKeys.GET("", apiKeyHandler.ListAPIKeys)
+
+			// @Summary Remove chave de API
+			// @Description Remove uma chave de API específica
+			// @Tags api-keys
+			// @Security Bearer
+			// @Param id path int true "ID da chave de API"
+			// @Success 204 "No Content"
+			// @Failure 401 {object} map[string]string
+			// @Failure 404 {object} map[string]string
+			// @Router /api-keys/{id} [delete]
+			apiKeys.DELETE("/:id", apiKeyHandler.DeleteAPIKey)
+
+			// @Summary Atualiza chave de API
+			// @Description Atualiza os dados de uma chave de API específica
+			// @Tags api-keys
+			// @Security Bearer
+			// @Accept json
+			// @Produce json
+			// @Param id path int true "ID da chave de API"
+			// @Param apiKey body models.APIKey true "Dados da chave de API"
+			// @Success 200 {object} models.APIKey
+			// @Failure 400 {object} map[string]string
+			// @Failure 401 {object} map[string]string
+			// @Failure 404 {object} map[string]string
+			// @Router /api-keys/{id} [put]
+			apiKeys.PUT("/:id", apiKeyHandler.UpdateAPIKey)
+
+			// Rotação com período de transição
+			apiKeys.POST("/:id/rotate", apiKeyHandler.RotateAPIKey)
+
+			// Série diária de requisições e erros
+			apiKeys.GET("/:id/usage", apiKeyHandler.GetAPIKeyUsage)
+		}
+
+		// Rotas administrativas; cada grupo exige a sua permissão
+		admin := firstParty.Group("/admin")
+		{
+			// Moderação de jogadores
+			moderation := admin.Group("/users", middleware.Authorize(db, middleware.HasPermission(auth.PermissionUsersModerate)))
+			moderation.POST("/:id/suspend", moderationHandler.Suspend)
+			moderation.POST("/:id/ban", moderationHandler.Ban)
+			moderation.POST("/:id/reinstate", moderationHandler.Reinstate)
+
+			// Consulta e exportação do log de auditoria
+			auditEvents := admin.Group("/audit-events", middleware.Authorize(db, middleware.HasPermission(auth.PermissionAuditRead)))
+			auditEvents.GET("", auditHandler.ListEvents)
+			auditEvents.GET("/export", auditHandler.ExportEvents)
+		}
+
+		// Rotas do servidor de autorização OAuth2
+		oauth := firstParty.Group("/oauth")
+		{
+			oauth.GET("/authorize", oauthHandler.Authorize)
+			oauth.POST("/authorize", oauthHandler.Decide)
+			oauth.POST("/clients", oauthClientHandler.CreateClient)
+			oauth.GET("/clients", oauthClientHandler.ListClients)
+			oauth.DELETE("/clients/:id", oauthClientHandler.DeleteClient)
+		}
 	}
 }
 
 // setupAPIProtectedRoutes configura as rotas protegidas por API Key
-func setupAPIProtectedRoutes(router *gin.RouterGroup) {
-	// Aqui você pode adicionar rotas que podem ser acessadas via API Key
-	// Por exemplo, endpoints públicos que precisam de rate limiting
+func setupAPIProtectedRoutes(router *gin.RouterGroup, db *gorm.DB, userHandler *handlers.UserHandler) {
+	// Integrações autenticadas por API key; cada rota exige o escopo correspondente
+	integrations := router.Group("/integrations")
+	{
+		integrations.GET("/profile", middleware.RequireScope(auth.ScopeProfileRead), userHandler.GetProfile)
+		integrations.GET("/users/:id", middleware.RequireScope(auth.ScopeUsersRead), middleware.Authorize(db, middleware.SelfOrPermission("id", auth.PermissionUsersRead)), userHandler.GetUser)
+	}
 }
@@ -0,0 +1,231 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"life/account"
+)
+
+// TestAccountConfig testa a leitura do período de cancelamento e do intervalo de remoção
+func TestAccountConfig(t *testing.T) {
+	t.Setenv("ACCOUNT_DELETION_GRACE", "")
+	if grace, err := account.DeletionGraceFromEnv(); err != nil || grace != 30*24*time.Hour {
+		t.Errorf("Padrão esperado 720h, recebido %v (%v)", grace, err)
+	}
+
+	t.Setenv("ACCOUNT_DELETION_GRACE", "0s")
+	if grace, err := account.DeletionGraceFromEnv(); err != nil || grace != 0 {
+		t.Errorf("Período esperado 0s, recebido %v (%v)", grace, err)
+	}
+
+	for _, value := range []string{"-1h", "um mês"} {
+		t.Setenv("ACCOUNT_DELETION_GRACE", value)
+		if _, err := account.DeletionGraceFromEnv(); err == nil {
+			t.Errorf("Valor %q deveria ser recusado", value)
+		}
+	}
+
+	t.Setenv("ACCOUNT_PURGE_INTERVAL", "")
+	if interval, err := account.PurgeIntervalFromEnv(); err != nil || interval != time.Hour {
+		t.Errorf("Padrão esperado 1h, recebido %v (%v)", interval, err)
+	}
+
+	for _, value := range []string{"0s", "-1m", "sempre"} {
+		t.Setenv("ACCOUNT_PURGE_INTERVAL", value)
+		if _, err := account.PurgeIntervalFromEnv(); err == nil {
+			t.Errorf("Valor %q deveria ser recusado", value)
+		}
+	}
+}
+
+// TestAccountDeletion testa a exportação dos dados, a exclusão da conta e o cancelamento pelo link enviado por email
+func TestAccountDeletion(t *testing.T) {
+	setupTest(t)
+	// 1. Registro, login e uma API key para constar na exportação
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	loginResp := testLogin(t, user.Username, testPassword)
+	if loginResp == nil {
+		t.Fatal("Falha no login")
+	}
+
+	apiKey := testCreateAPIKey(t, loginResp.AccessToken)
+	if apiKey == nil {
+		t.Fatal("Falha ao criar API key")
+	}
+
+	// 2. A exportação traz um arquivo por tipo de dado, sem hashes de senha
+	files := testExportAccount(t, loginResp.AccessToken)
+	for _, name := range []string{"profile.json", "sessions.json", "api_keys.json", "api_key_usage.json", "identities.json", "oauth_clients.json", "oauth_consents.json", "audit_events.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Arquivo %s ausente na exportação", name)
+		}
+	}
+
+	var profile map[string]interface{}
+	if err := json.Unmarshal(files["profile.json"], &profile); err != nil {
+		t.Fatalf("Erro ao decodificar perfil: %v", err)
+	}
+	if profile["username"] != user.Username || profile["password"] != nil {
+		t.Errorf("Perfil exportado inesperado: %s", files["profile.json"])
+	}
+
+	var apiKeys []APIKey
+	if err := json.Unmarshal(files["api_keys.json"], &apiKeys); err != nil || len(apiKeys) != 1 || apiKeys[0].Key != "" {
+		t.Errorf("API keys exportadas inesperadas: %s", files["api_keys.json"])
+	}
+
+	// 3. A exclusão exige a senha atual; um link de redefinição de senha é solicitado antes
+	if status, _ := testJSONRequest(t, "POST", "/password/forgot", "", map[string]string{"email": user.Email}); status != http.StatusAccepted {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusAccepted, status)
+	}
+	resetToken := testMailToken(t, user.Email, "reset-password")
+	if status, _ := testJSONRequest(t, "DELETE", "/profile", loginResp.AccessToken, map[string]string{"password": "Senha-Errada-2024"}); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+
+	status, body := testJSONRequest(t, "DELETE", "/profile", loginResp.AccessToken, map[string]string{"password": testPassword})
+	if status != http.StatusAccepted {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusAccepted, status)
+	}
+
+	var deletion struct {
+		PurgeAfter time.Time `json:"purge_after"`
+	}
+	if err := json.Unmarshal(body, &deletion); err != nil || deletion.PurgeAfter.Before(time.Now()) {
+		t.Errorf("Data de remoção inesperada: %s", body)
+	}
+
+	// 4. A conta fica inacessível: tokens, refresh tokens, API keys, login e redefinição de senha são recusados
+	if status := testAuthorizedStatus(t, "GET", "/profile", loginResp.AccessToken); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+	if status := testRefreshTokenStatus(t, loginResp.RefreshToken); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+	if status, _ := testAPIKeyRequest(t, "/integrations/profile", apiKey.Key); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+	if status, _ := testLoginAttempt(t, user.Username, testPassword); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+	if status, _ := testJSONRequest(t, "POST", "/password/reset", "", map[string]string{"token": resetToken, "password": "Nova-Senha-Forte-2025"}); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+
+	// 5. O nome de usuário e o email continuam reservados durante o período de cancelamento
+	registerData := map[string]string{
+		"username":     user.Username,
+		"password":     testPassword,
+		"display_name": "Usuário Teste",
+		"email":        user.Email,
+	}
+	if status, _ := testJSONRequest(t, "POST", "/register", "", registerData); status != http.StatusConflict {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusConflict, status)
+	}
+
+	// 6. O link enviado por email restaura a conta uma única vez
+	token := testMailToken(t, user.Email, "restore-account")
+	if token == "" {
+		t.Fatal("Email de restauração não encontrado")
+	}
+	if status, _ := testJSONRequest(t, "POST", "/account/restore", "", map[string]string{"token": token}); status != http.StatusNoContent {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusNoContent, status)
+	}
+	if status, _ := testJSONRequest(t, "POST", "/account/restore", "", map[string]string{"token": token}); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+
+	// 7. Após a restauração o usuário entra novamente
+	if testLogin(t, user.Username, testPassword) == nil {
+		t.Error("Login após a restauração falhou")
+	}
+}
+
+// TestAccountDeletionLockout testa que a senha exigida na exclusão não pode
+// ser adivinhada com um token roubado: as falhas bloqueiam a conta como no login
+func TestAccountDeletionLockout(t *testing.T) {
+	setupTest(t)
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	loginResp := testLogin(t, user.Username, testPassword)
+	if loginResp == nil {
+		t.Fatal("Falha no login")
+	}
+
+	// 1. As primeiras senhas erradas retornam 401
+	for i := 0; i < 5; i++ {
+		if status, _ := testJSONRequest(t, "DELETE", "/profile", loginResp.AccessToken, map[string]string{"password": "Senha-Errada-2024"}); status != http.StatusUnauthorized {
+			t.Fatalf("Tentativa %d: status code esperado %d, recebido %d", i+1, http.StatusUnauthorized, status)
+		}
+	}
+
+	// 2. Com a conta bloqueada, até a senha correta é recusada e a conta continua ativa
+	if status, _ := testJSONRequest(t, "DELETE", "/profile", loginResp.AccessToken, map[string]string{"password": testPassword}); status != http.StatusTooManyRequests {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusTooManyRequests, status)
+	}
+	if status := testAuthorizedStatus(t, "GET", "/profile", loginResp.AccessToken); status != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+
+	// 3. O login também fica bloqueado
+	if status, _ := testLoginAttempt(t, user.Username, testPassword); status != http.StatusTooManyRequests {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusTooManyRequests, status)
+	}
+}
+
+// testExportAccount baixa a exportação dos dados e retorna o conteúdo de cada arquivo do zip
+func testExportAccount(t *testing.T, accessToken string) map[string][]byte {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/profile/export", baseURL), nil)
+	if err != nil {
+		t.Errorf("Erro ao criar requisição: %v", err)
+		return nil
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Errorf("Erro na requisição: %v", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/zip" {
+		t.Errorf("Resposta inesperada: %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+		return nil
+	}
+	t.Logf("Content-Disposition: %s", resp.Header.Get("Content-Disposition"))
+
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Errorf("Erro ao abrir zip: %v", err)
+		return nil
+	}
+
+	files := make(map[string][]byte)
+	for _, file := range archive.File {
+		f, err := file.Open()
+		if err != nil {
+			t.Errorf("Erro ao abrir %s: %v", file.Name, err)
+			continue
+		}
+		files[file.Name], _ = io.ReadAll(f)
+		f.Close()
+	}
+
+	return files
+}
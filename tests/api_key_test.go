@@ -0,0 +1,427 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"life/auth"
+	"life/usage"
+)
+
+// APIKey representa uma chave de API nos testes
+type APIKey struct {
+	ID     uint     `json:"id"`
+	Name   string   `json:"name"`
+	Key    string   `json:"key"`
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes"`
+
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// TestAPIKeyFlow testa o fluxo de criação e listagem de chaves de API
+func TestAPIKeyFlow(t *testing.T) {
+	setupTest(t)
+	// 1. Registro e Login
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	loginData := testLogin(t, user.Username, testPassword)
+	if loginData == nil {
+		t.Fatal("Falha no login")
+	}
+
+	// 2. Criação retorna a chave completa uma única vez
+	created := testCreateAPIKey(t, loginData.AccessToken)
+	if created == nil {
+		t.Fatal("Falha ao criar chave de API")
+	}
+	if created.Key == "" || !strings.HasPrefix(created.Key, created.Prefix) {
+		t.Fatalf("A criação deveria retornar a chave completa, recebido key=%q prefix=%q", created.Key, created.Prefix)
+	}
+
+	// 3. Listagem retorna apenas o prefixo
+	keys := testListAPIKeys(t, loginData.AccessToken)
+	if keys == nil {
+		t.Fatal("Falha ao listar chaves de API")
+	}
+	for _, key := range keys {
+		if key.Key != "" {
+			t.Errorf("A listagem não deveria expor a chave completa (id %d)", key.ID)
+		}
+		if key.ID == created.ID && key.Prefix != created.Prefix {
+			t.Errorf("Prefixo esperado %q, recebido %q", created.Prefix, key.Prefix)
+		}
+	}
+}
+
+// TestAPIKeyScopes testa a restrição das API keys aos escopos concedidos
+func TestAPIKeyScopes(t *testing.T) {
+	setupTest(t)
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	loginData := testLogin(t, user.Username, testPassword)
+	if loginData == nil {
+		t.Fatal("Falha no login")
+	}
+
+	// 1. Escopos ausentes ou desconhecidos são recusados
+	for _, scopes := range [][]string{nil, {"profile:read", "admin"}} {
+		data := map[string]interface{}{"name": "Chave inválida", "expires_at": "2099-12-31T23:59:59Z", "scopes": scopes}
+		if status, _ := testJSONRequest(t, "POST", "/api-keys", loginData.AccessToken, data); status != http.StatusBadRequest {
+			t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+		}
+	}
+
+	// 2. Chave com profile:read acessa o perfil, mas não outros usuários
+	created := testCreateAPIKey(t, loginData.AccessToken)
+	if created == nil {
+		t.Fatal("Falha ao criar chave de API")
+	}
+	if status, _ := testAPIKeyRequest(t, "/integrations/profile", created.Key); status != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	userPath := fmt.Sprintf("/integrations/users/%d", user.ID)
+	status, body := testAPIKeyRequest(t, userPath, created.Key)
+	if status != http.StatusForbidden || !strings.Contains(string(body), "users:read") {
+		t.Errorf("Resposta inesperada: %d %s", status, body)
+	}
+
+	// 3. Após a troca dos escopos, o acesso acompanha a nova lista
+	update := map[string]interface{}{
+		"name":       "Chave de Teste",
+		"expires_at": "2099-12-31T23:59:59Z",
+		"rate_limit": 60,
+		"is_active":  true,
+		"scopes":     []string{"users:read"},
+	}
+	if status, _ := testJSONRequest(t, "PUT", fmt.Sprintf("/api-keys/%d", created.ID), loginData.AccessToken, update); status != http.StatusOK {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	if status, _ := testAPIKeyRequest(t, userPath, created.Key); status != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	if status, _ := testAPIKeyRequest(t, "/integrations/profile", created.Key); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+}
+
+// TestAPIKeyRotationGraceConfig testa a leitura do período de transição da rotação
+func TestAPIKeyRotationGraceConfig(t *testing.T) {
+	t.Setenv("API_KEY_ROTATION_GRACE", "")
+	if grace, err := auth.APIKeyRotationGraceFromEnv(); err != nil || grace != 24*time.Hour {
+		t.Errorf("Padrão esperado 24h, recebido %v (%v)", grace, err)
+	}
+
+	t.Setenv("API_KEY_ROTATION_GRACE", "0s")
+	if grace, err := auth.APIKeyRotationGraceFromEnv(); err != nil || grace != 0 {
+		t.Errorf("Período esperado 0s, recebido %v (%v)", grace, err)
+	}
+
+	for _, value := range []string{"-1h", "amanhã"} {
+		t.Setenv("API_KEY_ROTATION_GRACE", value)
+		if _, err := auth.APIKeyRotationGraceFromEnv(); err == nil {
+			t.Errorf("Valor %q deveria ser recusado", value)
+		}
+	}
+}
+
+// TestAPIKeyRotation testa a rotação com a chave anterior válida durante a transição
+func TestAPIKeyRotation(t *testing.T) {
+	setupTest(t)
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	loginData := testLogin(t, user.Username, testPassword)
+	if loginData == nil {
+		t.Fatal("Falha no login")
+	}
+
+	original := testCreateAPIKey(t, loginData.AccessToken)
+	if original == nil {
+		t.Fatal("Falha ao criar chave de API")
+	}
+
+	// 1. A rotação retorna uma nova chave e mantém a anterior aceita
+	rotated := testRotateAPIKey(t, loginData.AccessToken, original.ID)
+	if rotated.Key == "" || rotated.Key == original.Key || rotated.ID != original.ID {
+		t.Fatalf("Rotação inesperada: %+v", rotated)
+	}
+	for _, key := range []string{original.Key, rotated.Key} {
+		if status, _ := testAPIKeyRequest(t, "/integrations/profile", key); status != http.StatusOK {
+			t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+		}
+	}
+
+	// 2. Uma nova rotação encerra a transição da chave original
+	latest := testRotateAPIKey(t, loginData.AccessToken, original.ID)
+	if status, _ := testAPIKeyRequest(t, "/integrations/profile", original.Key); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+	for _, key := range []string{rotated.Key, latest.Key} {
+		if status, _ := testAPIKeyRequest(t, "/integrations/profile", key); status != http.StatusOK {
+			t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+		}
+	}
+
+	// 3. Chaves de outros usuários não podem ser rotacionadas
+	other := testRegister(t)
+	if other == nil {
+		t.Fatal("Falha no registro")
+	}
+	otherLogin := testLogin(t, other.Username, testPassword)
+	if otherLogin == nil {
+		t.Fatal("Falha no login")
+	}
+	if status, _ := testJSONRequest(t, "POST", fmt.Sprintf("/api-keys/%d/rotate", original.ID), otherLogin.AccessToken, nil); status != http.StatusNotFound {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusNotFound, status)
+	}
+}
+
+// TestUsageFlushIntervalConfig testa a leitura do intervalo de gravação do uso
+func TestUsageFlushIntervalConfig(t *testing.T) {
+	t.Setenv("USAGE_FLUSH_INTERVAL", "")
+	if interval, err := usage.FlushIntervalFromEnv(); err != nil || interval != 10*time.Second {
+		t.Errorf("Padrão esperado 10s, recebido %v (%v)", interval, err)
+	}
+
+	for _, value := range []string{"0s", "-1s", "sempre"} {
+		t.Setenv("USAGE_FLUSH_INTERVAL", value)
+		if _, err := usage.FlushIntervalFromEnv(); err == nil {
+			t.Errorf("Valor %q deveria ser recusado", value)
+		}
+	}
+}
+
+// TestAPIKeyUsage testa a série de uso e a taxa de erros de uma chave
+func TestAPIKeyUsage(t *testing.T) {
+	setupTest(t)
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	loginData := testLogin(t, user.Username, testPassword)
+	if loginData == nil {
+		t.Fatal("Falha no login")
+	}
+
+	key := testCreateAPIKey(t, loginData.AccessToken)
+	if key == nil {
+		t.Fatal("Falha ao criar chave de API")
+	}
+
+	// 1. Duas requisições bem-sucedidas e uma recusada por falta de escopo
+	for i := 0; i < 2; i++ {
+		if status, _ := testAPIKeyRequest(t, "/integrations/profile", key.Key); status != http.StatusOK {
+			t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+		}
+	}
+	if status, _ := testAPIKeyRequest(t, fmt.Sprintf("/integrations/users/%d", user.ID), key.Key); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+
+	// 2. O uso é gravado em lote (USAGE_FLUSH_INTERVAL=1s nos testes)
+	time.Sleep(2 * time.Second)
+
+	today := time.Now().UTC().Format(time.DateOnly)
+	status, body := testJSONRequest(t, "GET", fmt.Sprintf("/api-keys/%d/usage?from=%s&to=%s", key.ID, today, today), loginData.AccessToken, nil)
+	if status != http.StatusOK {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+
+	var usageResp struct {
+		Requests  int64   `json:"requests"`
+		Errors    int64   `json:"errors"`
+		ErrorRate float64 `json:"error_rate"`
+		Days      []struct {
+			Date     string           `json:"date"`
+			Requests int64            `json:"requests"`
+			Statuses map[string]int64 `json:"statuses"`
+		} `json:"days"`
+		Routes []struct {
+			Route    string `json:"route"`
+			Requests int64  `json:"requests"`
+			Errors   int64  `json:"errors"`
+		} `json:"routes"`
+	}
+	if err := json.Unmarshal(body, &usageResp); err != nil {
+		t.Fatalf("Erro ao decodificar resposta: %v", err)
+	}
+	if usageResp.Requests != 3 || usageResp.Errors != 1 {
+		t.Errorf("Esperado 3 requisições e 1 erro, recebido %+v", usageResp)
+	}
+	if len(usageResp.Days) != 1 || usageResp.Days[0].Date != today || usageResp.Days[0].Statuses["200"] != 2 || usageResp.Days[0].Statuses["403"] != 1 {
+		t.Errorf("Série diária inesperada: %+v", usageResp.Days)
+	}
+	if len(usageResp.Routes) != 2 || usageResp.Routes[0].Route != "/api/v1/integrations/profile" || usageResp.Routes[0].Requests != 2 {
+		t.Errorf("Rotas inesperadas: %+v", usageResp.Routes)
+	}
+
+	// 3. O último uso da chave é atualizado junto com os contadores
+	for _, listed := range testListAPIKeys(t, loginData.AccessToken) {
+		if listed.ID == key.ID && listed.LastUsedAt == nil {
+			t.Error("last_used_at deveria estar preenchido")
+		}
+	}
+
+	// 4. Períodos inválidos e chaves de outros usuários são recusados
+	if status, _ := testJSONRequest(t, "GET", fmt.Sprintf("/api-keys/%d/usage?from=2024-01-01&to=2023-01-01", key.ID), loginData.AccessToken, nil); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+	other := testRegister(t)
+	if other == nil {
+		t.Fatal("Falha no registro")
+	}
+	otherLogin := testLogin(t, other.Username, testPassword)
+	if otherLogin == nil {
+		t.Fatal("Falha no login")
+	}
+	if status, _ := testJSONRequest(t, "GET", fmt.Sprintf("/api-keys/%d/usage", key.ID), otherLogin.AccessToken, nil); status != http.StatusNotFound {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusNotFound, status)
+	}
+}
+
+// testRotateAPIKey rotaciona uma chave de API
+func testRotateAPIKey(t *testing.T, accessToken string, id uint) *APIKey {
+	status, body := testJSONRequest(t, "POST", fmt.Sprintf("/api-keys/%d/rotate", id), accessToken, nil)
+	if status != http.StatusOK {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+
+	var apiKey APIKey
+	if err := json.Unmarshal(body, &apiKey); err != nil {
+		t.Fatalf("Erro ao decodificar resposta: %v", err)
+	}
+	return &apiKey
+}
+
+// testAPIKeyRequest faz uma requisição GET autenticada por API key
+func testAPIKeyRequest(t *testing.T, path, key string) (int, []byte) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s%s", baseURL, path), nil)
+	if err != nil {
+		t.Errorf("Erro ao criar requisição: %v", err)
+		return 0, nil
+	}
+	req.Header.Set("X-API-Key", key)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Errorf("Erro na requisição: %v", err)
+		return 0, nil
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	t.Logf("Status code: %d", resp.StatusCode)
+	t.Logf("Resposta: %s", string(body))
+
+	return resp.StatusCode, body
+}
+
+// testCreateAPIKey testa a criação de uma chave de API
+func testCreateAPIKey(t *testing.T, accessToken string) *APIKey {
+	url := fmt.Sprintf("%s/api-keys", baseURL)
+
+	data := map[string]interface{}{
+		"name":       "Chave de Teste",
+		"expires_at": "2099-12-31T23:59:59Z",
+		"scopes":     []string{"profile:read"},
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		t.Errorf("Erro ao criar JSON: %v", err)
+		return nil
+	}
+
+	// Log do corpo da requisição
+	t.Logf("Corpo da requisição: %s", string(jsonData))
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		t.Errorf("Erro ao criar requisição: %v", err)
+		return nil
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Errorf("Erro na requisição: %v", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	// Log da resposta
+	body, _ := io.ReadAll(resp.Body)
+	t.Logf("Status code: %d", resp.StatusCode)
+	t.Logf("Resposta: %s", string(body))
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Status code esperado %d, recebido %d. Resposta: %s", http.StatusCreated, resp.StatusCode, string(body))
+		return nil
+	}
+
+	var apiKey APIKey
+	if err := json.NewDecoder(bytes.NewBuffer(body)).Decode(&apiKey); err != nil {
+		t.Errorf("Erro ao decodificar resposta: %v", err)
+		return nil
+	}
+
+	return &apiKey
+}
+
+// testListAPIKeys testa a listagem das chaves de API
+func testListAPIKeys(t *testing.T, accessToken string) []APIKey {
+	url := fmt.Sprintf("%s/api-keys", baseURL)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Errorf("Erro ao criar requisição: %v", err)
+		return nil
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Errorf("Erro na requisição: %v", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	// Log da resposta
+	body, _ := io.ReadAll(resp.Body)
+	t.Logf("Status code: %d", resp.StatusCode)
+	t.Logf("Resposta: %s", string(body))
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d. Resposta: %s", http.StatusOK, resp.StatusCode, string(body))
+		return nil
+	}
+
+	var apiKeys []APIKey
+	if err := json.NewDecoder(bytes.NewBuffer(body)).Decode(&apiKeys); err != nil {
+		t.Errorf("Erro ao decodificar resposta: %v", err)
+		return nil
+	}
+
+	return apiKeys
+}
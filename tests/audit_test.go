@@ -0,0 +1,269 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"life/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditEvent representa um evento do log de auditoria nos testes
+type AuditEvent struct {
+	ID         uint                   `json:"id"`
+	ActorID    *uint                  `json:"actor_id"`
+	Action     string                 `json:"action"`
+	TargetType string                 `json:"target_type"`
+	TargetID   string                 `json:"target_id"`
+	Success    bool                   `json:"success"`
+	IP         string                 `json:"ip"`
+	UserAgent  string                 `json:"user_agent"`
+	RequestID  string                 `json:"request_id"`
+	Metadata   map[string]interface{} `json:"metadata"`
+}
+
+// AuditEventsResponse representa uma página do log de auditoria
+type AuditEventsResponse struct {
+	Events       []AuditEvent `json:"events"`
+	NextBeforeID *uint        `json:"next_before_id"`
+}
+
+// TestRequestID testa a geração e a propagação do ID da requisição
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(logger.RequestID())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(logger.RequestIDKey))
+	})
+
+	request := func(requestID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		if requestID != "" {
+			req.Header.Set(logger.RequestIDHeader, requestID)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// 1. Um ID válido enviado pelo cliente é mantido
+	resp := request("pedido-123")
+	if resp.Header().Get(logger.RequestIDHeader) != "pedido-123" || resp.Body.String() != "pedido-123" {
+		t.Errorf("ID esperado pedido-123, recebido %q / %q", resp.Header().Get(logger.RequestIDHeader), resp.Body.String())
+	}
+
+	// 2. Sem ID, ou com um ID inválido, um novo é gerado
+	for _, requestID := range []string{"", "com espaço", strings.Repeat("a", 200)} {
+		resp := request(requestID)
+		generated := resp.Header().Get(logger.RequestIDHeader)
+		if len(generated) != 32 || generated == requestID || resp.Body.String() != generated {
+			t.Errorf("ID gerado inválido para %q: %q", requestID, generated)
+		}
+	}
+}
+
+// TestAuditLog testa o registro, a consulta e a exportação do log de auditoria
+func TestAuditLog(t *testing.T) {
+	setupTest(t)
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+	_, adminLogin := testRegisterAdmin(t)
+	if adminLogin == nil {
+		t.Fatal("Falha no registro do administrador")
+	}
+
+	// 1. Login com senha incorreta e login bem-sucedido, com ID de requisição conhecido
+	if status, _ := testLoginAttempt(t, user.Username, "senha-errada"); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+	requestID := fmt.Sprintf("audit-%d", user.ID)
+	loginData := testLoginWithRequestID(t, user.Username, testPassword, requestID)
+	if loginData == nil {
+		t.Fatal("Falha no login")
+	}
+	key := testCreateAPIKey(t, loginData.AccessToken)
+	if key == nil {
+		t.Fatal("Falha ao criar chave de API")
+	}
+
+	// 2. Usuários comuns não consultam o log
+	if status := testAuthorizedStatus(t, "GET", "/admin/audit-events", loginData.AccessToken); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+
+	// 3. As tentativas de login são registradas com o resultado
+	events := testListAuditEvents(t, adminLogin.AccessToken, url.Values{
+		"actor_id": {fmt.Sprint(user.ID)},
+		"action":   {"auth.login"},
+	})
+	if len(events.Events) != 2 || events.Events[0].Success != true || events.Events[1].Success != false {
+		t.Fatalf("Eventos de login inesperados: %+v", events.Events)
+	}
+	if events.Events[0].RequestID != requestID || events.Events[0].IP == "" {
+		t.Errorf("Origem do login não registrada: %+v", events.Events[0])
+	}
+	if events.Events[0].IP == spoofedIP {
+		t.Errorf("O IP registrado não deveria vir de um X-Forwarded-For forjado: %+v", events.Events[0])
+	}
+
+	// 4. A busca pelo ID da requisição encontra o evento correspondente
+	events = testListAuditEvents(t, adminLogin.AccessToken, url.Values{"request_id": {requestID}})
+	if len(events.Events) != 1 || events.Events[0].Action != "auth.login" {
+		t.Errorf("Eventos inesperados para o ID da requisição: %+v", events.Events)
+	}
+
+	// 5. Mutações de API keys registram o recurso afetado
+	events = testListAuditEvents(t, adminLogin.AccessToken, url.Values{
+		"target_type": {"api_key"},
+		"target_id":   {fmt.Sprint(key.ID)},
+	})
+	if len(events.Events) != 1 || events.Events[0].Action != "api_key.created" || events.Events[0].ActorID == nil || *events.Events[0].ActorID != user.ID {
+		t.Errorf("Evento de criação da chave inesperado: %+v", events.Events)
+	}
+
+	// 6. A listagem é paginada pelo ID
+	events = testListAuditEvents(t, adminLogin.AccessToken, url.Values{"actor_id": {fmt.Sprint(user.ID)}, "limit": {"1"}})
+	if len(events.Events) != 1 || events.NextBeforeID == nil {
+		t.Fatalf("Página inesperada: %+v", events)
+	}
+	next := testListAuditEvents(t, adminLogin.AccessToken, url.Values{
+		"actor_id":  {fmt.Sprint(user.ID)},
+		"limit":     {"1"},
+		"before_id": {fmt.Sprint(*events.NextBeforeID)},
+	})
+	if len(next.Events) != 1 || next.Events[0].ID >= events.Events[0].ID {
+		t.Errorf("Próxima página inesperada: %+v", next)
+	}
+
+	// 7. Filtros inválidos são recusados
+	if status := testAuthorizedStatus(t, "GET", "/admin/audit-events?from=ontem", adminLogin.AccessToken); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+
+	// 8. A exportação retorna um evento por linha, em ordem de inserção
+	exported := testExportAuditEvents(t, adminLogin.AccessToken, url.Values{"actor_id": {fmt.Sprint(user.ID)}})
+	if len(exported) < 3 || exported[0].Action != "auth.register" {
+		t.Fatalf("Exportação inesperada: %+v", exported)
+	}
+	for i := 1; i < len(exported); i++ {
+		if exported[i].ID <= exported[i-1].ID {
+			t.Errorf("Eventos fora de ordem: %d após %d", exported[i].ID, exported[i-1].ID)
+		}
+	}
+}
+
+// spoofedIP é o endereço enviado em X-Forwarded-For pelos testes; sem proxies
+// confiáveis configurados, a API deve ignorá-lo
+const spoofedIP = "198.51.100.99"
+
+// testLoginWithRequestID faz o login enviando o cabeçalho X-Request-ID e um
+// X-Forwarded-For forjado
+func testLoginWithRequestID(t *testing.T, username, password, requestID string) *LoginResponse {
+	jsonData, err := json.Marshal(map[string]string{
+		"username": username,
+		"password": password,
+	})
+	if err != nil {
+		t.Errorf("Erro ao criar JSON: %v", err)
+		return nil
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/login", baseURL), bytes.NewBuffer(jsonData))
+	if err != nil {
+		t.Errorf("Erro ao criar requisição: %v", err)
+		return nil
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", requestID)
+	req.Header.Set("X-Forwarded-For", spoofedIP)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Errorf("Erro na requisição: %v", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	t.Logf("Status code: %d", resp.StatusCode)
+	t.Logf("Resposta: %s", string(body))
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, resp.StatusCode)
+		return nil
+	}
+	if resp.Header.Get("X-Request-ID") != requestID {
+		t.Errorf("X-Request-ID esperado %q, recebido %q", requestID, resp.Header.Get("X-Request-ID"))
+	}
+
+	var loginResponse LoginResponse
+	if err := json.Unmarshal(body, &loginResponse); err != nil {
+		t.Errorf("Erro ao decodificar resposta: %v", err)
+		return nil
+	}
+
+	return &loginResponse
+}
+
+// testListAuditEvents consulta o log de auditoria com os filtros informados
+func testListAuditEvents(t *testing.T, accessToken string, filters url.Values) *AuditEventsResponse {
+	status, body := testJSONRequest(t, "GET", "/admin/audit-events?"+filters.Encode(), accessToken, nil)
+	if status != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+		return &AuditEventsResponse{}
+	}
+
+	var response AuditEventsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Errorf("Erro ao decodificar resposta: %v", err)
+	}
+
+	return &response
+}
+
+// testExportAuditEvents exporta o log de auditoria e decodifica cada linha
+func testExportAuditEvents(t *testing.T, accessToken string, filters url.Values) []AuditEvent {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/admin/audit-events/export?%s", baseURL, filters.Encode()), nil)
+	if err != nil {
+		t.Errorf("Erro ao criar requisição: %v", err)
+		return nil
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Errorf("Erro na requisição: %v", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("Resposta inesperada: %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+		return nil
+	}
+
+	var events []AuditEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var event AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Errorf("Linha inválida %q: %v", scanner.Text(), err)
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events
+}
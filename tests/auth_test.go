@@ -34,7 +34,7 @@ func TestAuthFlow(t *testing.T) {
 	}
 
 	// 2. Login
-	loginData := testLogin(t, user.Username, "senha123")
+	loginData := testLogin(t, user.Username, testPassword)
 	if loginData == nil {
 		t.Fatal("Falha no login")
 	}
@@ -51,6 +51,71 @@ func TestAuthFlow(t *testing.T) {
 	}
 }
 
+// TestRefreshTokenReuse testa a rotação do refresh token e a detecção de reutilização
+func TestRefreshTokenReuse(t *testing.T) {
+	setupTest(t)
+	// 1. Registro e Login
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	loginData := testLogin(t, user.Username, testPassword)
+	if loginData == nil {
+		t.Fatal("Falha no login")
+	}
+
+	// 2. Refresh deve rotacionar o token
+	rotated := testRefreshToken(t, loginData.RefreshToken)
+	if rotated == nil {
+		t.Fatal("Falha no refresh token")
+	}
+	if rotated.RefreshToken == loginData.RefreshToken {
+		t.Fatal("O refresh token deveria ter sido rotacionado")
+	}
+
+	// 3. Reapresentar o token antigo deve ser rejeitado
+	if status := testRefreshTokenStatus(t, loginData.RefreshToken); status != http.StatusUnauthorized {
+		t.Fatalf("Status code esperado %d ao reutilizar token, recebido %d", http.StatusUnauthorized, status)
+	}
+
+	// 4. A reutilização revoga a família inteira, inclusive o token mais recente
+	if status := testRefreshTokenStatus(t, rotated.RefreshToken); status != http.StatusUnauthorized {
+		t.Fatalf("Status code esperado %d após revogação da família, recebido %d", http.StatusUnauthorized, status)
+	}
+}
+
+// TestLogoutAll testa o encerramento de todas as sessões do usuário
+func TestLogoutAll(t *testing.T) {
+	setupTest(t)
+	// 1. Registro e dois logins (duas sessões)
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	first := testLogin(t, user.Username, testPassword)
+	second := testLogin(t, user.Username, testPassword)
+	if first == nil || second == nil {
+		t.Fatal("Falha no login")
+	}
+
+	// 2. Logout em todas as sessões
+	if status := testAuthorizedStatus(t, "POST", "/logout-all", first.AccessToken); status != http.StatusNoContent {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusNoContent, status)
+	}
+
+	// 3. Nenhum access token continua válido
+	for _, session := range []*LoginResponse{first, second} {
+		if status := testAuthorizedStatus(t, "GET", "/profile", session.AccessToken); status != http.StatusUnauthorized {
+			t.Errorf("Status code esperado %d para access token revogado, recebido %d", http.StatusUnauthorized, status)
+		}
+		if status := testRefreshTokenStatus(t, session.RefreshToken); status != http.StatusUnauthorized {
+			t.Errorf("Status code esperado %d para refresh token revogado, recebido %d", http.StatusUnauthorized, status)
+		}
+	}
+}
+
 // testRegister testa o registro de um novo usuário
 func testRegister(t *testing.T) *User {
 	url := fmt.Sprintf("%s/register", baseURL)
@@ -58,7 +123,7 @@ func testRegister(t *testing.T) *User {
 	timestamp := time.Now().Format("20060102150405")
 	data := map[string]string{
 		"username":     fmt.Sprintf("test_user_%s", timestamp),
-		"password":     "senha123",
+		"password":     testPassword,
 		"display_name": "Usuário Teste",
 		"email":        fmt.Sprintf("test_%s@example.com", timestamp),
 	}
@@ -212,6 +277,110 @@ func testRefreshToken(t *testing.T, refreshToken string) *LoginResponse {
 	return &loginData
 }
 
+// testRefreshTokenStatus envia um refresh e retorna apenas o status code
+func testRefreshTokenStatus(t *testing.T, refreshToken string) int {
+	url := fmt.Sprintf("%s/refresh", baseURL)
+
+	data := map[string]string{
+		"refresh_token": refreshToken,
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		t.Errorf("Erro ao criar JSON: %v", err)
+		return 0
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		t.Errorf("Erro ao criar requisição: %v", err)
+		return 0
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Errorf("Erro na requisição: %v", err)
+		return 0
+	}
+	defer resp.Body.Close()
+
+	// Log da resposta
+	body, _ := io.ReadAll(resp.Body)
+	t.Logf("Status code: %d", resp.StatusCode)
+	t.Logf("Resposta: %s", string(body))
+
+	return resp.StatusCode
+}
+
+// testAuthorizedStatus envia uma requisição autenticada sem corpo e retorna o status code
+func testAuthorizedStatus(t *testing.T, method, path, accessToken string) int {
+	url := fmt.Sprintf("%s%s", baseURL, path)
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Errorf("Erro ao criar requisição: %v", err)
+		return 0
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Errorf("Erro na requisição: %v", err)
+		return 0
+	}
+	defer resp.Body.Close()
+
+	// Log da resposta
+	body, _ := io.ReadAll(resp.Body)
+	t.Logf("Status code: %d", resp.StatusCode)
+	t.Logf("Resposta: %s", string(body))
+
+	return resp.StatusCode
+}
+
+// testJSONRequest envia uma requisição com corpo JSON, autenticada quando
+// accessToken não é vazio, e retorna o status code e o corpo da resposta
+func testJSONRequest(t *testing.T, method, path, accessToken string, data interface{}) (int, []byte) {
+	url := fmt.Sprintf("%s%s", baseURL, path)
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		t.Errorf("Erro ao criar JSON: %v", err)
+		return 0, nil
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		t.Errorf("Erro ao criar requisição: %v", err)
+		return 0, nil
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Errorf("Erro na requisição: %v", err)
+		return 0, nil
+	}
+	defer resp.Body.Close()
+
+	// Log da resposta
+	body, _ := io.ReadAll(resp.Body)
+	t.Logf("Status code: %d", resp.StatusCode)
+	t.Logf("Resposta: %s", string(body))
+
+	return resp.StatusCode, body
+}
+
 // testLogout testa o logout
 func testLogout(t *testing.T, refreshToken string) bool {
 	url := fmt.Sprintf("%s/logout", baseURL)
@@ -5,13 +5,23 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
 )
 
 // baseURL é a URL base da API
 var baseURL = "http://localhost:8080/api/v1"
 
+// testPassword é a senha dos usuários criados nos testes; atende à política de senhas padrão
+const testPassword = "Vida-Longa-2024"
+
+// mailFile é o arquivo em que a API grava os emails enviados durante os testes
+var mailFile = filepath.Join(os.TempDir(), "life-test-mail.jsonl")
+
 // setupTest configura o ambiente de teste
 func setupTest(t *testing.T) {
 	// Verifica se a API está rodando
@@ -41,6 +51,16 @@ func startAPI() error {
 	os.Setenv("DB_NAME", "life_test")
 	os.Setenv("JWT_SECRET", "test_secret")
 	os.Setenv("PORT", "8080")
+	os.Setenv("MAIL_DRIVER", "file")
+	os.Setenv("MAIL_FILE", mailFile)
+	// Todos os testes partem do mesmo IP; o bloqueio por IP não deve interferir entre eles
+	os.Setenv("LOGIN_IP_MAX_FAILURES", "1000")
+	os.Setenv("RATE_LIMIT_REGISTER", "1000/1h")
+	os.Setenv("RATE_LIMIT_LOGIN", "1000/1m")
+	os.Setenv("RATE_LIMIT_PASSWORD_FORGOT", "1000/1h")
+	os.Setenv("RATE_LIMIT_VERIFY_EMAIL_RESEND", "1000/1h")
+	os.Setenv("USAGE_FLUSH_INTERVAL", "1s")
+	os.Setenv("ADMIN_USERS", "@admin.example.com")
 
 	// Inicia a API em background
 	cmd := exec.Command("go", "run", "main.go")
@@ -51,6 +71,24 @@ func startAPI() error {
 	return nil
 }
 
+// testDB abre uma conexão com o banco de dados da API de teste, usada apenas
+// para simular dados que a API não gera mais (ex: registros legados)
+func testDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_NAME"),
+		os.Getenv("DB_PORT"),
+	)
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Erro ao conectar ao banco de dados: %v", err)
+	}
+	return db
+}
+
 // TestMain é a função principal de teste
 func TestMain(m *testing.M) {
 	// Executa os testes
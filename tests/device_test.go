@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"life/auth"
+)
+
+// DeviceCodeResponse representa a resposta da solicitação de código de dispositivo
+type DeviceCodeResponse struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	Interval        int64  `json:"interval"`
+}
+
+// TestUserCode testa a geração e a normalização dos códigos de dispositivo
+func TestUserCode(t *testing.T) {
+	code, err := auth.GenerateUserCode()
+	if err != nil {
+		t.Fatalf("Erro ao gerar código: %v", err)
+	}
+	if len(code) != 8 || strings.Trim(code, "BCDFGHJKLMNPQRSTVWXZ") != "" {
+		t.Errorf("Código fora do formato esperado: %s", code)
+	}
+
+	formatted := auth.FormatUserCode(code)
+	if formatted != code[:4]+"-"+code[4:] {
+		t.Errorf("Formatação inesperada: %s", formatted)
+	}
+	if auth.NormalizeUserCode(strings.ToLower(formatted)) != code || auth.NormalizeUserCode(" "+code[:4]+" "+code[4:]) != code {
+		t.Errorf("Normalização inesperada de %s", formatted)
+	}
+}
+
+// TestDeviceFlow testa o login de um dispositivo aprovado por um usuário autenticado
+func TestDeviceFlow(t *testing.T) {
+	setupTest(t)
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+	loginResp := testLogin(t, user.Username, testPassword)
+	if loginResp == nil {
+		t.Fatal("Falha no login")
+	}
+
+	// 1. Enquanto não há aprovação, o dispositivo recebe authorization_pending e,
+	// se consultar antes do intervalo, slow_down
+	pending := testRequestDeviceCode(t, "Console pendente")
+	if errorCode := testDeviceTokenError(t, pending.DeviceCode); errorCode != "authorization_pending" {
+		t.Errorf("Erro esperado authorization_pending, recebido %q", errorCode)
+	}
+	if errorCode := testDeviceTokenError(t, pending.DeviceCode); errorCode != "slow_down" {
+		t.Errorf("Erro esperado slow_down, recebido %q", errorCode)
+	}
+
+	// 2. Dispositivo recusado recebe access_denied
+	denied := testRequestDeviceCode(t, "Console recusado")
+	if status, _ := testJSONRequest(t, "POST", "/device/approve", loginResp.AccessToken, map[string]interface{}{"user_code": denied.UserCode, "approve": false}); status != http.StatusNoContent {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusNoContent, status)
+	}
+	if errorCode := testDeviceTokenError(t, denied.DeviceCode); errorCode != "access_denied" {
+		t.Errorf("Erro esperado access_denied, recebido %q", errorCode)
+	}
+
+	// 3. O usuário confere o dispositivo e o aprova digitando o código em minúsculas
+	device := testRequestDeviceCode(t, "Console da sala")
+	status, body := testJSONRequest(t, "GET", "/device?user_code="+strings.ToLower(device.UserCode), loginResp.AccessToken, nil)
+	if status != http.StatusOK || !strings.Contains(string(body), "Console da sala") {
+		t.Errorf("Resposta inesperada: %d %s", status, body)
+	}
+	if status, _ := testJSONRequest(t, "POST", "/device/approve", loginResp.AccessToken, map[string]interface{}{"user_code": strings.ToLower(device.UserCode), "approve": true}); status != http.StatusNoContent {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusNoContent, status)
+	}
+
+	// 4. O código não pode ser aprovado novamente
+	if status, _ := testJSONRequest(t, "POST", "/device/approve", loginResp.AccessToken, map[string]interface{}{"user_code": device.UserCode, "approve": true}); status != http.StatusNotFound {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusNotFound, status)
+	}
+
+	// 5. O dispositivo recebe os tokens e a sessão aparece na lista
+	status, body = testJSONRequest(t, "POST", "/device/token", "", map[string]string{"device_code": device.DeviceCode})
+	if status != http.StatusOK {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	var deviceLogin LoginResponse
+	if err := json.Unmarshal(body, &deviceLogin); err != nil || deviceLogin.AccessToken == "" || deviceLogin.RefreshToken == "" {
+		t.Fatalf("Resposta de login inválida: %s", body)
+	}
+	if status := testAuthorizedStatus(t, "GET", "/profile", deviceLogin.AccessToken); status != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+
+	found := false
+	for _, session := range testListSessions(t, loginResp.AccessToken) {
+		if session.DeviceName == "Console da sala" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Sessão do dispositivo não encontrada")
+	}
+}
+
+// testRequestDeviceCode solicita um código de dispositivo
+func testRequestDeviceCode(t *testing.T, deviceName string) *DeviceCodeResponse {
+	status, body := testJSONRequest(t, "POST", "/device/code", "", map[string]string{"device_name": deviceName})
+	if status != http.StatusOK {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+
+	var resp DeviceCodeResponse
+	if err := json.Unmarshal(body, &resp); err != nil || resp.DeviceCode == "" || resp.UserCode == "" {
+		t.Fatalf("Resposta inválida: %s", body)
+	}
+	return &resp
+}
+
+// testDeviceTokenError consulta a autorização do dispositivo e retorna o código de erro
+func testDeviceTokenError(t *testing.T, deviceCode string) string {
+	status, body := testJSONRequest(t, "POST", "/device/token", "", map[string]string{"device_code": deviceCode})
+	if status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+
+	var resp struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(body, &resp)
+	return resp.Error
+}
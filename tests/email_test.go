@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"life/mail"
+)
+
+// Mail representa um email gravado pelo FileMailer nos testes
+type Mail struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// TestFileMailer testa a gravação das mensagens pelo FileMailer
+func TestFileMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.jsonl")
+	mailer := mail.NewFileMailer(path)
+
+	for _, to := range []string{"a@example.com", "b@example.com"} {
+		if err := mailer.Send(mail.Message{To: to, Subject: "Assunto", Body: "Corpo"}); err != nil {
+			t.Fatalf("Erro ao enviar email: %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Erro ao abrir arquivo: %v", err)
+	}
+	defer f.Close()
+
+	var sent []Mail
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var m Mail
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			t.Fatalf("Linha inválida: %v", err)
+		}
+		sent = append(sent, m)
+	}
+
+	if len(sent) != 2 || sent[1].To != "b@example.com" {
+		t.Errorf("Mensagens gravadas inesperadas: %+v", sent)
+	}
+}
+
+// TestEmailVerification testa a confirmação de email pelo link enviado no registro
+func TestEmailVerification(t *testing.T) {
+	setupTest(t)
+	// 1. Registro envia o link de verificação
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	token := testMailToken(t, user.Email, "verify-email")
+	if token == "" {
+		t.Fatal("Email de verificação não encontrado")
+	}
+
+	// 2. Token inválido é rejeitado
+	if status, _ := testJSONRequest(t, "POST", "/verify-email", "", map[string]string{"token": "invalido"}); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+
+	// 3. Token válido confirma o email
+	if status, _ := testJSONRequest(t, "POST", "/verify-email", "", map[string]string{"token": token}); status != http.StatusNoContent {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusNoContent, status)
+	}
+
+	loginResp := testLogin(t, user.Username, testPassword)
+	if loginResp == nil {
+		t.Fatal("Falha no login")
+	}
+
+	status, body := testJSONRequest(t, "GET", "/profile", loginResp.AccessToken, nil)
+	if status != http.StatusOK {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	var profile struct {
+		EmailVerified bool `json:"email_verified"`
+	}
+	if err := json.Unmarshal(body, &profile); err != nil || !profile.EmailVerified {
+		t.Errorf("Email deveria estar verificado: %s", string(body))
+	}
+
+	// 4. O reenvio sempre responde 202, mesmo para emails desconhecidos
+	if status, _ := testJSONRequest(t, "POST", "/verify-email/resend", "", map[string]string{"email": "desconhecido@example.com"}); status != http.StatusAccepted {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusAccepted, status)
+	}
+}
+
+// testMailToken retorna o token do último link para linkPath enviado ao endereço
+func testMailToken(t *testing.T, email, linkPath string) string {
+	pattern := regexp.MustCompile(regexp.QuoteMeta(linkPath) + `\?token=(\S+)`)
+
+	f, err := os.Open(mailFile)
+	if err != nil {
+		t.Errorf("Erro ao abrir arquivo de emails: %v", err)
+		return ""
+	}
+	defer f.Close()
+
+	var token string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var m Mail
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil || m.To != email {
+			continue
+		}
+		if match := pattern.FindStringSubmatch(m.Body); match != nil {
+			if token, err = url.QueryUnescape(match[1]); err != nil {
+				t.Errorf("Erro ao decodificar token: %v", err)
+				return ""
+			}
+		}
+	}
+
+	t.Logf("Token de %s para %s: %s", linkPath, email, token)
+	return token
+}
@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"life/auth"
+)
+
+// TestLoginGuardConfig testa a leitura dos limites de tentativas de login
+func TestLoginGuardConfig(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "3")
+	t.Setenv("LOGIN_LOCKOUT_BASE", "30s")
+
+	config, err := auth.LoginGuardConfigFromEnv()
+	if err != nil {
+		t.Fatalf("Erro ao ler configuração: %v", err)
+	}
+	if config.MaxAccountFailures != 3 || config.BaseLockout != 30*time.Second {
+		t.Errorf("Configuração inesperada: %+v", config)
+	}
+
+	t.Setenv("LOGIN_LOCKOUT_MAX", "nunca")
+	if _, err := auth.LoginGuardConfigFromEnv(); err == nil {
+		t.Error("LOGIN_LOCKOUT_MAX inválido deveria ser rejeitado")
+	}
+}
+
+// TestLoginLockout testa o bloqueio da conta após falhas seguidas
+func TestLoginLockout(t *testing.T) {
+	setupTest(t)
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	// 1. As primeiras falhas retornam 401
+	for i := 0; i < 5; i++ {
+		if status, _ := testLoginAttempt(t, user.Username, "senhaerrada"); status != http.StatusUnauthorized {
+			t.Fatalf("Tentativa %d: status code esperado %d, recebido %d", i+1, http.StatusUnauthorized, status)
+		}
+	}
+
+	// 2. Com a conta bloqueada, até a senha correta é recusada com Retry-After
+	status, retryAfter := testLoginAttempt(t, user.Username, testPassword)
+	if status != http.StatusTooManyRequests {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusTooManyRequests, status)
+	}
+	if seconds, err := strconv.Atoi(retryAfter); err != nil || seconds <= 0 {
+		t.Errorf("Retry-After inválido: %q", retryAfter)
+	}
+}
+
+// TestLoginLockoutBackoff testa que o backoff continua crescendo quando os
+// bloqueios passam a durar mais que a janela de contagem de falhas
+func TestLoginLockoutBackoff(t *testing.T) {
+	setupTest(t)
+	guard := auth.NewLoginGuard(testDB(t), auth.LoginGuardConfig{
+		MaxAccountFailures: 2,
+		MaxIPFailures:      1000,
+		BaseLockout:        100 * time.Millisecond,
+		MaxLockout:         10 * time.Second,
+		FailureWindow:      150 * time.Millisecond,
+	})
+	username := fmt.Sprintf("backoff_%d", time.Now().UnixNano())
+	ip := "192.0.2.10"
+
+	// fail registra uma falha assim que o bloqueio anterior termina e retorna o novo bloqueio
+	fail := func() time.Duration {
+		for {
+			retryAfter, err := guard.Check(username, ip)
+			if err != nil {
+				t.Fatalf("Erro ao verificar bloqueio: %v", err)
+			}
+			if retryAfter <= 0 {
+				break
+			}
+			time.Sleep(retryAfter + 10*time.Millisecond)
+		}
+		if err := guard.RecordFailure(username, ip); err != nil {
+			t.Fatalf("Erro ao registrar falha: %v", err)
+		}
+		retryAfter, err := guard.Check(username, ip)
+		if err != nil {
+			t.Fatalf("Erro ao verificar bloqueio: %v", err)
+		}
+		return retryAfter
+	}
+
+	// 1. O limite de falhas bloqueia pelo tempo base
+	fail()
+	if lockout := fail(); lockout <= 0 || lockout > 100*time.Millisecond {
+		t.Fatalf("Bloqueio esperado de até 100ms, recebido %v", lockout)
+	}
+
+	// 2. Cada falha após um bloqueio dobra a duração, mesmo quando o bloqueio
+	// anterior foi mais longo que a janela de 150ms
+	previous := 100 * time.Millisecond
+	for i := 0; i < 3; i++ {
+		lockout := fail()
+		if lockout <= previous {
+			t.Fatalf("Falha %d: bloqueio deveria passar de %v, recebido %v", i+3, previous, lockout)
+		}
+		previous *= 2
+	}
+
+	// 3. Passada a janela após o fim do bloqueio, a contagem recomeça
+	time.Sleep(fail() + 200*time.Millisecond)
+	if lockout := fail(); lockout != 0 {
+		t.Errorf("A contagem deveria recomeçar, recebido bloqueio de %v", lockout)
+	}
+}
+
+// testLoginAttempt tenta o login e retorna o status code e o header Retry-After
+func testLoginAttempt(t *testing.T, username, password string) (int, string) {
+	url := fmt.Sprintf("%s/login", baseURL)
+
+	jsonData, err := json.Marshal(map[string]string{
+		"username": username,
+		"password": password,
+	})
+	if err != nil {
+		t.Errorf("Erro ao criar JSON: %v", err)
+		return 0, ""
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		t.Errorf("Erro ao criar requisição: %v", err)
+		return 0, ""
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Errorf("Erro na requisição: %v", err)
+		return 0, ""
+	}
+	defer resp.Body.Close()
+
+	// Log da resposta
+	body, _ := io.ReadAll(resp.Body)
+	t.Logf("Status code: %d", resp.StatusCode)
+	t.Logf("Resposta: %s", string(body))
+
+	return resp.StatusCode, resp.Header.Get("Retry-After")
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"life/auth"
+)
+
+// MFAChallenge representa o desafio de 2FA retornado pelo login nos testes
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+// TestTOTP testa o cálculo dos códigos com os vetores da RFC 6238 (SHA-1)
+func TestTOTP(t *testing.T) {
+	// "12345678901234567890" em base32
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	// Os vetores da RFC usam 8 dígitos; os códigos de 6 dígitos são o sufixo
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, expected := range vectors {
+		code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatalf("Erro ao calcular código: %v", err)
+		}
+		if code != expected {
+			t.Errorf("T=%d: código esperado %s, recebido %s", unix, expected, code)
+		}
+	}
+
+	// Um passo de diferença de relógio é aceito, dois não
+	now := time.Unix(1111111111, 0)
+	previous, _ := auth.TOTPCode(secret, auth.TOTPStep(now)-1)
+	if _, ok := auth.ValidateTOTP(secret, previous, now); !ok {
+		t.Error("Código do passo anterior deveria ser aceito")
+	}
+	old, _ := auth.TOTPCode(secret, auth.TOTPStep(now)-2)
+	if _, ok := auth.ValidateTOTP(secret, old, now); ok {
+		t.Error("Código de dois passos atrás não deveria ser aceito")
+	}
+
+	// Códigos de recuperação são normalizados
+	codes, err := auth.GenerateRecoveryCodes(2)
+	if err != nil || len(codes) != 2 {
+		t.Fatalf("Erro ao gerar códigos de recuperação: %v", err)
+	}
+	if auth.NormalizeRecoveryCode(" "+codes[0]+" ") != auth.NormalizeRecoveryCode(codes[0]) {
+		t.Error("Normalização do código de recuperação inconsistente")
+	}
+}
+
+// TestMFAFlow testa a ativação da 2FA e o login em duas etapas
+func TestMFAFlow(t *testing.T) {
+	setupTest(t)
+	// 1. Registro, login e início da configuração
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	loginResp := testLogin(t, user.Username, testPassword)
+	if loginResp == nil {
+		t.Fatal("Falha no login")
+	}
+
+	status, body := testJSONRequest(t, "POST", "/2fa/setup", loginResp.AccessToken, nil)
+	if status != http.StatusOK {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	var setup struct {
+		Secret string `json:"secret"`
+	}
+	if err := json.Unmarshal(body, &setup); err != nil || setup.Secret == "" {
+		t.Fatalf("Resposta de configuração inválida: %v", err)
+	}
+
+	// 2. Confirmação com o código atual retorna os códigos de recuperação
+	code, err := auth.TOTPCode(setup.Secret, auth.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatalf("Erro ao calcular código: %v", err)
+	}
+	status, body = testJSONRequest(t, "POST", "/2fa/enable", loginResp.AccessToken, map[string]string{"code": code})
+	if status != http.StatusOK {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	var recovery struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	if err := json.Unmarshal(body, &recovery); err != nil || len(recovery.RecoveryCodes) == 0 {
+		t.Fatalf("Códigos de recuperação não retornados: %v", err)
+	}
+
+	// 3. O login passa a retornar um desafio em vez dos tokens
+	status, body = testJSONRequest(t, "POST", "/login", "", map[string]string{
+		"username": user.Username,
+		"password": testPassword,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	var challenge MFAChallenge
+	if err := json.Unmarshal(body, &challenge); err != nil || !challenge.MFARequired || challenge.MFAToken == "" {
+		t.Fatalf("Desafio de 2FA não retornado: %s", string(body))
+	}
+
+	// 4. O código já usado na ativação não pode ser reutilizado
+	status, _ = testJSONRequest(t, "POST", "/login/2fa", "", map[string]string{
+		"mfa_token": challenge.MFAToken,
+		"code":      code,
+	})
+	if status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+
+	// 5. Um código de recuperação conclui o login uma única vez
+	status, body = testJSONRequest(t, "POST", "/login/2fa", "", map[string]string{
+		"mfa_token": challenge.MFAToken,
+		"code":      recovery.RecoveryCodes[0],
+	})
+	if status != http.StatusOK {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	var tokens LoginResponse
+	if err := json.Unmarshal(body, &tokens); err != nil || tokens.AccessToken == "" {
+		t.Fatalf("Tokens não retornados: %s", string(body))
+	}
+
+	status, _ = testJSONRequest(t, "POST", "/login/2fa", "", map[string]string{
+		"mfa_token": challenge.MFAToken,
+		"code":      recovery.RecoveryCodes[1],
+	})
+	if status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+
+	// 6. Um token de desafio não dá acesso à API
+	if status := testAuthorizedStatus(t, "GET", "/profile", challenge.MFAToken); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"life/models"
+)
+
+// TestAccountRestriction testa a situação efetiva de suspensões e banimentos
+func TestAccountRestriction(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	cases := []struct {
+		name   string
+		user   models.User
+		status string
+	}{
+		{"ativa", models.User{Status: models.UserStatusActive}, ""},
+		{"suspensa", models.User{Status: models.UserStatusSuspended, SuspendedUntil: &future}, models.UserStatusSuspended},
+		{"suspensão expirada", models.User{Status: models.UserStatusSuspended, SuspendedUntil: &past}, ""},
+		{"banida", models.User{Status: models.UserStatusBanned}, models.UserStatusBanned},
+	}
+	for _, tc := range cases {
+		restriction := tc.user.Restriction(now)
+		switch {
+		case tc.status == "" && restriction != nil:
+			t.Errorf("Conta %s não deveria ter restrição: %+v", tc.name, restriction)
+		case tc.status != "" && (restriction == nil || restriction.Status != tc.status):
+			t.Errorf("Conta %s deveria estar %s: %+v", tc.name, tc.status, restriction)
+		}
+	}
+}
+
+// TestModeration testa a suspensão, o banimento e a reabilitação de jogadores
+func TestModeration(t *testing.T) {
+	setupTest(t)
+	player := testRegister(t)
+	if player == nil {
+		t.Fatal("Falha no registro")
+	}
+	playerLogin := testLogin(t, player.Username, testPassword)
+	if playerLogin == nil {
+		t.Fatal("Falha no login")
+	}
+	key := testCreateAPIKey(t, playerLogin.AccessToken)
+	if key == nil {
+		t.Fatal("Falha ao criar chave de API")
+	}
+
+	admin, adminLogin := testRegisterAdmin(t)
+	if adminLogin == nil {
+		t.Fatal("Falha no registro do administrador")
+	}
+	adminPath := fmt.Sprintf("/admin/users/%d", player.ID)
+	suspension := map[string]string{
+		"reason": "Linguagem ofensiva no chat",
+		"until":  time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+	}
+
+	// 1. Jogadores comuns não acessam a moderação e administradores não moderam a si mesmos
+	if status, _ := testJSONRequest(t, "POST", adminPath+"/suspend", playerLogin.AccessToken, suspension); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+	if status, _ := testJSONRequest(t, "POST", fmt.Sprintf("/admin/users/%d/ban", admin.ID), adminLogin.AccessToken, map[string]string{"reason": "Teste"}); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+
+	// 2. Suspensões exigem motivo e fim no futuro
+	if status, _ := testJSONRequest(t, "POST", adminPath+"/suspend", adminLogin.AccessToken, map[string]string{"until": suspension["until"]}); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+	expired := map[string]string{"reason": suspension["reason"], "until": time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)}
+	if status, _ := testJSONRequest(t, "POST", adminPath+"/suspend", adminLogin.AccessToken, expired); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+
+	// 3. A suspensão recusa o access token, o refresh token, a API key e o login
+	if status, _ := testJSONRequest(t, "POST", adminPath+"/suspend", adminLogin.AccessToken, suspension); status != http.StatusOK {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	status, body := testJSONRequest(t, "GET", "/profile", playerLogin.AccessToken, nil)
+	var restriction struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	if status != http.StatusForbidden || json.Unmarshal(body, &restriction) != nil || restriction.Status != "suspended" || restriction.Reason != suspension["reason"] {
+		t.Errorf("Resposta inesperada: %d %s", status, body)
+	}
+	if status := testRefreshTokenStatus(t, playerLogin.RefreshToken); status != http.StatusUnauthorized && status != http.StatusForbidden {
+		t.Errorf("Refresh token deveria ser recusado, recebido %d", status)
+	}
+	if status, _ := testAPIKeyRequest(t, "/integrations/profile", key.Key); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+	if status, _ := testJSONRequest(t, "POST", "/login", "", map[string]string{"username": player.Username, "password": testPassword}); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+
+	// 4. O banimento também bloqueia o login
+	if status, _ := testJSONRequest(t, "POST", adminPath+"/ban", adminLogin.AccessToken, map[string]string{"reason": "Uso de trapaças"}); status != http.StatusOK {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	status, body = testJSONRequest(t, "POST", "/login", "", map[string]string{"username": player.Username, "password": testPassword})
+	if status != http.StatusForbidden || json.Unmarshal(body, &restriction) != nil || restriction.Status != "banned" {
+		t.Errorf("Resposta inesperada: %d %s", status, body)
+	}
+
+	// 5. Após a reabilitação, o jogador faz login e usa a API key novamente
+	if status, _ := testJSONRequest(t, "POST", adminPath+"/reinstate", adminLogin.AccessToken, nil); status != http.StatusOK {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	newLogin := testLogin(t, player.Username, testPassword)
+	if newLogin == nil {
+		t.Fatal("Falha no login após a reabilitação")
+	}
+	if status := testAuthorizedStatus(t, "GET", "/profile", newLogin.AccessToken); status != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	if status, _ := testAPIKeyRequest(t, "/integrations/profile", key.Key); status != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+
+	// 6. As sessões encerradas na suspensão continuam encerradas
+	if status := testRefreshTokenStatus(t, playerLogin.RefreshToken); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+
+	// 7. A exportação de dados do jogador traz as ações de moderação sem os dados do moderador
+	var events []AuditEvent
+	if err := json.Unmarshal(testExportAccount(t, newLogin.AccessToken)["audit_events.json"], &events); err != nil {
+		t.Fatalf("Erro ao decodificar eventos exportados: %v", err)
+	}
+	moderationEvents := 0
+	for _, event := range events {
+		if event.Action != "user.suspended" && event.Action != "user.banned" && event.Action != "user.reinstated" {
+			continue
+		}
+		moderationEvents++
+		if event.ActorID != nil || event.IP != "" || event.UserAgent != "" || event.RequestID != "" {
+			t.Errorf("Evento de moderação com dados do moderador: %+v", event)
+		}
+	}
+	if moderationEvents != 3 {
+		t.Errorf("Eventos de moderação esperados 3, recebidos %d", moderationEvents)
+	}
+}
@@ -0,0 +1,308 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"life/auth"
+	"life/oidc"
+)
+
+// OAuthClient representa um cliente OAuth2 retornado pela API
+type OAuthClient struct {
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes"`
+}
+
+// OAuthTokenResponse representa a resposta do endpoint de token
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	Scope       string `json:"scope"`
+	Error       string `json:"error"`
+}
+
+// TestScopesAndPKCE testa o tratamento de escopos e a verificação PKCE
+func TestScopesAndPKCE(t *testing.T) {
+	// 1. Escopos são deduplicados e ordenados
+	scopes := auth.ParseScopes("scores:read profile:read  scores:read")
+	if strings.Join(scopes, " ") != "profile:read scores:read" {
+		t.Errorf("Escopos inesperados: %v", scopes)
+	}
+	if err := auth.ValidateScopes([]string{"profile:read", "admin"}); err == nil {
+		t.Error("Escopo desconhecido deveria ser recusado")
+	}
+	if !auth.ContainsScopes(scopes, []string{"profile:read"}) || auth.ContainsScopes(scopes, []string{"profile:write"}) {
+		t.Error("ContainsScopes retornou resultado inesperado")
+	}
+
+	// 2. Vetor de teste da RFC 7636, apêndice B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	if auth.PKCEChallenge(verifier) != challenge {
+		t.Errorf("code_challenge esperado %s, recebido %s", challenge, auth.PKCEChallenge(verifier))
+	}
+	if !auth.VerifyPKCE(verifier, challenge) || auth.VerifyPKCE(verifier+"x", challenge) {
+		t.Error("VerifyPKCE retornou resultado inesperado")
+	}
+}
+
+// TestOAuthAuthorizationCodeFlow testa o fluxo authorization code + PKCE de um cliente público
+func TestOAuthAuthorizationCodeFlow(t *testing.T) {
+	setupTest(t)
+	// 1. Registro do usuário e do cliente
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+	loginResp := testLogin(t, user.Username, testPassword)
+	if loginResp == nil {
+		t.Fatal("Falha no login")
+	}
+
+	redirectURI := "http://localhost:9999/callback"
+	client := testCreateOAuthClient(t, loginResp.AccessToken, map[string]interface{}{
+		"name":          "Cliente de Teste",
+		"redirect_uris": []string{redirectURI},
+		"scopes":        []string{"profile:read", "scores:read"},
+	})
+	if client == nil {
+		t.Fatal("Falha ao registrar cliente")
+	}
+	if client.ClientSecret != "" {
+		t.Error("Cliente público não deveria receber client_secret")
+	}
+
+	verifier, _ := oidc.RandomString()
+	authorization := map[string]interface{}{
+		"response_type":         "code",
+		"client_id":             client.ClientID,
+		"redirect_uri":          redirectURI,
+		"scope":                 "profile:read",
+		"state":                 "xyz",
+		"code_challenge":        auth.PKCEChallenge(verifier),
+		"code_challenge_method": "S256",
+	}
+
+	// 2. Autorização sem PKCE, com escopo não permitido ou redirect_uri desconhecida é recusada
+	query := url.Values{"response_type": {"code"}, "client_id": {client.ClientID}, "redirect_uri": {redirectURI}}
+	if status, _ := testJSONRequest(t, "GET", "/oauth/authorize?"+query.Encode(), loginResp.AccessToken, nil); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+	query.Set("code_challenge", auth.PKCEChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	query.Set("scope", "profile:write")
+	if status, _ := testJSONRequest(t, "GET", "/oauth/authorize?"+query.Encode(), loginResp.AccessToken, nil); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+	query.Set("scope", "profile:read")
+	query.Set("redirect_uri", "http://localhost:9999/outro")
+	if status, _ := testJSONRequest(t, "GET", "/oauth/authorize?"+query.Encode(), loginResp.AccessToken, nil); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+
+	// 3. A tela de consentimento exige aprovação na primeira vez
+	query.Set("redirect_uri", redirectURI)
+	status, body := testJSONRequest(t, "GET", "/oauth/authorize?"+query.Encode(), loginResp.AccessToken, nil)
+	if status != http.StatusOK {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	var consent struct {
+		ConsentRequired bool `json:"consent_required"`
+	}
+	if err := json.Unmarshal(body, &consent); err != nil || !consent.ConsentRequired {
+		t.Errorf("Consentimento deveria ser exigido: %s", body)
+	}
+
+	// 4. Recusa retorna access_denied
+	authorization["approve"] = false
+	redirect := testAuthorizeDecision(t, loginResp.AccessToken, authorization)
+	if redirect.Query().Get("error") != "access_denied" || redirect.Query().Get("state") != "xyz" {
+		t.Errorf("Redirecionamento inesperado: %s", redirect)
+	}
+
+	// 5. Aprovação retorna o código
+	authorization["approve"] = true
+	redirect = testAuthorizeDecision(t, loginResp.AccessToken, authorization)
+	code := redirect.Query().Get("code")
+	if code == "" || redirect.Query().Get("state") != "xyz" {
+		t.Fatalf("Redirecionamento inesperado: %s", redirect)
+	}
+
+	// 6. Troca com code_verifier incorreto é recusada e consome o código
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {client.ClientID},
+		"code_verifier": {verifier + "x"},
+	}
+	if status, resp := testOAuthToken(t, form, "", ""); status != http.StatusBadRequest || resp.Error != "invalid_grant" {
+		t.Errorf("Resposta inesperada: %d %+v", status, resp)
+	}
+	form.Set("code_verifier", verifier)
+	if status, _ := testOAuthToken(t, form, "", ""); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+
+	// 7. Nova autorização dispensa o consentimento e o código é trocado uma única vez
+	status, body = testJSONRequest(t, "GET", "/oauth/authorize?"+query.Encode(), loginResp.AccessToken, nil)
+	if status != http.StatusOK || json.Unmarshal(body, &consent) != nil || consent.ConsentRequired {
+		t.Errorf("Consentimento anterior deveria ser reaproveitado: %s", body)
+	}
+	form.Set("code", testAuthorizeDecision(t, loginResp.AccessToken, authorization).Query().Get("code"))
+	status, tokenResp := testOAuthToken(t, form, "", "")
+	if status != http.StatusOK || tokenResp.AccessToken == "" || tokenResp.Scope != "profile:read" {
+		t.Fatalf("Resposta inesperada: %d %+v", status, tokenResp)
+	}
+	if status, _ := testOAuthToken(t, form, "", ""); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+
+	// 8. O token delegado só acessa rotas cobertas pelos escopos concedidos
+	if status := testAuthorizedStatus(t, "GET", "/profile", tokenResp.AccessToken); status != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	if status := testAuthorizedStatus(t, "GET", "/users", tokenResp.AccessToken); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+	if status := testAuthorizedStatus(t, "GET", "/sessions", tokenResp.AccessToken); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+	if status := testAuthorizedStatus(t, "GET", "/oauth/clients", tokenResp.AccessToken); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+
+	// 9. A redirect_uri informada na autorização é exigida na troca
+	form.Set("code", testAuthorizeDecision(t, loginResp.AccessToken, authorization).Query().Get("code"))
+	form.Del("redirect_uri")
+	if status, resp := testOAuthToken(t, form, "", ""); status != http.StatusBadRequest || resp.Error != "invalid_grant" {
+		t.Errorf("Resposta inesperada: %d %+v", status, resp)
+	}
+
+	// 10. Omitida na autorização (cliente com uma única URI registrada), pode ser omitida na troca
+	delete(authorization, "redirect_uri")
+	form.Set("code", testAuthorizeDecision(t, loginResp.AccessToken, authorization).Query().Get("code"))
+	if status, resp := testOAuthToken(t, form, "", ""); status != http.StatusOK || resp.AccessToken == "" {
+		t.Errorf("Resposta inesperada: %d %+v", status, resp)
+	}
+}
+
+// TestOAuthClientCredentials testa o fluxo client_credentials de um cliente
+// confidencial, que não herda as permissões do administrador que o registrou
+func TestOAuthClientCredentials(t *testing.T) {
+	setupTest(t)
+	_, loginResp := testRegisterAdmin(t)
+	if loginResp == nil {
+		t.Fatal("Falha no registro do administrador")
+	}
+
+	client := testCreateOAuthClient(t, loginResp.AccessToken, map[string]interface{}{
+		"name":         "Serviço de Estatísticas",
+		"scopes":       []string{"users:read"},
+		"confidential": true,
+	})
+	if client == nil || client.ClientSecret == "" {
+		t.Fatal("Cliente confidencial deveria receber client_secret")
+	}
+
+	// 1. Segredo incorreto é recusado
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if status, resp := testOAuthToken(t, form, client.ClientID, "segredo-errado"); status != http.StatusUnauthorized || resp.Error != "invalid_client" {
+		t.Errorf("Resposta inesperada: %d %+v", status, resp)
+	}
+
+	// 2. Escopo fora do registrado é recusado
+	form.Set("scope", "profile:read")
+	if status, resp := testOAuthToken(t, form, client.ClientID, client.ClientSecret); status != http.StatusBadRequest || resp.Error != "invalid_scope" {
+		t.Errorf("Resposta inesperada: %d %+v", status, resp)
+	}
+
+	// 3. Token emitido com os escopos do cliente
+	form.Del("scope")
+	status, tokenResp := testOAuthToken(t, form, client.ClientID, client.ClientSecret)
+	if status != http.StatusOK || tokenResp.Scope != "users:read" {
+		t.Fatalf("Resposta inesperada: %d %+v", status, tokenResp)
+	}
+	if status := testAuthorizedStatus(t, "GET", "/profile", tokenResp.AccessToken); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+
+	// 4. O token não tem as permissões de administrador do dono do cliente
+	if status := testAuthorizedStatus(t, "GET", "/users", tokenResp.AccessToken); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+	if status := testAuthorizedStatus(t, "GET", "/users/1", tokenResp.AccessToken); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+}
+
+// testCreateOAuthClient registra um cliente OAuth2
+func testCreateOAuthClient(t *testing.T, accessToken string, data map[string]interface{}) *OAuthClient {
+	status, body := testJSONRequest(t, "POST", "/oauth/clients", accessToken, data)
+	if status != http.StatusCreated {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusCreated, status)
+		return nil
+	}
+
+	var client OAuthClient
+	if err := json.Unmarshal(body, &client); err != nil {
+		t.Errorf("Erro ao decodificar resposta: %v", err)
+		return nil
+	}
+
+	return &client
+}
+
+// testAuthorizeDecision envia a decisão de consentimento e retorna a URL de retorno
+func testAuthorizeDecision(t *testing.T, accessToken string, data map[string]interface{}) *url.URL {
+	status, body := testJSONRequest(t, "POST", "/oauth/authorize", accessToken, data)
+	if status != http.StatusOK {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+
+	var resp struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("Erro ao decodificar resposta: %v", err)
+	}
+
+	redirect, err := url.Parse(resp.RedirectTo)
+	if err != nil {
+		t.Fatalf("URL de retorno inválida: %v", err)
+	}
+	return redirect
+}
+
+// testOAuthToken chama o endpoint de token; com clientID, autentica via HTTP Basic
+func testOAuthToken(t *testing.T, form url.Values, clientID, clientSecret string) (int, *OAuthTokenResponse) {
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/oauth/token", baseURL), strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatalf("Erro ao criar requisição: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" {
+		req.SetBasicAuth(clientID, clientSecret)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Erro na requisição: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	t.Logf("Status code: %d", resp.StatusCode)
+	t.Logf("Resposta: %s", string(body))
+
+	var tokenResp OAuthTokenResponse
+	json.Unmarshal(body, &tokenResp)
+	return resp.StatusCode, &tokenResp
+}
@@ -0,0 +1,314 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"life/auth"
+	"life/handlers"
+	"life/oidc"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockOIDCProvider simula um provedor OpenID Connect com PKCE
+type mockOIDCProvider struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	mu    sync.Mutex
+	codes map[string]mockAuthorization
+}
+
+// mockAuthorization guarda o que o provedor recebeu na autorização
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+	subject   string
+}
+
+// newMockOIDCProvider inicia o provedor simulado
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Erro ao gerar chave RSA: %v", err)
+	}
+
+	m := &mockOIDCProvider{key: key, clientID: "life-test", codes: make(map[string]mockAuthorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "mock-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		m.mu.Lock()
+		auth, ok := m.codes[r.Form.Get("code")]
+		delete(m.codes, r.Form.Get("code"))
+		m.mu.Unlock()
+
+		clientID, _, _ := r.BasicAuth()
+		if !ok || clientID != m.clientID || oidc.CodeChallenge(r.Form.Get("code_verifier")) != auth.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "mock-access-token",
+			"token_type":   "Bearer",
+			"id_token":     m.idToken(t, auth.subject, auth.nonce, m.key),
+		})
+	})
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// authorize simula o consentimento do usuário e retorna o código emitido
+func (m *mockOIDCProvider) authorize(t *testing.T, authorizationURL, subject string) (code, state string) {
+	parsed, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatalf("URL de autorização inválida: %v", err)
+	}
+
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("client_id") != m.clientID {
+		t.Fatalf("Parâmetros de autorização inesperados: %s", parsed.RawQuery)
+	}
+
+	code = "code-" + subject
+	m.mu.Lock()
+	m.codes[code] = mockAuthorization{
+		challenge: query.Get("code_challenge"),
+		nonce:     query.Get("nonce"),
+		subject:   subject,
+	}
+	m.mu.Unlock()
+
+	return code, query.Get("state")
+}
+
+// idToken assina um id_token com a chave informada
+func (m *mockOIDCProvider) idToken(t *testing.T, subject, nonce string, key *rsa.PrivateKey) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            m.server.URL,
+		"sub":            subject,
+		"aud":            m.clientID,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"nonce":          nonce,
+		"email":          subject + "@example.com",
+		"email_verified": true,
+		"name":           "Jogador " + subject,
+	})
+	token.Header["kid"] = "mock-key"
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Erro ao assinar id_token: %v", err)
+	}
+	return signed
+}
+
+// TestOIDCProvider testa o fluxo authorization code + PKCE contra um provedor simulado
+func TestOIDCProvider(t *testing.T) {
+	mock := newMockOIDCProvider(t)
+	provider := oidc.NewProvider(oidc.Config{
+		Name:         "mock",
+		Issuer:       mock.server.URL,
+		ClientID:     mock.clientID,
+		ClientSecret: "segredo",
+		RedirectURL:  "http://localhost:8080/api/v1/oidc/mock/callback",
+		Scopes:       []string{"openid", "email"},
+	}, nil)
+	ctx := context.Background()
+
+	newAuthorization := func(subject string) (code, verifier, nonce string) {
+		verifier, _ = oidc.RandomString()
+		nonce, _ = oidc.RandomString()
+		authorizationURL, err := provider.AuthCodeURL(ctx, "state-"+subject, nonce, verifier)
+		if err != nil {
+			t.Fatalf("Erro ao montar URL de autorização: %v", err)
+		}
+		code, state := mock.authorize(t, authorizationURL, subject)
+		if state != "state-"+subject {
+			t.Fatalf("State esperado %q, recebido %q", "state-"+subject, state)
+		}
+		return code, verifier, nonce
+	}
+
+	// 1. Fluxo completo retorna a identidade do id_token
+	code, verifier, nonce := newAuthorization("alice")
+	identity, err := provider.Exchange(ctx, code, verifier, nonce)
+	if err != nil {
+		t.Fatalf("Erro na troca do código: %v", err)
+	}
+	if identity.Subject != "alice" || identity.Email != "alice@example.com" || !identity.EmailVerified {
+		t.Errorf("Identidade inesperada: %+v", identity)
+	}
+
+	// 2. Um code_verifier diferente é recusado pelo provedor
+	code, _, nonce = newAuthorization("bob")
+	otherVerifier, _ := oidc.RandomString()
+	if _, err := provider.Exchange(ctx, code, otherVerifier, nonce); err == nil {
+		t.Error("Troca com code_verifier incorreto deveria falhar")
+	}
+
+	// 3. Um nonce divergente invalida o id_token
+	code, verifier, _ = newAuthorization("carol")
+	if _, err := provider.Exchange(ctx, code, verifier, "outro-nonce"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("Erro esperado %v, recebido %v", oidc.ErrInvalidIDToken, err)
+	}
+}
+
+// TestOIDCProviderRejectsForgedToken testa a recusa de id_tokens assinados com outra chave
+func TestOIDCProviderRejectsForgedToken(t *testing.T) {
+	mock := newMockOIDCProvider(t)
+	forger, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Erro ao gerar chave RSA: %v", err)
+	}
+
+	// O endpoint de token passa a devolver um id_token assinado por outra chave
+	mock.key = forger
+	provider := oidc.NewProvider(oidc.Config{
+		Name:         "mock",
+		Issuer:       mock.server.URL,
+		ClientID:     mock.clientID,
+		ClientSecret: "segredo",
+		RedirectURL:  "http://localhost:8080/api/v1/oidc/mock/callback",
+	}, nil)
+
+	verifier, _ := oidc.RandomString()
+	authorizationURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce", verifier)
+	if err != nil {
+		t.Fatalf("Erro ao montar URL de autorização: %v", err)
+	}
+	code, _ := mock.authorize(t, authorizationURL, "mallory")
+
+	if _, err := provider.Exchange(context.Background(), code, verifier, "nonce"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("Erro esperado %v, recebido %v", oidc.ErrInvalidIDToken, err)
+	}
+}
+
+// TestOIDCLinkBrowserBinding testa que o vínculo de um provedor só é concluído
+// no navegador que o iniciou, e não por quem abre a URL gerada por outra pessoa
+func TestOIDCLinkBrowserBinding(t *testing.T) {
+	setupTest(t)
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	mock := newMockOIDCProvider(t)
+	provider := oidc.NewProvider(oidc.Config{
+		Name:         "mock",
+		Issuer:       mock.server.URL,
+		ClientID:     mock.clientID,
+		ClientSecret: "segredo",
+		RedirectURL:  "http://localhost:8080/api/v1/oidc/mock/callback",
+		Scopes:       []string{"openid", "email"},
+	}, nil)
+	handler := handlers.NewOIDCHandler(testDB(t), nil, auth.EmailVerificationOff, map[string]*oidc.Provider{"mock": provider})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/oidc/:provider/link", func(c *gin.Context) { c.Set("user_id", user.ID) }, handler.Link)
+	router.GET("/oidc/:provider/callback", handler.Callback)
+
+	// link inicia um vínculo e retorna a URL de autorização e o cookie definido
+	link := func() (string, *http.Cookie) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/oidc/mock/link", nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Status code esperado %d, recebido %d", http.StatusOK, recorder.Code)
+		}
+
+		var resp struct {
+			AuthorizationURL string `json:"authorization_url"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Erro ao decodificar resposta: %v", err)
+		}
+		for _, cookie := range recorder.Result().Cookies() {
+			if cookie.Name == "life_oidc_link" {
+				return resp.AuthorizationURL, cookie
+			}
+		}
+		t.Fatal("Cookie do vínculo não definido")
+		return "", nil
+	}
+
+	// callback conclui a autorização, enviando o cookie quando informado
+	callback := func(code, state string, cookie *http.Cookie) int {
+		req := httptest.NewRequest("GET", "/oidc/mock/callback?"+url.Values{"code": {code}, "state": {state}}.Encode(), nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	subject := fmt.Sprintf("vitima_%d", time.Now().UnixNano())
+
+	// 1. O cookie do vínculo é HttpOnly
+	authorizationURL, cookie := link()
+	if !cookie.HttpOnly || cookie.Value == "" {
+		t.Errorf("Cookie inesperado: %+v", cookie)
+	}
+
+	// 2. A URL aberta em outro navegador, sem o cookie, é recusada
+	code, state := mock.authorize(t, authorizationURL, subject)
+	if status := callback(code, state, nil); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+
+	// 3. O cookie de outro vínculo também é recusado
+	authorizationURL, _ = link()
+	_, otherCookie := link()
+	code, state = mock.authorize(t, authorizationURL, subject)
+	if status := callback(code, state, otherCookie); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+
+	// 4. No navegador que iniciou o vínculo, a identidade é vinculada
+	authorizationURL, cookie = link()
+	code, state = mock.authorize(t, authorizationURL, subject)
+	if status := callback(code, state, cookie); status != http.StatusCreated {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusCreated, status)
+	}
+}
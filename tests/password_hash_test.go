@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"life/auth"
+	"life/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestPasswordHasher testa os hashes argon2id e bcrypt e a indicação de recálculo
+func TestPasswordHasher(t *testing.T) {
+	hasher := auth.DefaultPasswordHasher()
+
+	// 1. Novos hashes usam argon2id no formato PHC
+	hash, err := hasher.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Erro ao gerar hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") || strings.Count(hash, "$") != 5 {
+		t.Errorf("Hash fora do formato PHC: %s", hash)
+	}
+	if other, _ := hasher.Hash(testPassword); other == hash {
+		t.Error("Hashes da mesma senha deveriam usar salts diferentes")
+	}
+
+	// 2. A senha correta confere sem recálculo; a incorreta não confere
+	if ok, needsRehash, err := hasher.Verify(testPassword, hash); !ok || needsRehash || err != nil {
+		t.Errorf("Verificação inesperada: ok=%v rehash=%v err=%v", ok, needsRehash, err)
+	}
+	if ok, _, err := hasher.Verify("Outra-Senha-2024", hash); ok || err != nil {
+		t.Errorf("Senha incorreta não deveria conferir: ok=%v err=%v", ok, err)
+	}
+
+	// 3. Hashes bcrypt antigos continuam aceitos e são indicados para recálculo
+	legacy, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Erro ao gerar hash bcrypt: %v", err)
+	}
+	if ok, needsRehash, err := hasher.Verify(testPassword, string(legacy)); !ok || !needsRehash || err != nil {
+		t.Errorf("Hash bcrypt deveria conferir e ser recalculado: ok=%v rehash=%v err=%v", ok, needsRehash, err)
+	}
+	if ok, needsRehash, _ := hasher.Verify("Outra-Senha-2024", string(legacy)); ok || needsRehash {
+		t.Error("Senha incorreta não deveria conferir nem indicar recálculo")
+	}
+
+	// 4. Parâmetros do argon2id diferentes dos configurados exigem recálculo
+	stronger := auth.DefaultPasswordHasher()
+	stronger.Argon2.Iterations = 3
+	if ok, needsRehash, _ := stronger.Verify(testPassword, hash); !ok || !needsRehash {
+		t.Errorf("Hash com parâmetros antigos deveria ser recalculado: ok=%v rehash=%v", ok, needsRehash)
+	}
+
+	// 5. Com bcrypt configurado, o custo também é comparado
+	bcryptHasher := auth.DefaultPasswordHasher()
+	bcryptHasher.Algorithm = auth.PasswordHashBcrypt
+	bcryptHasher.BcryptCost = bcrypt.MinCost
+	if ok, needsRehash, _ := bcryptHasher.Verify(testPassword, string(legacy)); !ok || needsRehash {
+		t.Errorf("Hash bcrypt atual não deveria ser recalculado: ok=%v rehash=%v", ok, needsRehash)
+	}
+	if ok, needsRehash, _ := bcryptHasher.Verify(testPassword, hash); !ok || !needsRehash {
+		t.Errorf("Hash argon2id deveria ser recalculado com bcrypt: ok=%v rehash=%v", ok, needsRehash)
+	}
+	bcryptHash, err := bcryptHasher.Hash(testPassword)
+	if err != nil || !strings.HasPrefix(bcryptHash, "$2a$04$") {
+		t.Errorf("Hash bcrypt inesperado: %s (%v)", bcryptHash, err)
+	}
+
+	// 6. Contas sem senha nunca conferem e formatos desconhecidos são recusados
+	if ok, _, err := hasher.Verify("", ""); ok || err != nil {
+		t.Errorf("Hash vazio não deveria conferir: ok=%v err=%v", ok, err)
+	}
+	for _, invalid := range []string{"senha-em-texto-puro", "$argon2id$v=19$m=19456,t=0,p=1$c2FsdA$aGFzaA", "$argon2id$v=16$m=19456,t=2,p=1$c2FsdA$aGFzaA"} {
+		if ok, _, err := hasher.Verify(testPassword, invalid); ok || err == nil {
+			t.Errorf("Hash %q deveria ser recusado", invalid)
+		}
+	}
+
+	// 7. Sem hash a verificar, o custo é o mesmo de uma verificação real
+	start := time.Now()
+	hasher.VerifyDummy(testPassword)
+	hasher.VerifyDummy(testPassword)
+	dummy := time.Since(start) / 2
+	start = time.Now()
+	hasher.Verify(testPassword, hash)
+	verify := time.Since(start)
+	if dummy < verify/4 {
+		t.Errorf("Verificação fictícia rápida demais: %v (real: %v)", dummy, verify)
+	}
+}
+
+// TestPasswordHasherFromEnv testa a configuração do algoritmo e dos parâmetros
+func TestPasswordHasherFromEnv(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "")
+	t.Setenv("PASSWORD_ARGON2_MEMORY", "65536")
+	t.Setenv("PASSWORD_ARGON2_ITERATIONS", "3")
+	t.Setenv("PASSWORD_ARGON2_PARALLELISM", "4")
+	t.Setenv("PASSWORD_BCRYPT_COST", "")
+	hasher, err := auth.PasswordHasherFromEnv()
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	expected := auth.Argon2Params{Memory: 65536, Iterations: 3, Parallelism: 4}
+	if hasher.Algorithm != auth.PasswordHashArgon2id || hasher.Argon2 != expected {
+		t.Errorf("Hasher inesperado: %+v", hasher)
+	}
+
+	for name, value := range map[string]string{
+		"PASSWORD_HASH_ALGORITHM":    "md5",
+		"PASSWORD_ARGON2_ITERATIONS": "0",
+		"PASSWORD_BCRYPT_COST":       "99",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := auth.PasswordHasherFromEnv(); err == nil {
+				t.Errorf("Valor %q deveria ser recusado", value)
+			}
+		})
+	}
+}
+
+// TestPasswordSettingsFromEnv testa o tamanho máximo das senhas com bcrypt
+func TestPasswordSettingsFromEnv(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "bcrypt")
+	t.Setenv("PASSWORD_MAX_LENGTH", "")
+	t.Setenv("PASSWORD_BREACHED_PATH", "off")
+
+	// 1. Sem PASSWORD_MAX_LENGTH, o máximo padrão passa a ser o limite do bcrypt
+	policy, hasher, err := config.PasswordSettingsFromEnv()
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if hasher.Algorithm != auth.PasswordHashBcrypt || policy.MaxLength != auth.BcryptMaxPasswordLength {
+		t.Errorf("Configuração inesperada: algoritmo %q, máximo %d", hasher.Algorithm, policy.MaxLength)
+	}
+
+	// 2. Um máximo explícito acima do limite é recusado
+	t.Setenv("PASSWORD_MAX_LENGTH", "100")
+	if _, _, err := config.PasswordSettingsFromEnv(); err == nil {
+		t.Error("PASSWORD_MAX_LENGTH acima de 72 deveria ser recusado com bcrypt")
+	}
+
+	// 3. Com argon2id, o máximo padrão é mantido
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "")
+	t.Setenv("PASSWORD_MAX_LENGTH", "")
+	if policy, _, err := config.PasswordSettingsFromEnv(); err != nil || policy.MaxLength != 128 {
+		t.Errorf("Máximo esperado 128, recebido %v (%v)", policy, err)
+	}
+}
@@ -0,0 +1,280 @@
+package tests
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"life/auth"
+	"life/models"
+	"life/validator"
+
+	"gorm.io/gorm"
+)
+
+// TestPasswordPolicy testa os requisitos de tamanho, classes de caracteres e o corpus de senhas vazadas
+func TestPasswordPolicy(t *testing.T) {
+	policy := validator.DefaultPasswordPolicy()
+
+	// 1. Requisitos da política padrão
+	cases := []struct {
+		password string
+		err      error
+	}{
+		{testPassword, nil},
+		{"Curta-1", validator.ErrPasswordTooShort},
+		{strings.Repeat("Aa1", 50), validator.ErrPasswordTooLong},
+		{"sem-numeros-Aqui", validator.ErrPasswordNoNumber},
+		{"sem-maiusculas-1", validator.ErrPasswordNoUppercase},
+		{"SEM-MINUSCULAS-1", validator.ErrPasswordNoLowercase},
+		{"Password123", validator.ErrPasswordBreached},
+		{"Senha12345", validator.ErrPasswordBreached},
+	}
+	for _, tc := range cases {
+		if err := policy.Validate(tc.password); !errors.Is(err, tc.err) {
+			t.Errorf("Senha %q: erro esperado %v, recebido %v", tc.password, tc.err, err)
+		}
+	}
+
+	// 2. O tamanho mínimo conta caracteres e o máximo conta bytes
+	if err := policy.Validate("Ação-Água1"); err != nil {
+		t.Errorf("Senha com acentos deveria ser aceita: %v", err)
+	}
+
+	// 3. Corpus em arquivo, com ou sem contagem de ocorrências
+	hash := func(password string) string {
+		sum := sha1.Sum([]byte(password))
+		return strings.ToUpper(hex.EncodeToString(sum[:]))
+	}
+	dir := t.TempDir()
+	corpusFile := filepath.Join(dir, "corpus.txt")
+	corpus := fmt.Sprintf("# corpus de teste\n%s:42\n%s\n", hash("Vazada-Numero-1"), strings.ToLower(hash("Vazada-Numero-2")))
+	if err := os.WriteFile(corpusFile, []byte(corpus), 0o600); err != nil {
+		t.Fatalf("Erro ao gravar corpus: %v", err)
+	}
+	breached, err := validator.OpenBreachedPasswords(corpusFile)
+	if err != nil {
+		t.Fatalf("Erro ao abrir corpus: %v", err)
+	}
+	for password, expected := range map[string]bool{"Vazada-Numero-1": true, "Vazada-Numero-2": true, testPassword: false} {
+		if found, err := breached.Contains(password); err != nil || found != expected {
+			t.Errorf("Senha %q: esperado %v, recebido %v (%v)", password, expected, found, err)
+		}
+	}
+
+	// 4. Diretório com um arquivo por faixa (prefixo de 5 caracteres do SHA-1)
+	rangeDir := filepath.Join(dir, "faixas")
+	if err := os.Mkdir(rangeDir, 0o700); err != nil {
+		t.Fatalf("Erro ao criar diretório: %v", err)
+	}
+	leaked := hash("Vazada-Numero-3")
+	if err := os.WriteFile(filepath.Join(rangeDir, leaked[:5]+".txt"), []byte(leaked[5:]+":7\n"), 0o600); err != nil {
+		t.Fatalf("Erro ao gravar faixa: %v", err)
+	}
+	breached, err = validator.OpenBreachedPasswords(rangeDir)
+	if err != nil {
+		t.Fatalf("Erro ao abrir corpus: %v", err)
+	}
+	for password, expected := range map[string]bool{"Vazada-Numero-3": true, testPassword: false} {
+		if found, err := breached.Contains(password); err != nil || found != expected {
+			t.Errorf("Senha %q: esperado %v, recebido %v (%v)", password, expected, found, err)
+		}
+	}
+
+	// 5. Corpus com linhas inválidas é recusado
+	if err := os.WriteFile(corpusFile, []byte("não é um hash\n"), 0o600); err != nil {
+		t.Fatalf("Erro ao gravar corpus: %v", err)
+	}
+	if _, err := validator.OpenBreachedPasswords(corpusFile); err == nil {
+		t.Error("Corpus inválido deveria ser recusado")
+	}
+}
+
+// TestPasswordPolicyFromEnv testa a configuração da política de senhas
+func TestPasswordPolicyFromEnv(t *testing.T) {
+	t.Setenv("PASSWORD_MIN_LENGTH", "12")
+	t.Setenv("PASSWORD_MAX_LENGTH", "")
+	t.Setenv("PASSWORD_REQUIRE", "lowercase,special")
+	t.Setenv("PASSWORD_BREACHED_PATH", "off")
+	policy, err := validator.PasswordPolicyFromEnv()
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if policy.MinLength != 12 || policy.RequireUppercase || policy.RequireNumber || !policy.RequireLowercase || !policy.RequireSpecial || policy.Breached != nil {
+		t.Errorf("Política inesperada: %+v", policy)
+	}
+	if err := policy.Validate("password-comum"); err != nil {
+		t.Errorf("Senha deveria ser aceita sem o corpus: %v", err)
+	}
+
+	for name, value := range map[string]string{
+		"PASSWORD_MIN_LENGTH":    "zero",
+		"PASSWORD_REQUIRE":       "emoji",
+		"PASSWORD_BREACHED_PATH": filepath.Join(t.TempDir(), "inexistente.txt"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := validator.PasswordPolicyFromEnv(); err == nil {
+				t.Errorf("Valor %q deveria ser recusado", value)
+			}
+		})
+	}
+}
+
+// TestRegisterPasswordPolicy testa a aplicação da política de senhas no registro
+func TestRegisterPasswordPolicy(t *testing.T) {
+	setupTest(t)
+	timestamp := time.Now().Format("20060102150405")
+
+	for _, password := range []string{"senha123", "Password123"} {
+		data := map[string]string{
+			"username":     fmt.Sprintf("weak_user_%s", timestamp),
+			"password":     password,
+			"display_name": "Usuário Teste",
+			"email":        fmt.Sprintf("weak_%s@example.com", timestamp),
+		}
+		status, body := testJSONRequest(t, "POST", "/register", "", data)
+		if status != http.StatusBadRequest {
+			t.Fatalf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+		}
+
+		var response struct {
+			Policy map[string]interface{} `json:"policy"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil || response.Policy["min_length"] == nil {
+			t.Errorf("A resposta deveria descrever a política: %s", body)
+		}
+	}
+}
+
+// TestPasswordReset testa a redefinição de senha pelo link enviado por email
+func TestPasswordReset(t *testing.T) {
+	setupTest(t)
+	// 1. Registro e login antes da redefinição
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	loginResp := testLogin(t, user.Username, testPassword)
+	if loginResp == nil {
+		t.Fatal("Falha no login")
+	}
+
+	// 2. Solicitação responde 202 para emails cadastrados ou não
+	for _, email := range []string{user.Email, "desconhecido@example.com"} {
+		if status, _ := testJSONRequest(t, "POST", "/password/forgot", "", map[string]string{"email": email}); status != http.StatusAccepted {
+			t.Errorf("Status code esperado %d, recebido %d", http.StatusAccepted, status)
+		}
+	}
+
+	token := testMailToken(t, user.Email, "reset-password")
+	if token == "" {
+		t.Fatal("Email de redefinição não encontrado")
+	}
+
+	// 3. A nova senha precisa atender à política, sem consumir o token
+	if status, _ := testJSONRequest(t, "POST", "/password/reset", "", map[string]string{"token": token, "password": "curta"}); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+
+	// 4. Redefinição com o token recebido
+	resetData := map[string]string{"token": token, "password": "Nova-Senha-Forte-2025"}
+	if status, _ := testJSONRequest(t, "POST", "/password/reset", "", resetData); status != http.StatusNoContent {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusNoContent, status)
+	}
+
+	// 5. O token é de uso único
+	if status, _ := testJSONRequest(t, "POST", "/password/reset", "", resetData); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+
+	// 6. Sessões anteriores são encerradas e apenas a nova senha é aceita
+	if status := testRefreshTokenStatus(t, loginResp.RefreshToken); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+	if status := testAuthorizedStatus(t, "GET", "/profile", loginResp.AccessToken); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+	if status, _ := testJSONRequest(t, "POST", "/login", "", map[string]string{"username": user.Username, "password": testPassword}); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+	if testLogin(t, user.Username, "Nova-Senha-Forte-2025") == nil {
+		t.Error("Login com a nova senha falhou")
+	}
+}
+
+// TestChangePassword testa a alteração de senha pelo usuário autenticado
+func TestChangePassword(t *testing.T) {
+	setupTest(t)
+	// 1. Registro e duas sessões (dispositivo atual e outro dispositivo)
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	current := testLogin(t, user.Username, testPassword)
+	other := testLogin(t, user.Username, testPassword)
+	legacy := testLogin(t, user.Username, testPassword)
+	if current == nil || other == nil || legacy == nil {
+		t.Fatal("Falha no login")
+	}
+
+	// Sessões criadas antes das famílias de tokens não têm family_id
+	if err := testDB(t).Model(&models.RefreshToken{}).
+		Where("token_hash = ?", auth.HashSecret(legacy.RefreshToken)).
+		Update("family_id", gorm.Expr("NULL")).Error; err != nil {
+		t.Fatalf("Erro ao simular sessão legada: %v", err)
+	}
+
+	// 2. A senha atual é obrigatória e a nova precisa atender à política
+	if status, _ := testJSONRequest(t, "PUT", "/profile/password", current.AccessToken, map[string]string{"current_password": "Senha-Errada-2024", "new_password": "Nova-Senha-Forte-2025"}); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+	if status, _ := testJSONRequest(t, "PUT", "/profile/password", current.AccessToken, map[string]string{"current_password": testPassword, "new_password": "curta"}); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+	if status, _ := testJSONRequest(t, "PUT", "/profile/password", current.AccessToken, map[string]string{"current_password": testPassword, "new_password": testPassword}); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+
+	// 3. Alteração com a senha atual
+	changeData := map[string]string{"current_password": testPassword, "new_password": "Nova-Senha-Forte-2025"}
+	if status, _ := testJSONRequest(t, "PUT", "/profile/password", current.AccessToken, changeData); status != http.StatusNoContent {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusNoContent, status)
+	}
+
+	// 4. A sessão atual continua ativa e pode ser renovada
+	if status := testAuthorizedStatus(t, "GET", "/profile", current.AccessToken); status != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	if testRefreshToken(t, current.RefreshToken) == nil {
+		t.Error("A sessão atual deveria continuar ativa")
+	}
+
+	// 5. As outras sessões são encerradas, inclusive as legadas
+	for _, session := range []*LoginResponse{other, legacy} {
+		if status := testAuthorizedStatus(t, "GET", "/profile", session.AccessToken); status != http.StatusUnauthorized {
+			t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+		}
+		if status := testRefreshTokenStatus(t, session.RefreshToken); status != http.StatusUnauthorized {
+			t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+		}
+	}
+
+	// 6. Apenas a nova senha é aceita no login
+	if status, _ := testJSONRequest(t, "POST", "/login", "", map[string]string{"username": user.Username, "password": testPassword}); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+	if testLogin(t, user.Username, "Nova-Senha-Forte-2025") == nil {
+		t.Error("Login com a nova senha falhou")
+	}
+}
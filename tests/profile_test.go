@@ -19,7 +19,7 @@ func TestProfileFlow(t *testing.T) {
 		t.Fatal("Falha no registro")
 	}
 
-	loginData := testLogin(t, user.Username, "senha123")
+	loginData := testLogin(t, user.Username, testPassword)
 	if loginData == nil {
 		t.Fatal("Falha no login")
 	}
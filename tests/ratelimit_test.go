@@ -0,0 +1,317 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"life/middleware"
+	"life/ratelimit"
+	"life/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestMemoryLimiter testa o token bucket em memória
+func TestMemoryLimiter(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter()
+	ctx := context.Background()
+	window := 200 * time.Millisecond
+
+	// 1. O limite é respeitado mesmo com requisições concorrentes
+	var allowed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := limiter.Allow(ctx, "concorrente", 10, time.Minute)
+			if err != nil {
+				t.Errorf("Erro inesperado: %v", err)
+			}
+			if result.Allowed {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 10 {
+		t.Errorf("Requisições permitidas esperadas 10, recebido %d", allowed)
+	}
+
+	// 2. Requisição bloqueada informa quando tentar novamente
+	result, _ := limiter.Allow(ctx, "concorrente", 10, time.Minute)
+	if result.Allowed || result.Remaining != 0 || result.RetryAfter <= 0 || result.RetryAfter > 6*time.Second {
+		t.Errorf("Resultado inesperado: %+v", result)
+	}
+
+	// 3. As fichas são repostas ao longo da janela
+	for i := 0; i < 2; i++ {
+		if result, _ := limiter.Allow(ctx, "reposicao", 2, window); !result.Allowed {
+			t.Fatalf("Requisição %d deveria ser permitida", i+1)
+		}
+	}
+	if result, _ := limiter.Allow(ctx, "reposicao", 2, window); result.Allowed {
+		t.Error("Terceira requisição deveria ser bloqueada")
+	}
+	time.Sleep(window)
+	if result, _ := limiter.Allow(ctx, "reposicao", 2, window); !result.Allowed || result.Remaining != 1 {
+		t.Errorf("Resultado inesperado após a reposição: %+v", result)
+	}
+
+	// 4. Chaves são independentes e limites não positivos bloqueiam tudo
+	if result, _ := limiter.Allow(ctx, "outra", 2, window); !result.Allowed {
+		t.Error("Chave nova deveria ser permitida")
+	}
+	if result, _ := limiter.Allow(ctx, "zerado", 0, window); result.Allowed {
+		t.Error("Limite zero deveria bloquear")
+	}
+}
+
+// TestRateLimiterFromEnv testa a seleção do backend do limitador
+func TestRateLimiterFromEnv(t *testing.T) {
+	t.Setenv("RATE_LIMIT_BACKEND", "")
+	if limiter, err := ratelimit.NewFromEnv(nil); err != nil {
+		t.Errorf("Erro inesperado: %v", err)
+	} else if _, ok := limiter.(*ratelimit.MemoryLimiter); !ok {
+		t.Errorf("Backend padrão deveria ser memory, recebido %T", limiter)
+	}
+
+	t.Setenv("RATE_LIMIT_BACKEND", "postgres")
+	if limiter, err := ratelimit.NewFromEnv(nil); err != nil {
+		t.Errorf("Erro inesperado: %v", err)
+	} else if _, ok := limiter.(*ratelimit.PostgresLimiter); !ok {
+		t.Errorf("Backend esperado postgres, recebido %T", limiter)
+	}
+
+	t.Setenv("RATE_LIMIT_BACKEND", "redis")
+	if _, err := ratelimit.NewFromEnv(nil); err == nil {
+		t.Error("Backend desconhecido deveria ser recusado")
+	}
+}
+
+// TestAPIKeyRateLimit testa o bloqueio de uma API key que excede o limite
+func TestAPIKeyRateLimit(t *testing.T) {
+	setupTest(t)
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	loginData := testLogin(t, user.Username, testPassword)
+	if loginData == nil {
+		t.Fatal("Falha no login")
+	}
+
+	data := map[string]interface{}{
+		"name":       "Chave limitada",
+		"expires_at": "2099-12-31T23:59:59Z",
+		"rate_limit": 2,
+		"scopes":     []string{"profile:read"},
+	}
+	status, body := testJSONRequest(t, "POST", "/api-keys", loginData.AccessToken, data)
+	if status != http.StatusCreated {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusCreated, status)
+	}
+	var key APIKey
+	if err := json.Unmarshal(body, &key); err != nil {
+		t.Fatalf("Erro ao decodificar resposta: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if status, _ := testAPIKeyRequest(t, "/integrations/profile", key.Key); status != http.StatusOK {
+			t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("%s/integrations/profile", baseURL), nil)
+	req.Header.Set("X-API-Key", key.Key)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Erro na requisição: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("Esperado 429 com Retry-After, recebido %d %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	if resp.Header.Get("RateLimit-Limit") != "2" || resp.Header.Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Cabeçalhos RateLimit inesperados: %v", resp.Header)
+	}
+}
+
+// TestRateLimitRoutes testa as políticas por rota e os cabeçalhos RateLimit-*
+func TestRateLimitRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policies := map[string]middleware.RateLimitPolicy{
+		"POST /register": {Name: "register", Limit: 2, Window: time.Hour, By: middleware.RateLimitByIP},
+		"PUT /profile":   {Name: "profile", Limit: 1, Window: time.Minute, By: middleware.RateLimitByUser},
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if userID := c.GetHeader("X-Test-User"); userID != "" {
+			id, _ := strconv.Atoi(userID)
+			c.Set("user_id", uint(id))
+		}
+	}, middleware.RateLimitRoutes(ratelimit.NewMemoryLimiter(), policies))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/register", ok)
+	router.PUT("/profile", ok)
+	router.GET("/profile", ok)
+
+	request := func(method, path, ip, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		if userID != "" {
+			req.Header.Set("X-Test-User", userID)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// 1. Respostas limitadas informam o limite, o restante e o reinício
+	resp := request("POST", "/register", "10.0.0.1", "")
+	if resp.Code != http.StatusOK || resp.Header().Get("RateLimit-Limit") != "2" || resp.Header().Get("RateLimit-Remaining") != "1" {
+		t.Errorf("Resposta inesperada: %d %v", resp.Code, resp.Header())
+	}
+	if resp.Header().Get("RateLimit-Reset") == "" || resp.Header().Get("RateLimit-Policy") != "2;w=3600" {
+		t.Errorf("Cabeçalhos inesperados: %v", resp.Header())
+	}
+
+	// 2. Excedido o limite, a resposta é 429 com Retry-After
+	request("POST", "/register", "10.0.0.1", "")
+	resp = request("POST", "/register", "10.0.0.1", "")
+	if resp.Code != http.StatusTooManyRequests || resp.Header().Get("Retry-After") == "" || resp.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Esperado 429 com Retry-After, recebido %d %v", resp.Code, resp.Header())
+	}
+
+	// 3. Outro IP tem seu próprio limite
+	if resp := request("POST", "/register", "10.0.0.2", ""); resp.Code != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, resp.Code)
+	}
+
+	// 4. Rotas autenticadas são limitadas por usuário, mesmo a partir do mesmo IP
+	if resp := request("PUT", "/profile", "10.0.0.3", "1"); resp.Code != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, resp.Code)
+	}
+	if resp := request("PUT", "/profile", "10.0.0.3", "1"); resp.Code != http.StatusTooManyRequests {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusTooManyRequests, resp.Code)
+	}
+	if resp := request("PUT", "/profile", "10.0.0.3", "2"); resp.Code != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, resp.Code)
+	}
+
+	// 5. Rotas sem política não são limitadas nem recebem os cabeçalhos
+	if resp := request("GET", "/profile", "10.0.0.3", "1"); resp.Code != http.StatusOK || resp.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("Resposta inesperada: %d %v", resp.Code, resp.Header())
+	}
+}
+
+// TestRateLimitPoliciesFromEnv testa a configuração dos limites por rota
+func TestRateLimitPoliciesFromEnv(t *testing.T) {
+	t.Setenv("RATE_LIMIT_REGISTER", "10/30m")
+	policies, err := routes.RateLimitPoliciesFromEnv()
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if policy := policies["POST /api/v1/register"]; policy.Limit != 10 || policy.Window != 30*time.Minute {
+		t.Errorf("Política inesperada: %+v", policy)
+	}
+	if routes.DefaultRateLimitPolicies()["POST /api/v1/register"].Limit != 5 {
+		t.Error("As políticas padrão não devem ser alteradas")
+	}
+	if policy, ok := policies["DELETE /api/v1/profile"]; !ok || policy.By != middleware.RateLimitByUser {
+		t.Errorf("A exclusão da conta deveria ser limitada por usuário: %+v", policy)
+	}
+
+	for _, value := range []string{"10", "0/1h", "dez/1h", "10/uma hora"} {
+		t.Setenv("RATE_LIMIT_REGISTER", value)
+		if _, err := routes.RateLimitPoliciesFromEnv(); err == nil {
+			t.Errorf("Valor %q deveria ser recusado", value)
+		}
+	}
+}
+
+// TestRateLimitSpoofedForwardedFor testa que X-Forwarded-For só é aceito de proxies confiáveis
+func TestRateLimitSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policies := map[string]middleware.RateLimitPolicy{
+		"POST /register": {Name: "register", Limit: 2, Window: time.Hour, By: middleware.RateLimitByIP},
+	}
+
+	newRouter := func(trustedProxies []string) *gin.Engine {
+		router := routes.NewEngine(trustedProxies)
+		router.Use(middleware.RateLimitRoutes(ratelimit.NewMemoryLimiter(), policies))
+		router.POST("/register", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return router
+	}
+	request := func(router *gin.Engine, remoteIP, forwardedFor string) int {
+		req := httptest.NewRequest("POST", "/register", nil)
+		req.RemoteAddr = remoteIP + ":1234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.Header.Set("X-Real-IP", forwardedFor)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// 1. Sem proxies confiáveis, trocar o X-Forwarded-For não escapa do limite
+	router := newRouter(nil)
+	for i := 1; i <= 3; i++ {
+		expected := http.StatusOK
+		if i == 3 {
+			expected = http.StatusTooManyRequests
+		}
+		if status := request(router, "203.0.113.7", fmt.Sprintf("198.51.100.%d", i)); status != expected {
+			t.Errorf("Requisição %d: status code esperado %d, recebido %d", i, expected, status)
+		}
+	}
+
+	// 2. Atrás de um proxy confiável, o IP do cabeçalho identifica o cliente
+	router = newRouter([]string{"10.0.0.0/8"})
+	for i := 1; i <= 3; i++ {
+		if status := request(router, "10.0.0.1", fmt.Sprintf("198.51.100.%d", i)); status != http.StatusOK {
+			t.Errorf("Requisição %d: status code esperado %d, recebido %d", i, http.StatusOK, status)
+		}
+	}
+
+	// 3. Clientes fora da faixa confiável continuam limitados pelo próprio endereço
+	for i := 1; i <= 3; i++ {
+		expected := http.StatusOK
+		if i == 3 {
+			expected = http.StatusTooManyRequests
+		}
+		if status := request(router, "203.0.113.8", fmt.Sprintf("198.51.100.%d", 10+i)); status != expected {
+			t.Errorf("Requisição %d: status code esperado %d, recebido %d", i, expected, status)
+		}
+	}
+}
+
+// TestTrustedProxiesFromEnv testa a configuração dos proxies confiáveis
+func TestTrustedProxiesFromEnv(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "")
+	if proxies, err := routes.TrustedProxiesFromEnv(); err != nil || proxies != nil {
+		t.Errorf("Padrão esperado sem proxies, recebido %v (%v)", proxies, err)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.10,::1")
+	proxies, err := routes.TrustedProxiesFromEnv()
+	if err != nil || len(proxies) != 3 || proxies[1] != "192.168.1.10" {
+		t.Errorf("Proxies inesperados: %v (%v)", proxies, err)
+	}
+
+	for _, value := range []string{"proxy.interno", "10.0.0.0/99"} {
+		t.Setenv("TRUSTED_PROXIES", value)
+		if _, err := routes.TrustedProxiesFromEnv(); err == nil {
+			t.Errorf("Valor %q deveria ser recusado", value)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// Session representa uma sessão nos testes
+type Session struct {
+	ID         uint   `json:"id"`
+	DeviceName string `json:"device_name"`
+	Current    bool   `json:"current"`
+}
+
+// TestSessionFlow testa a listagem e o encerramento de sessões
+func TestSessionFlow(t *testing.T) {
+	setupTest(t)
+	// 1. Registro e login em dois dispositivos
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+
+	desktop := testLoginWithDevice(t, user.Username, testPassword, "Desktop")
+	console := testLoginWithDevice(t, user.Username, testPassword, "Console")
+	if desktop == nil || console == nil {
+		t.Fatal("Falha no login")
+	}
+
+	// 2. Listagem mostra as duas sessões, marcando a atual
+	sessions := testListSessions(t, desktop.AccessToken)
+	if len(sessions) != 2 {
+		t.Fatalf("Esperadas 2 sessões, recebidas %d", len(sessions))
+	}
+
+	var lost *Session
+	for i := range sessions {
+		if sessions[i].DeviceName == "Desktop" && !sessions[i].Current {
+			t.Error("A sessão do Desktop deveria ser a atual")
+		}
+		if sessions[i].DeviceName == "Console" {
+			lost = &sessions[i]
+		}
+	}
+	if lost == nil {
+		t.Fatal("Sessão do Console não encontrada")
+	}
+
+	// 3. Encerrar a sessão do Console invalida seus tokens
+	path := fmt.Sprintf("/sessions/%d", lost.ID)
+	if status := testAuthorizedStatus(t, "DELETE", path, desktop.AccessToken); status != http.StatusNoContent {
+		t.Fatalf("Status code esperado %d, recebido %d", http.StatusNoContent, status)
+	}
+	if status := testRefreshTokenStatus(t, console.RefreshToken); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+	if status := testAuthorizedStatus(t, "GET", "/profile", console.AccessToken); status != http.StatusUnauthorized {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusUnauthorized, status)
+	}
+
+	// 4. A sessão atual continua ativa
+	if sessions := testListSessions(t, desktop.AccessToken); len(sessions) != 1 {
+		t.Errorf("Esperada 1 sessão, recebidas %d", len(sessions))
+	}
+}
+
+// testLoginWithDevice testa o login informando o nome do dispositivo
+func testLoginWithDevice(t *testing.T, username, password, deviceName string) *LoginResponse {
+	url := fmt.Sprintf("%s/login", baseURL)
+
+	data := map[string]string{
+		"username":    username,
+		"password":    password,
+		"device_name": deviceName,
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		t.Errorf("Erro ao criar JSON: %v", err)
+		return nil
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		t.Errorf("Erro ao criar requisição: %v", err)
+		return nil
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Errorf("Erro na requisição: %v", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	// Log da resposta
+	body, _ := io.ReadAll(resp.Body)
+	t.Logf("Status code: %d", resp.StatusCode)
+	t.Logf("Resposta: %s", string(body))
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d. Resposta: %s", http.StatusOK, resp.StatusCode, string(body))
+		return nil
+	}
+
+	var loginData LoginResponse
+	if err := json.NewDecoder(bytes.NewBuffer(body)).Decode(&loginData); err != nil {
+		t.Errorf("Erro ao decodificar resposta: %v", err)
+		return nil
+	}
+
+	return &loginData
+}
+
+// testListSessions testa a listagem de sessões
+func testListSessions(t *testing.T, accessToken string) []Session {
+	url := fmt.Sprintf("%s/sessions", baseURL)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Errorf("Erro ao criar requisição: %v", err)
+		return nil
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Errorf("Erro na requisição: %v", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	// Log da resposta
+	body, _ := io.ReadAll(resp.Body)
+	t.Logf("Status code: %d", resp.StatusCode)
+	t.Logf("Resposta: %s", string(body))
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d. Resposta: %s", http.StatusOK, resp.StatusCode, string(body))
+		return nil
+	}
+
+	var sessions []Session
+	if err := json.NewDecoder(bytes.NewBuffer(body)).Decode(&sessions); err != nil {
+		t.Errorf("Erro ao decodificar resposta: %v", err)
+		return nil
+	}
+
+	return sessions
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"life/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TestKeySetRotation testa a verificação de tokens assinados por chaves rotacionadas
+func TestKeySetRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	// Chave antiga mantida apenas como pública, chave nova RSA ativa
+	_, oldPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Erro ao gerar chave Ed25519: %v", err)
+	}
+	newPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Erro ao gerar chave RSA: %v", err)
+	}
+
+	writePEM(t, filepath.Join(dir, "old-private.pem"), "PRIVATE KEY", mustMarshalPKCS8(t, oldPrivate))
+	oldKeys, err := auth.LoadKeySetFromDir(dir, "")
+	if err != nil {
+		t.Fatalf("Erro ao carregar chaves antigas: %v", err)
+	}
+
+	claims := jwt.MapClaims{"user_id": 1, "exp": time.Now().Add(time.Minute).Unix()}
+	oldToken, err := oldKeys.Sign(claims)
+	if err != nil {
+		t.Fatalf("Erro ao assinar token: %v", err)
+	}
+
+	// Rotação: a chave antiga vira apenas pública e a nova assume a assinatura
+	os.Remove(filepath.Join(dir, "old-private.pem"))
+	publicDER, err := x509.MarshalPKIXPublicKey(oldPrivate.Public())
+	if err != nil {
+		t.Fatalf("Erro ao serializar chave pública: %v", err)
+	}
+	writePEM(t, filepath.Join(dir, "old-private.pem"), "PUBLIC KEY", publicDER)
+	writePEM(t, filepath.Join(dir, "new.pem"), "PRIVATE KEY", mustMarshalPKCS8(t, newPrivate))
+
+	keys, err := auth.LoadKeySetFromDir(dir, "")
+	if err != nil {
+		t.Fatalf("Erro ao carregar chaves rotacionadas: %v", err)
+	}
+
+	newToken, err := keys.Sign(claims)
+	if err != nil {
+		t.Fatalf("Erro ao assinar token: %v", err)
+	}
+
+	for name, tokenString := range map[string]string{"antigo": oldToken, "novo": newToken} {
+		token, err := jwt.Parse(tokenString, keys.Keyfunc, jwt.WithValidMethods(auth.SupportedMethods))
+		if err != nil || !token.Valid {
+			t.Errorf("Token %s deveria ser válido após a rotação: %v", name, err)
+		}
+	}
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("Erro ao ler token: %v", err)
+	}
+	if parsed.Header["kid"] != "new" || parsed.Method.Alg() != "RS256" {
+		t.Errorf("Header inesperado: %v", parsed.Header)
+	}
+
+	jwks := keys.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("JWKS deveria conter 2 chaves, contém %d", len(jwks.Keys))
+	}
+	for _, key := range jwks.Keys {
+		if key.Kid == "new" && (key.Kty != "RSA" || key.N == "" || key.E == "") {
+			t.Errorf("JWK RSA inválida: %+v", key)
+		}
+		if key.Kid == "old-private" && (key.Kty != "OKP" || key.Crv != "Ed25519" || key.X == "") {
+			t.Errorf("JWK Ed25519 inválida: %+v", key)
+		}
+	}
+}
+
+// TestTokenService testa a emissão e a validação dos access tokens
+func TestTokenService(t *testing.T) {
+	keys, err := auth.NewEphemeralKeySet()
+	if err != nil {
+		t.Fatalf("Erro ao gerar chaves: %v", err)
+	}
+
+	config := auth.TokenConfig{AccessTTL: 15 * time.Minute, Issuer: "life-test", Audience: "life-game"}
+	tokens := auth.NewTokenService(keys, config, nil)
+
+	accessToken, err := tokens.Issue(42)
+	if err != nil {
+		t.Fatalf("Erro ao emitir token: %v", err)
+	}
+	if accessToken.ExpiresIn() != 900 {
+		t.Errorf("ExpiresIn esperado 900, recebido %d", accessToken.ExpiresIn())
+	}
+
+	claims, err := tokens.Validate(accessToken.Token)
+	if err != nil {
+		t.Fatalf("Token deveria ser válido: %v", err)
+	}
+	if claims.UserID != 42 || claims.ID != accessToken.ID || claims.IssuedAt == nil || claims.NotBefore == nil {
+		t.Errorf("Claims inesperadas: %+v", claims)
+	}
+
+	// Emissor e público diferentes devem ser rejeitados
+	other := auth.NewTokenService(keys, auth.TokenConfig{AccessTTL: time.Minute, Issuer: "life-test", Audience: "outro"}, nil)
+	if _, err := other.Validate(accessToken.Token); err == nil {
+		t.Error("Token com público diferente deveria ser rejeitado")
+	}
+
+	// Tokens expirados devem ser rejeitados
+	expired := auth.NewTokenService(keys, auth.TokenConfig{AccessTTL: -time.Minute, Issuer: "life-test", Audience: "life-game"}, nil)
+	expiredToken, err := expired.Issue(42)
+	if err != nil {
+		t.Fatalf("Erro ao emitir token: %v", err)
+	}
+	if _, err := tokens.Validate(expiredToken.Token); err == nil {
+		t.Error("Token expirado deveria ser rejeitado")
+	}
+}
+
+// writePEM grava um bloco PEM em disco
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Erro ao gravar %s: %v", path, err)
+	}
+}
+
+// mustMarshalPKCS8 serializa uma chave privada em PKCS#8
+func mustMarshalPKCS8(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Erro ao serializar chave privada: %v", err)
+	}
+	return der
+}
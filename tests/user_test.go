@@ -6,11 +6,17 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"life/auth"
+	"life/middleware"
 	"life/models"
+
+	"github.com/gin-gonic/gin"
 )
 
 // TestUserModel testa o modelo de usuário
@@ -59,7 +65,7 @@ func TestUserFlow(t *testing.T) {
 		t.Fatal("Falha no registro")
 	}
 
-	loginData := testLogin(t, user.Username, "senha123")
+	loginData := testLogin(t, user.Username, testPassword)
 	if loginData == nil {
 		t.Fatal("Falha no login")
 	}
@@ -76,8 +82,15 @@ func TestUserFlow(t *testing.T) {
 		t.Fatal("Falha ao atualizar usuário")
 	}
 
-	// 4. Listar usuários
-	users := testListUsers(t, loginData.AccessToken)
+	// 4. Listar usuários é restrito a administradores
+	if status := testAuthorizedStatus(t, "GET", "/users", loginData.AccessToken); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+	_, adminLogin := testRegisterAdmin(t)
+	if adminLogin == nil {
+		t.Fatal("Falha no registro do administrador")
+	}
+	users := testListUsers(t, adminLogin.AccessToken)
 	if users == nil {
 		t.Fatal("Falha ao listar usuários")
 	}
@@ -88,6 +101,166 @@ func TestUserFlow(t *testing.T) {
 	}
 }
 
+// TestRoles testa as permissões dos papéis e a configuração de ADMIN_USERS
+func TestRoles(t *testing.T) {
+	if !auth.RoleHasPermission(auth.RoleAdmin, auth.PermissionUsersList) || auth.RoleHasPermission(auth.RoleUser, auth.PermissionUsersList) {
+		t.Error("Apenas administradores devem listar usuários")
+	}
+	if !auth.ValidRole(auth.RoleUser) || auth.ValidRole("superuser") || auth.ValidRole(auth.RoleClient) {
+		t.Error("Validação de papéis inesperada")
+	}
+
+	t.Setenv("ADMIN_USERS", " Ana@Empresa.com, @admin.example.com ")
+	cases := []struct {
+		email    string
+		verified bool
+		role     string
+	}{
+		{"ana@empresa.com", true, auth.RoleAdmin},
+		{"ANA@empresa.com", true, auth.RoleAdmin},
+		{"ana@empresa.com", false, auth.RoleUser},
+		{"bia@admin.example.com", true, auth.RoleAdmin},
+		{"bia@notadmin.example.com", true, auth.RoleUser},
+		{"bia@empresa.com", true, auth.RoleUser},
+	}
+	for _, tc := range cases {
+		if role := auth.EffectiveRole(auth.RoleUser, tc.email, tc.verified); role != tc.role {
+			t.Errorf("Papel esperado %s para %s (verificado: %v), recebido %s", tc.role, tc.email, tc.verified, role)
+		}
+	}
+}
+
+// TestClientCredentialsAuthorization testa que tokens client_credentials
+// recebem o papel fixo de cliente, sem nenhuma permissão
+func TestClientCredentialsAuthorization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// Simula o AuthMiddleware com um token client_credentials (sem usuário);
+	// o papel do cliente não depende do banco de dados
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(0))
+		c.Set("client_id", "cliente-de-teste")
+		c.Next()
+	})
+	router.GET("/users", middleware.Authorize(nil, middleware.HasPermission(auth.PermissionUsersList)), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/users/:id", middleware.Authorize(nil, middleware.SelfOrPermission("id", auth.PermissionUsersRead)), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/public", middleware.Authorize(nil, func(c *gin.Context, subject middleware.Subject) bool { return true }), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(middleware.RoleKey))
+	})
+
+	// 1. Rotas que exigem permissão são recusadas, inclusive com o ID zero
+	for _, path := range []string{"/users", "/users/1", "/users/0"} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Status code esperado %d em %s, recebido %d", http.StatusForbidden, path, recorder.Code)
+		}
+	}
+
+	// 2. Nas demais rotas o papel efetivo é o de cliente
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/public", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != auth.RoleClient {
+		t.Errorf("Papel esperado %s, recebido %d %q", auth.RoleClient, recorder.Code, recorder.Body.String())
+	}
+}
+
+// TestUserAuthorization testa o acesso aos dados de outros usuários conforme o papel
+func TestUserAuthorization(t *testing.T) {
+	setupTest(t)
+	user := testRegister(t)
+	if user == nil {
+		t.Fatal("Falha no registro")
+	}
+	loginData := testLogin(t, user.Username, testPassword)
+	if loginData == nil {
+		t.Fatal("Falha no login")
+	}
+	time.Sleep(time.Second)
+	other := testRegister(t)
+	if other == nil {
+		t.Fatal("Falha no registro")
+	}
+	otherPath := fmt.Sprintf("/users/%d", other.ID)
+	update := map[string]string{"display_name": "Alterado", "email": fmt.Sprintf("alterado_%d@example.com", time.Now().UnixNano())}
+
+	// 1. Usuários comuns não acessam nem alteram outros usuários
+	if status := testAuthorizedStatus(t, "GET", otherPath, loginData.AccessToken); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+	if status, _ := testJSONRequest(t, "PUT", otherPath, loginData.AccessToken, update); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+	if status, _ := testJSONRequest(t, "PUT", otherPath+"/role", loginData.AccessToken, map[string]string{"role": "admin"}); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+
+	// 2. Administradores acessam e alteram qualquer usuário
+	admin, adminLogin := testRegisterAdmin(t)
+	if adminLogin == nil {
+		t.Fatal("Falha no registro do administrador")
+	}
+	if status := testAuthorizedStatus(t, "GET", otherPath, adminLogin.AccessToken); status != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+	if status, _ := testJSONRequest(t, "PUT", otherPath, adminLogin.AccessToken, update); status != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+
+	// 3. Papéis inválidos e a alteração do próprio papel são recusados
+	if status, _ := testJSONRequest(t, "PUT", otherPath+"/role", adminLogin.AccessToken, map[string]string{"role": "superuser"}); status != http.StatusBadRequest {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusBadRequest, status)
+	}
+	if status, _ := testJSONRequest(t, "PUT", fmt.Sprintf("/users/%d/role", admin.ID), adminLogin.AccessToken, map[string]string{"role": "user"}); status != http.StatusForbidden {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusForbidden, status)
+	}
+
+	// 4. A promoção vale imediatamente, sem novo login
+	status, body := testJSONRequest(t, "PUT", fmt.Sprintf("/users/%d/role", user.ID), adminLogin.AccessToken, map[string]string{"role": "admin"})
+	if status != http.StatusOK || !strings.Contains(string(body), `"role":"admin"`) {
+		t.Fatalf("Resposta inesperada: %d %s", status, body)
+	}
+	if status := testAuthorizedStatus(t, "GET", "/users", loginData.AccessToken); status != http.StatusOK {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusOK, status)
+	}
+}
+
+// testRegisterAdmin registra um usuário com email em ADMIN_USERS, confirma o
+// email e faz login
+func testRegisterAdmin(t *testing.T) (*User, *LoginResponse) {
+	suffix := time.Now().UnixNano()
+	data := map[string]string{
+		"username":     fmt.Sprintf("test_admin_%d", suffix),
+		"password":     testPassword,
+		"display_name": "Administrador Teste",
+		"email":        fmt.Sprintf("admin_%d@admin.example.com", suffix),
+	}
+	status, body := testJSONRequest(t, "POST", "/register", "", data)
+	if status != http.StatusCreated {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusCreated, status)
+		return nil, nil
+	}
+
+	var user User
+	if err := json.Unmarshal(body, &user); err != nil {
+		t.Errorf("Erro ao decodificar resposta: %v", err)
+		return nil, nil
+	}
+
+	// O papel de ADMIN_USERS só vale com o email verificado
+	token := testMailToken(t, user.Email, "verify-email")
+	if status, _ := testJSONRequest(t, "POST", "/verify-email", "", map[string]string{"token": token}); status != http.StatusNoContent {
+		t.Errorf("Status code esperado %d, recebido %d", http.StatusNoContent, status)
+		return nil, nil
+	}
+
+	return &user, testLogin(t, user.Username, testPassword)
+}
+
 // testGetUser testa a obtenção de um usuário específico
 func testGetUser(t *testing.T, accessToken string, userID string) *User {
 	url := fmt.Sprintf("%s/users/%s", baseURL, userID)
@@ -0,0 +1,185 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"life/models"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// defaultFlushInterval define de quanto em quanto tempo o uso acumulado é gravado
+	defaultFlushInterval = 10 * time.Second
+
+	// maxPendingCounters antecipa a gravação quando há muitos contadores acumulados
+	maxPendingCounters = 1000
+)
+
+// counterKey agrupa as requisições de uma API key por dia, rota e status
+type counterKey struct {
+	apiKeyID uint
+	day      string
+	route    string
+	status   int
+}
+
+// Recorder acumula em memória o uso das API keys e grava os contadores em
+// lote, evitando uma escrita no banco a cada requisição
+type Recorder struct {
+	db       *gorm.DB
+	interval time.Duration
+
+	mu       sync.Mutex
+	counters map[counterKey]int64
+	lastUsed map[uint]time.Time
+
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// FlushIntervalFromEnv lê USAGE_FLUSH_INTERVAL (padrão: 10s)
+func FlushIntervalFromEnv() (time.Duration, error) {
+	value := os.Getenv("USAGE_FLUSH_INTERVAL")
+	if value == "" {
+		return defaultFlushInterval, nil
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("USAGE_FLUSH_INTERVAL inválido: %q", value)
+	}
+	return interval, nil
+}
+
+// NewRecorder cria um Recorder e inicia a gravação periódica em segundo plano
+func NewRecorder(db *gorm.DB, interval time.Duration) *Recorder {
+	r := &Recorder{
+		db:       db,
+		interval: interval,
+		counters: make(map[counterKey]int64),
+		lastUsed: make(map[uint]time.Time),
+		flush:    make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Record registra uma requisição feita com a API key
+func (r *Recorder) Record(apiKeyID uint, route string, status int, at time.Time) {
+	key := counterKey{apiKeyID: apiKeyID, day: at.UTC().Format(time.DateOnly), route: route, status: status}
+
+	r.mu.Lock()
+	r.counters[key]++
+	if at.After(r.lastUsed[apiKeyID]) {
+		r.lastUsed[apiKeyID] = at
+	}
+	pending := len(r.counters)
+	r.mu.Unlock()
+
+	if pending >= maxPendingCounters {
+		select {
+		case r.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Flush grava imediatamente o uso acumulado. Em caso de erro, os contadores
+// voltam ao buffer para a próxima tentativa.
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	counters, lastUsed := r.counters, r.lastUsed
+	r.counters, r.lastUsed = make(map[counterKey]int64), make(map[uint]time.Time)
+	r.mu.Unlock()
+
+	if len(counters) == 0 {
+		return nil
+	}
+
+	rows := make([]models.APIKeyUsage, 0, len(counters))
+	for key, count := range counters {
+		day, _ := time.Parse(time.DateOnly, key.day)
+		rows = append(rows, models.APIKeyUsage{APIKeyID: key.apiKeyID, Day: day, Route: key.route, Status: key.status, Count: count})
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "api_key_id"}, {Name: "day"}, {Name: "route"}, {Name: "status"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"count":      gorm.Expr("api_key_usages.count + EXCLUDED.count"),
+				"updated_at": gorm.Expr("EXCLUDED.updated_at"),
+			}),
+		}).Create(&rows).Error; err != nil {
+			return err
+		}
+
+		// O último uso nunca retrocede, mesmo com instâncias gravando fora de ordem
+		for apiKeyID, at := range lastUsed {
+			if err := tx.Model(&models.APIKey{}).
+				Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", apiKeyID, at).
+				Update("last_used_at", at).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		r.restore(counters, lastUsed)
+		return err
+	}
+	return nil
+}
+
+// Close interrompe a gravação periódica e grava o uso pendente
+func (r *Recorder) Close() error {
+	r.once.Do(func() { close(r.stop) })
+	<-r.done
+	return r.Flush(context.Background())
+}
+
+// run grava o uso acumulado a cada intervalo ou quando o buffer enche
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		case <-r.flush:
+		}
+
+		if err := r.Flush(context.Background()); err != nil {
+			log.Warn().Err(err).Msg("Falha ao gravar o uso das API keys; nova tentativa no próximo intervalo")
+		}
+	}
+}
+
+// restore devolve ao buffer os contadores que não puderam ser gravados
+func (r *Recorder) restore(counters map[counterKey]int64, lastUsed map[uint]time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, count := range counters {
+		r.counters[key] += count
+	}
+	for apiKeyID, at := range lastUsed {
+		if at.After(r.lastUsed[apiKeyID]) {
+			r.lastUsed[apiKeyID] = at
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package validator
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// breachedPrefixLength é o tamanho do prefixo do SHA-1 que identifica uma
+// faixa do corpus, como na API de faixas do Have I Been Pwned
+const breachedPrefixLength = 5
+
+//go:embed breached_passwords.txt
+var commonBreachedPasswords string
+
+// BreachedPasswords consulta senhas expostas em vazamentos de dados
+type BreachedPasswords interface {
+	Contains(password string) (bool, error)
+}
+
+// BreachedCorpus é um corpus de senhas vazadas mantido em memória e
+// organizado por faixas: o SHA-1 da senha é dividido em um prefixo de 5
+// caracteres e o sufixo restante, e a consulta compara apenas os sufixos da
+// faixa do prefixo. É a mesma estrutura de k-anonimato da API de faixas, o que
+// permite trocar o corpus local por uma consulta remota sem expor a senha.
+type BreachedCorpus struct {
+	ranges map[string]map[string]struct{}
+}
+
+var (
+	commonBreachedOnce   sync.Once
+	commonBreachedCorpus *BreachedCorpus
+)
+
+// CommonBreachedPasswords retorna o corpus embutido com as senhas mais comuns
+// em vazamentos
+func CommonBreachedPasswords() *BreachedCorpus {
+	commonBreachedOnce.Do(func() {
+		corpus, err := ReadBreachedCorpus(strings.NewReader(commonBreachedPasswords))
+		if err != nil {
+			panic(err)
+		}
+		commonBreachedCorpus = corpus
+	})
+	return commonBreachedCorpus
+}
+
+// ReadBreachedCorpus lê um corpus com um SHA-1 em hexadecimal por linha,
+// opcionalmente seguido de ":ocorrências". Linhas vazias e iniciadas por #
+// são ignoradas.
+func ReadBreachedCorpus(r io.Reader) (*BreachedCorpus, error) {
+	corpus := &BreachedCorpus{ranges: make(map[string]map[string]struct{})}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		hash, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if hash == "" || strings.HasPrefix(hash, "#") {
+			continue
+		}
+		if !validSHA1(hash) {
+			return nil, fmt.Errorf("linha %d do corpus de senhas vazadas não é um SHA-1: %q", line, hash)
+		}
+
+		hash = strings.ToUpper(hash)
+		prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+		if corpus.ranges[prefix] == nil {
+			corpus.ranges[prefix] = make(map[string]struct{})
+		}
+		corpus.ranges[prefix][suffix] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return corpus, nil
+}
+
+// Contains informa se a senha está no corpus
+func (b *BreachedCorpus) Contains(password string) (bool, error) {
+	prefix, suffix := breachedRange(password)
+	_, ok := b.ranges[prefix][suffix]
+	return ok, nil
+}
+
+// BreachedRangeDir consulta um diretório com um arquivo por faixa, nomeado
+// pelo prefixo de 5 caracteres (ex: 5BAA6 ou 5BAA6.txt) e com um sufixo por
+// linha no formato "SUFIXO:ocorrências", como os arquivos baixados da API de
+// faixas. Apenas a faixa consultada é lida, o que permite usar o corpus
+// completo sem carregá-lo em memória.
+type BreachedRangeDir struct {
+	dir string
+}
+
+// Contains informa se a senha está na faixa correspondente do diretório
+func (b *BreachedRangeDir) Contains(password string) (bool, error) {
+	prefix, suffix := breachedRange(password)
+
+	file, err := os.Open(filepath.Join(b.dir, prefix))
+	if os.IsNotExist(err) {
+		file, err = os.Open(filepath.Join(b.dir, prefix+".txt"))
+	}
+	if os.IsNotExist(err) {
+		// Faixa ausente: nenhuma senha vazada com esse prefixo
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		candidate, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(candidate, suffix) {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+// OpenBreachedPasswords abre o corpus em path: um diretório de faixas
+// (BreachedRangeDir) ou um arquivo carregado em memória (BreachedCorpus)
+func OpenBreachedPasswords(path string) (BreachedPasswords, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("corpus de senhas vazadas: %w", err)
+	}
+	if info.IsDir() {
+		return &BreachedRangeDir{dir: path}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("corpus de senhas vazadas: %w", err)
+	}
+	defer file.Close()
+
+	return ReadBreachedCorpus(file)
+}
+
+// breachedRange retorna o prefixo e o sufixo do SHA-1 da senha, em maiúsculas
+func breachedRange(password string) (prefix, suffix string) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+}
+
+// validSHA1 informa se hash tem 40 dígitos hexadecimais
+func validSHA1(hash string) bool {
+	if len(hash) != 2*sha1.Size {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
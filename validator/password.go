@@ -0,0 +1,207 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// defaultPasswordMinLength é o tamanho mínimo padrão, em caracteres
+	defaultPasswordMinLength = 10
+
+	// defaultPasswordMaxLength é o tamanho máximo padrão, em bytes; limita o
+	// custo do hash de senhas enormes
+	defaultPasswordMaxLength = 128
+)
+
+var (
+	// ErrPasswordBreached indica uma senha presente no corpus de senhas vazadas
+	ErrPasswordBreached = errors.New("senha encontrada em vazamentos de dados; escolha outra")
+
+	// ErrBreachedUnavailable indica falha ao consultar o corpus; não é culpa do usuário
+	ErrBreachedUnavailable = errors.New("corpus de senhas vazadas indisponível")
+)
+
+// Classes de caracteres que a política pode exigir
+const (
+	PasswordClassLowercase = "lowercase"
+	PasswordClassUppercase = "uppercase"
+	PasswordClassNumber    = "number"
+	PasswordClassSpecial   = "special"
+)
+
+// PasswordPolicy define os requisitos das senhas escolhidas pelos usuários
+type PasswordPolicy struct {
+	// Tamanho mínimo, em caracteres
+	MinLength int
+
+	// Tamanho máximo, em bytes
+	MaxLength int
+
+	// Classes de caracteres exigidas
+	RequireLowercase bool
+	RequireUppercase bool
+	RequireNumber    bool
+	RequireSpecial   bool
+
+	// Corpus de senhas vazadas; nil desativa a verificação
+	Breached BreachedPasswords
+}
+
+// strictPasswordPolicy é a política usada por ValidatePassword
+var strictPasswordPolicy = &PasswordPolicy{
+	MinLength:        8,
+	MaxLength:        100,
+	RequireLowercase: true,
+	RequireUppercase: true,
+	RequireNumber:    true,
+	RequireSpecial:   true,
+}
+
+// DefaultPasswordPolicy retorna a política padrão: ao menos 10 caracteres,
+// letras maiúsculas, minúsculas e números, e fora da lista embutida de senhas
+// mais comuns em vazamentos
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		MinLength:        defaultPasswordMinLength,
+		MaxLength:        defaultPasswordMaxLength,
+		RequireLowercase: true,
+		RequireUppercase: true,
+		RequireNumber:    true,
+		Breached:         CommonBreachedPasswords(),
+	}
+}
+
+// PasswordPolicyFromEnv lê PASSWORD_MIN_LENGTH, PASSWORD_MAX_LENGTH,
+// PASSWORD_REQUIRE (classes separadas por vírgula ou "none") e
+// PASSWORD_BREACHED_PATH (arquivo ou diretório do corpus, ou "off")
+func PasswordPolicyFromEnv() (*PasswordPolicy, error) {
+	policy := DefaultPasswordPolicy()
+
+	for name, target := range map[string]*int{
+		"PASSWORD_MIN_LENGTH": &policy.MinLength,
+		"PASSWORD_MAX_LENGTH": &policy.MaxLength,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("%s inválido: %q", name, value)
+			}
+			*target = parsed
+		}
+	}
+	if policy.MinLength > policy.MaxLength {
+		return nil, fmt.Errorf("PASSWORD_MIN_LENGTH (%d) maior que PASSWORD_MAX_LENGTH (%d)", policy.MinLength, policy.MaxLength)
+	}
+
+	if value := os.Getenv("PASSWORD_REQUIRE"); value != "" {
+		policy.RequireLowercase, policy.RequireUppercase, policy.RequireNumber, policy.RequireSpecial = false, false, false, false
+		if value != "none" {
+			for _, class := range strings.Split(value, ",") {
+				switch strings.TrimSpace(class) {
+				case PasswordClassLowercase:
+					policy.RequireLowercase = true
+				case PasswordClassUppercase:
+					policy.RequireUppercase = true
+				case PasswordClassNumber:
+					policy.RequireNumber = true
+				case PasswordClassSpecial:
+					policy.RequireSpecial = true
+				default:
+					return nil, fmt.Errorf("classe de caracteres desconhecida em PASSWORD_REQUIRE: %q", class)
+				}
+			}
+		}
+	}
+
+	switch path := os.Getenv("PASSWORD_BREACHED_PATH"); path {
+	case "":
+	case "off":
+		policy.Breached = nil
+	default:
+		breached, err := OpenBreachedPasswords(path)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breached = breached
+	}
+
+	return policy, nil
+}
+
+// Validate verifica se a senha atende à política. Os requisitos são
+// verificados antes do corpus de senhas vazadas.
+func (p *PasswordPolicy) Validate(password string) error {
+	if length := utf8.RuneCountInString(password); length < p.MinLength {
+		return fmt.Errorf("%w: mínimo de %d caracteres", ErrPasswordTooShort, p.MinLength)
+	}
+	if len(password) > p.MaxLength {
+		return fmt.Errorf("%w: máximo de %d bytes", ErrPasswordTooLong, p.MaxLength)
+	}
+
+	var lower, upper, number, special bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			number = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			special = true
+		}
+	}
+
+	switch {
+	case p.RequireNumber && !number:
+		return ErrPasswordNoNumber
+	case p.RequireSpecial && !special:
+		return ErrPasswordNoSpecial
+	case p.RequireUppercase && !upper:
+		return ErrPasswordNoUppercase
+	case p.RequireLowercase && !lower:
+		return ErrPasswordNoLowercase
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrBreachedUnavailable, err)
+		}
+		if breached {
+			return ErrPasswordBreached
+		}
+	}
+
+	return nil
+}
+
+// Requirements descreve a política para exibição aos usuários
+func (p *PasswordPolicy) Requirements() map[string]interface{} {
+	classes := []string{}
+	for class, required := range map[string]bool{
+		PasswordClassLowercase: p.RequireLowercase,
+		PasswordClassUppercase: p.RequireUppercase,
+		PasswordClassNumber:    p.RequireNumber,
+		PasswordClassSpecial:   p.RequireSpecial,
+	} {
+		if required {
+			classes = append(classes, class)
+		}
+	}
+	sort.Strings(classes)
+
+	return map[string]interface{}{
+		"min_length":       p.MinLength,
+		"max_length":       p.MaxLength,
+		"required_classes": classes,
+		"breached_check":   p.Breached != nil,
+	}
+}
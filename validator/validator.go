@@ -3,7 +3,6 @@ package validator
 import (
 	"errors"
 	"regexp"
-	"strings"
 )
 
 var (
@@ -34,33 +33,10 @@ func ValidateUsername(username string) error {
 	return nil
 }
 
-// ValidatePassword valida uma senha
+// ValidatePassword valida uma senha com a política mais restritiva: ao menos
+// 8 caracteres, com número, caractere especial, maiúscula e minúscula
 func ValidatePassword(password string) error {
-	if len(password) < 8 {
-		return ErrPasswordTooShort
-	}
-
-	if len(password) > 100 {
-		return ErrPasswordTooLong
-	}
-
-	if !strings.ContainsAny(password, "0123456789") {
-		return ErrPasswordNoNumber
-	}
-
-	if !strings.ContainsAny(password, "!@#$%^&*()_+-=[]{}|;:,.<>?") {
-		return ErrPasswordNoSpecial
-	}
-
-	if !strings.ContainsAny(password, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") {
-		return ErrPasswordNoUppercase
-	}
-
-	if !strings.ContainsAny(password, "abcdefghijklmnopqrstuvwxyz") {
-		return ErrPasswordNoLowercase
-	}
-
-	return nil
+	return strictPasswordPolicy.Validate(password)
 }
 
 // ValidateEmail valida um email